@@ -0,0 +1,145 @@
+/*
+Package bench measures how long the compilers take to compile generated programs of increasing size.
+Run from the repository root:
+
+	go test ./bench -bench .
+
+For each dialect, a program of each size (in lines) is written to a temporary file and compiled
+(as 'pigeon check' would, plus the generation of the Go code, but without the Go build).
+Besides the time per compile, each benchmark reports the time per 1000 lines, which should stay
+about the same as the size of the program grows (compile time should grow about linearly).
+*/
+package bench
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/BrianWill/pigeon/goPigeon"
+	"github.com/BrianWill/pigeon/pigeon"
+)
+
+var sizes = []int{1000, 2000, 4000, 8000, 16000}
+
+// the number of lines of each generated function
+const funcLines = 11
+
+type dialect struct {
+	Name      string
+	Extension string
+	Func      func(name string) string // returns a function of funcLines lines
+	Main      func(funcs []string) string
+	Compile   func(filename string) error
+}
+
+var dialects = []dialect{
+	{
+		Name:      "GoPigeon",
+		Extension: ".gopigeon",
+		Func: func(name string) string {
+			return "func " + name + " a I b I : I\n" +
+				"    locals c I\n" +
+				"    as c (add a b)\n" +
+				"    if (gt c 10)\n" +
+				"        as c (sub c 1)\n" +
+				"    else\n" +
+				"        as c (mul c 2)\n" +
+				"    forinc i I 0 3\n" +
+				"        as c (add c i)\n" +
+				"    return c\n" +
+				"\n"
+		},
+		Main: func(funcs []string) string {
+			var code strings.Builder
+			code.WriteString("func main\n    locals total I\n")
+			for i, name := range funcs {
+				code.WriteString("    as total (add total (" + name + " " + strconv.Itoa(i) + " 2))\n")
+			}
+			code.WriteString("    (println total)\n")
+			return code.String()
+		},
+		Compile: func(filename string) error {
+			_, diags := goPigeon.Compile(filename, "bench/", false)
+			if len(diags) > 0 {
+				return diags[0]
+			}
+			return nil
+		},
+	},
+	{
+		Name:      "Pigeon",
+		Extension: ".pigeon",
+		Func: func(name string) string {
+			return "func " + name + " a b\n" +
+				"    locals c\n" +
+				"    as c (add a b)\n" +
+				"    if (gt c 10)\n" +
+				"        as c (sub c 1)\n" +
+				"    else\n" +
+				"        as c (mul c 2)\n" +
+				"    forinc i 0 3\n" +
+				"        as c (add c i)\n" +
+				"    return c\n" +
+				"\n"
+		},
+		Main: func(funcs []string) string {
+			var code strings.Builder
+			code.WriteString("func main\n    locals total\n    as total 0\n")
+			for i, name := range funcs {
+				code.WriteString("    as total (add total (" + name + " " + strconv.Itoa(i) + " 2))\n")
+			}
+			code.WriteString("    (println total)\n")
+			return code.String()
+		},
+		Compile: func(filename string) error {
+			_, diags := pigeon.Compile(filename, "bench/", false)
+			if len(diags) > 0 {
+				return diags[0]
+			}
+			return nil
+		},
+	},
+}
+
+func BenchmarkCompile(b *testing.B) {
+	for _, d := range dialects {
+		for _, size := range sizes {
+			d, size := d, size
+			b.Run(d.Name+"/"+strconv.Itoa(size), func(b *testing.B) {
+				src, lines := generate(d, size)
+				filename := filepath.Join(b.TempDir(), "bench"+strconv.Itoa(size)+d.Extension)
+				err := ioutil.WriteFile(filename, []byte(src), 0644)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					err := d.Compile(filename)
+					if err != nil {
+						b.Fatalf("%s (%d lines): %v", d.Name, lines, err)
+					}
+				}
+				b.StopTimer()
+				perThousand := float64(b.Elapsed().Nanoseconds()) / float64(b.N) * 1000 / float64(lines)
+				b.ReportMetric(perThousand, "ns/1000lines")
+			})
+		}
+	}
+}
+
+// returns a program of about the given number of lines, and its actual number of lines
+func generate(d dialect, size int) (string, int) {
+	var code strings.Builder
+	funcs := []string{}
+	// each function adds a line to main
+	for i := 0; i < size/(funcLines+1); i++ {
+		name := "f" + strconv.Itoa(i)
+		funcs = append(funcs, name)
+		code.WriteString(d.Func(name))
+	}
+	code.WriteString(d.Main(funcs))
+	return code.String(), strings.Count(code.String(), "\n")
+}
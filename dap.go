@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+A Debug Adapter Protocol server (https://microsoft.github.io/debug-adapter-protocol/)
+for debugging Pigeon and GoPigeon programs from an editor. Each message is a JSON object
+preceded by a 'Content-Length: N' header and a blank line.
+
+Supported requests: initialize, launch, setBreakpoints, configurationDone, threads,
+continue, next, stepIn, stackTrace, scopes, variables, disconnect, terminate.
+
+The launch arguments are 'program' (the source file), 'dialect' (optional) and 'stopOnEntry'.
+A program has one thread (id 1). In the variables of a stopped program, reference 1 is
+the globals, and reference N+2 is the locals of stack frame N (the innermost frame is 0).
+*/
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+type dapSource struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type dapLaunchArguments struct {
+	Program     string `json:"program"`
+	Dialect     string `json:"dialect"`
+	StopOnEntry bool   `json:"stopOnEntry"`
+}
+
+type dapSetBreakpointsArguments struct {
+	Source      dapSource `json:"source"`
+	Breakpoints []struct {
+		Line int `json:"line"`
+	} `json:"breakpoints"`
+	Lines []int `json:"lines"` // deprecated form
+}
+
+// A dapServer reads requests from in and writes responses and events to out.
+type dapServer struct {
+	in          *bufio.Reader
+	out         io.Writer
+	outMutex    sync.Mutex // guards out and seq
+	seq         int
+	session     *debugSession
+	source      dapSource
+	stopOnEntry bool
+	mutex       sync.Mutex // guards stop
+	stop        *debugStop // the current stop, or nil if the program is running
+}
+
+func dapCommand(args []string) int {
+	if len(args) != 0 {
+		fmt.Println("pigeon dap takes no arguments: it serves the Debug Adapter Protocol over stdin and stdout.")
+		return 2
+	}
+	err := serveDAP(os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// serveDAP serves one debug session, returning when the client disconnects or in is exhausted.
+func serveDAP(in io.Reader, out io.Writer) error {
+	s := &dapServer{in: bufio.NewReader(in), out: out}
+	defer func() {
+		if s.session != nil {
+			s.session.Kill()
+		}
+	}()
+	for {
+		data, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req dapRequest
+		err = json.Unmarshal(data, &req)
+		if err != nil {
+			return err
+		}
+		if req.Type != "request" {
+			continue
+		}
+		done := s.handle(req)
+		if done {
+			return nil
+		}
+	}
+}
+
+// reads the content of one message framed by a Content-Length header (as in both DAP and LSP)
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length == -1 {
+				return nil, io.EOF
+			}
+			return nil, errors.New("Incomplete message header.")
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if length == -1 {
+				continue
+			}
+			break
+		}
+		if strings.HasPrefix(line, "Content-Length:") {
+			length, err = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+			if err != nil {
+				return nil, errors.New("Invalid Content-Length header: " + line)
+			}
+		}
+	}
+	data := make([]byte, length)
+	_, err := io.ReadFull(r, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *dapServer) write(msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		panic(err) // all messages are marshalable
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (s *dapServer) respond(req dapRequest, body interface{}, err error) {
+	s.outMutex.Lock()
+	defer s.outMutex.Unlock()
+	s.seq++
+	resp := dapResponse{Seq: s.seq, Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	s.write(resp)
+}
+
+func (s *dapServer) event(event string, body interface{}) {
+	s.outMutex.Lock()
+	defer s.outMutex.Unlock()
+	s.seq++
+	s.write(dapEvent{Seq: s.seq, Type: "event", Event: event, Body: body})
+}
+
+// handles a request. Returns true if the session is over.
+func (s *dapServer) handle(req dapRequest) bool {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsTerminateRequest":         true,
+		}, nil)
+	case "launch":
+		err := s.launch(req.Arguments)
+		s.respond(req, nil, err)
+		if err == nil {
+			s.event("initialized", nil)
+		}
+	case "setBreakpoints":
+		body, err := s.setBreakpoints(req.Arguments)
+		s.respond(req, body, err)
+	case "configurationDone":
+		if s.session == nil {
+			s.respond(req, nil, errors.New("No program has been launched."))
+			break
+		}
+		s.respond(req, nil, nil)
+		go s.watch()
+		if s.stopOnEntry {
+			s.resume("step")
+		} else {
+			s.resume("continue")
+		}
+	case "threads":
+		s.respond(req, map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": 1, "name": "main"}},
+		}, nil)
+	case "continue":
+		s.respond(req, map[string]interface{}{"allThreadsContinued": true}, nil)
+		s.resume("continue")
+	case "next":
+		s.respond(req, nil, nil)
+		s.resume("next")
+	case "stepIn":
+		s.respond(req, nil, nil)
+		s.resume("step")
+	case "stackTrace":
+		body, err := s.stackTrace()
+		s.respond(req, body, err)
+	case "scopes":
+		body, err := s.scopes(req.Arguments)
+		s.respond(req, body, err)
+	case "variables":
+		body, err := s.variables(req.Arguments)
+		s.respond(req, body, err)
+	case "disconnect", "terminate":
+		if s.session != nil {
+			s.session.Kill()
+		}
+		s.respond(req, nil, nil)
+		return req.Command == "disconnect"
+	default:
+		s.respond(req, nil, errors.New("Unsupported request: "+req.Command))
+	}
+	return false
+}
+
+// writes the output of the program as output events
+type dapOutput struct {
+	server   *dapServer
+	category string
+}
+
+func (o dapOutput) Write(p []byte) (int, error) {
+	o.server.event("output", map[string]interface{}{"category": o.category, "output": string(p)})
+	return len(p), nil
+}
+
+func (s *dapServer) launch(arguments json.RawMessage) error {
+	if s.session != nil {
+		return errors.New("A program has already been launched.")
+	}
+	var args dapLaunchArguments
+	err := json.Unmarshal(arguments, &args)
+	if err != nil {
+		return err
+	}
+	if args.Program == "" {
+		return errors.New("Missing launch argument 'program'.")
+	}
+	path, err := filepath.Abs(args.Program)
+	if err != nil {
+		return err
+	}
+	session, err := startDebugSession(args.Program, args.Dialect, nil,
+		dapOutput{s, "stdout"}, dapOutput{s, "stderr"})
+	if err != nil {
+		return err
+	}
+	s.session = session
+	s.source = dapSource{Name: filepath.Base(path), Path: path}
+	s.stopOnEntry = args.StopOnEntry
+	return nil
+}
+
+func (s *dapServer) setBreakpoints(arguments json.RawMessage) (interface{}, error) {
+	if s.session == nil {
+		return nil, errors.New("No program has been launched.")
+	}
+	var args dapSetBreakpointsArguments
+	err := json.Unmarshal(arguments, &args)
+	if err != nil {
+		return nil, err
+	}
+	lines := args.Lines
+	if args.Breakpoints != nil {
+		lines = make([]int, len(args.Breakpoints))
+		for i, bp := range args.Breakpoints {
+			lines[i] = bp.Line
+		}
+	}
+	verified := s.session.SetBreakpoints(lines)
+	breakpoints := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		bp := map[string]interface{}{"verified": verified[i], "line": line}
+		if !verified[i] {
+			bp["message"] = "Not a valid breakpoint: line " + strconv.Itoa(line) + " has no statement."
+		}
+		breakpoints[i] = bp
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func (s *dapServer) resume(command string) {
+	s.mutex.Lock()
+	s.stop = nil
+	s.mutex.Unlock()
+	s.session.Resume(command)
+}
+
+// reports the stops and the exit of the program as events
+func (s *dapServer) watch() {
+	first := true
+	for stop := range s.session.Stops {
+		s.mutex.Lock()
+		s.stop = stop
+		s.mutex.Unlock()
+		reason := stop.Reason
+		if first && s.stopOnEntry {
+			reason = "entry"
+		}
+		first = false
+		s.event("stopped", map[string]interface{}{"reason": reason, "threadId": 1, "allThreadsStopped": true})
+	}
+	s.event("exited", map[string]interface{}{"exitCode": s.session.ExitStatus})
+	s.event("terminated", nil)
+}
+
+// returns the current stop, or an error if the program is not stopped
+func (s *dapServer) currentStop() (*debugStop, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stop == nil {
+		return nil, errors.New("The program is not stopped.")
+	}
+	return s.stop, nil
+}
+
+func (s *dapServer) stackTrace() (interface{}, error) {
+	stop, err := s.currentStop()
+	if err != nil {
+		return nil, err
+	}
+	frames := make([]map[string]interface{}, len(stop.Frames))
+	for i, f := range stop.Frames {
+		frames[i] = map[string]interface{}{
+			"id":     i,
+			"name":   f.Name,
+			"line":   f.Line,
+			"column": 1,
+			"source": s.source,
+		}
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+func (s *dapServer) scopes(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		FrameID int `json:"frameId"`
+	}
+	err := json.Unmarshal(arguments, &args)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := s.currentStop()
+	if err != nil {
+		return nil, err
+	}
+	if args.FrameID < 0 || args.FrameID >= len(stop.Frames) {
+		return nil, errors.New("Invalid frame id: " + strconv.Itoa(args.FrameID))
+	}
+	return map[string]interface{}{
+		"scopes": []map[string]interface{}{
+			{"name": "Locals", "variablesReference": args.FrameID + 2, "expensive": false},
+			{"name": "Globals", "variablesReference": 1, "expensive": false},
+		},
+	}, nil
+}
+
+func (s *dapServer) variables(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	err := json.Unmarshal(arguments, &args)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := s.currentStop()
+	if err != nil {
+		return nil, err
+	}
+	var vars []debugVar
+	ref := args.VariablesReference
+	switch {
+	case ref == 1:
+		vars = stop.Globals
+	case ref >= 2 && ref-2 < len(stop.Frames):
+		vars = stop.Frames[ref-2].Locals
+	default:
+		return nil, errors.New("Invalid variables reference: " + strconv.Itoa(ref))
+	}
+	variables := make([]map[string]interface{}, len(vars))
+	for i, v := range vars {
+		variables[i] = map[string]interface{}{"name": v.Name, "value": v.Value, "variablesReference": 0}
+	}
+	return map[string]interface{}{"variables": variables}, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// a client of serveDAP, talking to it through in-memory pipes
+type dapClient struct {
+	t        *testing.T
+	in       *io.PipeWriter
+	messages chan map[string]interface{}
+	seq      int
+	output   string // the text of the output events
+}
+
+func newDAPClient(t *testing.T) *dapClient {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command is not installed")
+	}
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &dapClient{t: t, in: inW, messages: make(chan map[string]interface{}, 100)}
+	go func() {
+		err := serveDAP(inR, outW)
+		outW.CloseWithError(fmt.Errorf("serveDAP returned: %v", err))
+	}()
+	go func() {
+		r := bufio.NewReader(outR)
+		for {
+			data, err := readMessage(r)
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			var msg map[string]interface{}
+			if json.Unmarshal(data, &msg) != nil {
+				close(c.messages)
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+	return c
+}
+
+func (c *dapClient) request(command string, arguments interface{}) {
+	c.seq++
+	data, err := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": arguments})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	_, err = fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// returns the next message other than an output event
+func (c *dapClient) next() map[string]interface{} {
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatal("the server closed its output")
+			}
+			if msg["event"] == "output" {
+				c.output += msg["body"].(map[string]interface{})["output"].(string)
+				continue
+			}
+			return msg
+		case <-time.After(30 * time.Second):
+			c.t.Fatal("timed out waiting for a message from the server")
+		}
+	}
+}
+
+// sends a request and returns the body of its response, which must be successful
+func (c *dapClient) call(command string, arguments interface{}) map[string]interface{} {
+	c.request(command, arguments)
+	msg := c.next()
+	if msg["type"] != "response" || msg["command"] != command || msg["request_seq"] != float64(c.seq) {
+		c.t.Fatalf("got %v, want the response to %s", msg, command)
+	}
+	if msg["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, msg["message"])
+	}
+	body, _ := msg["body"].(map[string]interface{})
+	return body
+}
+
+// returns the body of the next event, which must be the given event
+func (c *dapClient) expectEvent(event string) map[string]interface{} {
+	msg := c.next()
+	if msg["type"] != "event" || msg["event"] != event {
+		c.t.Fatalf("got %v, want the %s event", msg, event)
+	}
+	body, _ := msg["body"].(map[string]interface{})
+	return body
+}
+
+// waits for a stopped event and returns its reason and the line of the innermost frame
+func (c *dapClient) expectStop() (string, float64) {
+	reason := c.expectEvent("stopped")["reason"].(string)
+	frames := c.call("stackTrace", map[string]interface{}{"threadId": 1})["stackFrames"].([]interface{})
+	return reason, frames[0].(map[string]interface{})["line"].(float64)
+}
+
+// A session through the requests an editor sends: a breakpoint stops the program, its
+// variables are inspected, and the program steps over a line and then runs to its end.
+func TestDAPSession(t *testing.T) {
+	c := newDAPClient(t)
+	c.call("initialize", map[string]interface{}{"adapterID": "pigeon"})
+	c.call("launch", map[string]interface{}{"program": "goPigeon/examples/loops.gopigeon"})
+	c.expectEvent("initialized")
+
+	// (line 1 is a comment)
+	body := c.call("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": "goPigeon/examples/loops.gopigeon"},
+		"breakpoints": []map[string]interface{}{{"line": 20}, {"line": 1}},
+	})
+	breakpoints := body["breakpoints"].([]interface{})
+	if len(breakpoints) != 2 {
+		t.Fatalf("got %d breakpoints, want 2", len(breakpoints))
+	}
+	for i, want := range []bool{true, false} {
+		bp := breakpoints[i].(map[string]interface{})
+		if bp["verified"] != want {
+			t.Errorf("breakpoint on line %v: verified is %v, want %v", bp["line"], bp["verified"], want)
+		}
+	}
+
+	c.call("configurationDone", nil)
+	reason, line := c.expectStop()
+	if reason != "breakpoint" || line != 20 {
+		t.Fatalf("stopped by %s on line %v, want by breakpoint on line 20", reason, line)
+	}
+	frames := c.call("stackTrace", map[string]interface{}{"threadId": 1})["stackFrames"].([]interface{})
+	if len(frames) != 1 || frames[0].(map[string]interface{})["name"] != "main" {
+		t.Errorf("stack frames %v, want just main", frames)
+	}
+
+	scopes := c.call("scopes", map[string]interface{}{"frameId": 0})["scopes"].([]interface{})
+	locals := scopes[0].(map[string]interface{})
+	if locals["name"] != "Locals" || locals["variablesReference"] != float64(2) {
+		t.Fatalf("first scope %v, want the locals of frame 0 (reference 2)", locals)
+	}
+	variables := c.call("variables", map[string]interface{}{"variablesReference": 2})["variables"].([]interface{})
+	values := map[string]interface{}{}
+	for _, v := range variables {
+		v := v.(map[string]interface{})
+		values[v["name"].(string)] = v["value"]
+	}
+	if values["total"] != "12" {
+		t.Errorf("locals %v, want total = 12", values)
+	}
+
+	c.call("next", map[string]interface{}{"threadId": 1})
+	reason, line = c.expectStop()
+	if reason != "step" || line != 21 {
+		t.Errorf("stopped by %s on line %v, want by step on line 21", reason, line)
+	}
+
+	c.call("continue", map[string]interface{}{"threadId": 1})
+	exited := c.expectEvent("exited")
+	if exited["exitCode"] != float64(0) {
+		t.Errorf("exit code %v, want 0", exited["exitCode"])
+	}
+	c.expectEvent("terminated")
+	c.call("disconnect", nil)
+	if want := "12\n0 bee\n1 cat\n"; len(c.output) < len(want) || c.output[len(c.output)-len(want):] != want {
+		t.Errorf("program output:\n%s", c.output)
+	}
+}
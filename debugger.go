@@ -0,0 +1,262 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const debuggerHelp = `Commands:
+    break N      (b)   set a breakpoint on line N
+    clear N            remove the breakpoint on line N
+    breakpoints        list the breakpoints
+    continue     (c)   run until the next breakpoint (also: run)
+    step         (s)   run to the next statement, stepping into function calls
+    next         (n)   run to the next statement, stepping over function calls
+    locals             print the local variables of the current function
+    globals            print the global variables
+    print NAME   (p)   print a local or global variable
+    where        (bt)  print the function calls on the stack
+    list               show the source around the current line
+    quit         (q)   end the program and the debugger
+`
+
+// A debugger is the command-line interface of a debug session.
+type debugger struct {
+	session *debugSession
+	source  []string   // lines of the source file
+	stop    *debugStop // the current stop, or nil if the program has not yet run
+	in      io.Reader
+	out     io.Writer
+}
+
+func debugCommand(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	dialectName := flags.String("dialect", "", "compile the file as this dialect, regardless of its extension")
+	filename, err := parseArgs(flags, args)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	// the program shares the terminal with the debugger: it reads stdin only while running,
+	// and the debugger reads stdin only while the program is stopped
+	session, err := startDebugSession(filename, *dialectName, os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	db := &debugger{
+		session: session,
+		source:  strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n"),
+		in:      os.Stdin,
+		out:     os.Stdout,
+	}
+	return db.loop()
+}
+
+// reads and executes commands until the program exits or the user quits.
+// Returns the exit status of the program (or 0 if the user quits).
+func (db *debugger) loop() int {
+	fmt.Fprintln(db.out, "Breakpoints can be set on lines:", joinLines(db.session.Program.ValidBreakpoints))
+	fmt.Fprintln(db.out, "Type 'help' for a list of commands. The program starts with 'continue', 'step' or 'next'.")
+	for {
+		fmt.Fprint(db.out, "(pigeon) ")
+		line, err := readLine(db.in)
+		if err != nil {
+			db.session.Kill()
+			for range db.session.Stops {
+			}
+			fmt.Fprintln(db.out)
+			return 0
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		arg := ""
+		if len(fields) > 1 {
+			arg = fields[1]
+		}
+		switch fields[0] {
+		case "break", "b":
+			db.setBreakpoint(arg, true)
+		case "clear":
+			db.setBreakpoint(arg, false)
+		case "breakpoints":
+			fmt.Fprintln(db.out, "Breakpoints:", joinLines(db.session.Breakpoints()))
+		case "continue", "c", "run":
+			if !db.resume("continue") {
+				return db.session.ExitStatus
+			}
+		case "step", "s":
+			if !db.resume("step") {
+				return db.session.ExitStatus
+			}
+		case "next", "n":
+			if !db.resume("next") {
+				return db.session.ExitStatus
+			}
+		case "locals":
+			if db.stopped() {
+				db.printVars(db.stop.Frames[0].Locals)
+			}
+		case "globals":
+			if db.stopped() {
+				db.printVars(db.stop.Globals)
+			}
+		case "print", "p":
+			if db.stopped() {
+				db.printVar(arg)
+			}
+		case "where", "bt":
+			if db.stopped() {
+				for _, f := range db.stop.Frames {
+					fmt.Fprintf(db.out, "    %s, line %d\n", f.Name, f.Line)
+				}
+			}
+		case "list":
+			if db.stopped() {
+				for line := db.stop.Line - 3; line <= db.stop.Line+3; line++ {
+					db.printSourceLine(line)
+				}
+			}
+		case "quit", "q":
+			db.session.Kill()
+			for range db.session.Stops {
+			}
+			return 0
+		case "help", "h":
+			fmt.Fprint(db.out, debuggerHelp)
+		default:
+			fmt.Fprintln(db.out, "Unknown command: "+fields[0]+". Type 'help' for a list of commands.")
+		}
+	}
+}
+
+// resumes the program and waits for it to stop. Returns false if the program exited.
+func (db *debugger) resume(command string) bool {
+	db.session.Resume(command)
+	stop, ok := <-db.session.Stops
+	if !ok {
+		fmt.Fprintf(db.out, "Program exited with status %d.\n", db.session.ExitStatus)
+		return false
+	}
+	db.stop = stop
+	function := ""
+	if len(stop.Frames) > 0 {
+		function = " in " + stop.Frames[0].Name
+	}
+	if stop.Reason == "breakpoint" {
+		fmt.Fprintf(db.out, "Stopped at breakpoint%s, line %d:\n", function, stop.Line)
+	} else {
+		fmt.Fprintf(db.out, "Stopped%s, line %d:\n", function, stop.Line)
+	}
+	db.printSourceLine(stop.Line)
+	return true
+}
+
+// returns true if the program is stopped (otherwise prints a reminder)
+func (db *debugger) stopped() bool {
+	if db.stop == nil {
+		fmt.Fprintln(db.out, "The program has not started. Use 'continue', 'step' or 'next'.")
+		return false
+	}
+	return true
+}
+
+func (db *debugger) setBreakpoint(arg string, set bool) {
+	line, err := strconv.Atoi(arg)
+	if err != nil {
+		fmt.Fprintln(db.out, "Expecting a line number.")
+		return
+	}
+	lines := []int{}
+	for _, bp := range db.session.Breakpoints() {
+		if bp != line {
+			lines = append(lines, bp)
+		}
+	}
+	if !set {
+		db.session.SetBreakpoints(lines)
+		fmt.Fprintf(db.out, "Breakpoint on line %d cleared.\n", line)
+		return
+	}
+	verified := db.session.SetBreakpoints(append(lines, line))
+	if !verified[len(verified)-1] {
+		fmt.Fprintf(db.out, "Line %d is not a valid breakpoint. Breakpoints can be set on lines: %s\n",
+			line, joinLines(db.session.Program.ValidBreakpoints))
+		return
+	}
+	fmt.Fprintf(db.out, "Breakpoint set on line %d.\n", line)
+}
+
+func (db *debugger) printVars(vars []debugVar) {
+	if len(vars) == 0 {
+		fmt.Fprintln(db.out, "    (none)")
+	}
+	for _, v := range vars {
+		fmt.Fprintf(db.out, "    %s = %s\n", v.Name, v.Value)
+	}
+}
+
+func (db *debugger) printVar(name string) {
+	for _, vars := range [][]debugVar{db.stop.Frames[0].Locals, db.stop.Globals} {
+		for _, v := range vars {
+			if v.Name == name {
+				fmt.Fprintf(db.out, "    %s = %s\n", v.Name, v.Value)
+				return
+			}
+		}
+	}
+	fmt.Fprintln(db.out, "No variable named '"+name+"'.")
+}
+
+// prints the numbered source line, marking the current line
+func (db *debugger) printSourceLine(line int) {
+	if line < 1 || line > len(db.source) {
+		return
+	}
+	marker := " "
+	if db.stop != nil && line == db.stop.Line {
+		marker = ">"
+	}
+	fmt.Fprintf(db.out, "%s %4d | %s\n", marker, line, db.source[line-1])
+}
+
+// reads one line a byte at a time, so as not to consume input meant for the program
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				return strings.TrimSuffix(string(line), "\r"), nil
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+	}
+}
+
+func joinLines(lines []int) string {
+	strs := make([]string, len(lines))
+	for i, line := range lines {
+		strs[i] = strconv.Itoa(line)
+	}
+	return strings.Join(strs, " ")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runs the debugger with the script of commands on the example program, returning the debugger's
+// transcript and the program's output
+func runDebugger(t *testing.T, filename string, script string) (string, string) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command is not installed")
+	}
+	var transcript, output bytes.Buffer
+	session, err := startDebugSession(filename, "", nil, &output, &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &debugger{session: session, in: strings.NewReader(script), out: &transcript}
+	status := db.loop()
+	if status != 0 {
+		t.Errorf("exit status %d, want 0", status)
+	}
+	return transcript.String(), output.String()
+}
+
+// A breakpoint, a next, and a step in a goroutine stop in that goroutine, and its calls alone are
+// on the stack.
+func TestDebugGoroutine(t *testing.T) {
+	transcript, output := runDebugger(t, "goPigeon/examples/channels.gopigeon",
+		"break 8\ncontinue\nwhere\nnext\nwhere\nstep\nprint n\nclear 8\ncontinue\n")
+	// (the source lines aren't loaded, so the debugger prints none)
+	want := `Breakpoints can be set on lines: 5 6 8 10 11 12 16 17 18 19 20 21 22 23 24 25 27 28 30 32 33 34 35
+Type 'help' for a list of commands. The program starts with 'continue', 'step' or 'next'.
+(pigeon) Breakpoint set on line 8.
+(pigeon) Stopped at breakpoint in square, line 8:
+(pigeon)     square, line 8
+(pigeon) Stopped in square, line 6:
+(pigeon)     square, line 6
+(pigeon) Stopped at breakpoint in square, line 8:
+(pigeon)     n = 2
+(pigeon) Breakpoint on line 8 cleared.
+(pigeon) Program exited with status 0.
+`
+	if transcript != want {
+		t.Errorf("transcript:\n%s\nwant:\n%s", transcript, want)
+	}
+	if !strings.HasSuffix(output, "5 squared is 25\nsquare got quit true\nsent 0\nsent 1\nbuffered is full\nhi hi 0\n") {
+		t.Errorf("program output:\n%s", output)
+	}
+}
+
+// A global's initializer runs before the debugger can stop the program, so a breakpoint can't be
+// set on its line.
+func TestDebugGlobalBreakpoint(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "greeting.gopigeon")
+	src := "global greeting Str (concat \"hi\" \"!\")\n\nfunc main\n    (println greeting)\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	transcript, output := runDebugger(t, filename, "break 1\nbreak 4\ncontinue\ncontinue\n")
+	want := `Breakpoints can be set on lines: 4
+Type 'help' for a list of commands. The program starts with 'continue', 'step' or 'next'.
+(pigeon) Line 1 is not a valid breakpoint. Breakpoints can be set on lines: 4
+(pigeon) Breakpoint set on line 4.
+(pigeon) Stopped at breakpoint in main, line 4:
+(pigeon) Program exited with status 0.
+`
+	if transcript != want {
+		t.Errorf("transcript:\n%s\nwant:\n%s", transcript, want)
+	}
+	if !strings.HasSuffix(output, "hi!\n") {
+		t.Errorf("program output:\n%s", output)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+// The messages exchanged with a program compiled for debugging (see DebugStop and DebugCommand in stdlib/debug.go).
+
+type debugVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type debugFrame struct {
+	Name   string     `json:"name"`
+	Line   int        `json:"line"`
+	Locals []debugVar `json:"locals"`
+}
+
+type debugStop struct {
+	Line    int          `json:"line"`
+	Reason  string       `json:"reason"` // "breakpoint" or "step"
+	Frames  []debugFrame `json:"frames"` // innermost call first
+	Globals []debugVar   `json:"globals"`
+}
+
+type debugRequest struct {
+	Command string `json:"command"`
+	Lines   []int  `json:"lines,omitempty"`
+}
+
+// A debugSession is a program running under the control of a debugger.
+// The program does not run until the first call to Resume.
+type debugSession struct {
+	Program *program
+	// receives each stop of the program; closed when the program has exited
+	Stops chan *debugStop
+	// exit status of the program, valid once Stops is closed
+	ExitStatus int
+
+	cmd         *exec.Cmd
+	mutex       sync.Mutex
+	conn        net.Conn      // nil until the program connects
+	ready       chan struct{} // closed when the program connects or exits without connecting
+	breakpoints []int
+}
+
+// compiles the file for debugging and starts the program. The program's standard streams are connected
+// to stdin, stdout and stderr (stdin may be nil).
+func startDebugSession(filename string, dialectName string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (*debugSession, error) {
+	d, err := findDialect(filename, dialectName)
+	if err != nil {
+		return nil, err
+	}
+	prog, diags := d.Compile(filename, outputModule, true)
+	if prog == nil {
+		return nil, diagnosticsError(diags)
+	}
+	w, err := newWorkspace("", d)
+	if err != nil {
+		return nil, err
+	}
+	w.Stdout = stdout
+	w.Stderr = stderr
+	_, err = w.writeProgram(prog)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	status, err := w.build(w.executable())
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	if status != 0 {
+		w.Close()
+		return nil, errors.New("Build failed with exit status " + strconv.Itoa(status) + ".")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	s := &debugSession{
+		Program: prog,
+		Stops:   make(chan *debugStop),
+		ready:   make(chan struct{}),
+	}
+	s.cmd = exec.Command(w.executable())
+	s.cmd.Env = append(os.Environ(), "PIGEON_DEBUG="+listener.Addr().String())
+	s.cmd.Stdin = stdin
+	s.cmd.Stdout = stdout
+	traceStderr := newTraceFilter(stderr, prog, w.Dir)
+	s.cmd.Stderr = traceStderr
+	err = s.cmd.Start()
+	if err != nil {
+		listener.Close()
+		w.Close()
+		return nil, err
+	}
+	exited := make(chan struct{})
+	go func() {
+		err := s.cmd.Wait()
+		traceStderr.Flush()
+		s.ExitStatus, _ = exitStatus(err)
+		listener.Close() // in case the program exited without connecting
+		close(exited)
+	}()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			s.mutex.Lock()
+			s.conn = conn
+			err = s.send(debugRequest{Command: "breakpoints", Lines: s.breakpoints})
+			s.mutex.Unlock()
+		}
+		close(s.ready)
+		if err == nil {
+			scanner := bufio.NewScanner(conn)
+			scanner.Buffer(nil, 16*1024*1024)
+			for scanner.Scan() {
+				var stop debugStop
+				if json.Unmarshal(scanner.Bytes(), &stop) == nil {
+					s.Stops <- &stop
+				}
+			}
+			conn.Close()
+		}
+		<-exited
+		w.Close()
+		close(s.Stops)
+	}()
+	return s, nil
+}
+
+// sends the command to the program (the mutex must be held)
+func (s *debugSession) send(cmd debugRequest) error {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Write(append(data, '\n'))
+	return err
+}
+
+// returns true if a breakpoint can be set on the line
+func (s *debugSession) validBreakpoint(line int) bool {
+	for _, valid := range s.Program.ValidBreakpoints {
+		if valid == line {
+			return true
+		}
+	}
+	return false
+}
+
+// SetBreakpoints replaces the breakpoints. Returns for each line whether it is a valid breakpoint;
+// invalid lines are ignored.
+func (s *debugSession) SetBreakpoints(lines []int) []bool {
+	verified := make([]bool, len(lines))
+	valid := []int{}
+	for i, line := range lines {
+		if s.validBreakpoint(line) {
+			verified[i] = true
+			valid = append(valid, line)
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.breakpoints = valid
+	if s.conn != nil {
+		s.send(debugRequest{Command: "breakpoints", Lines: valid})
+	}
+	return verified
+}
+
+// Breakpoints returns the lines of the current breakpoints.
+func (s *debugSession) Breakpoints() []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]int{}, s.breakpoints...)
+}
+
+// Resume runs the program until its next stop. The command is "continue", "step" or "next".
+func (s *debugSession) Resume(command string) {
+	<-s.ready
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn != nil {
+		s.send(debugRequest{Command: command})
+	}
+}
+
+// Kill ends the program.
+func (s *debugSession) Kill() {
+	s.cmd.Process.Kill()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/BrianWill/pigeon/explain"
+)
+
+// checkCommand compiles a file without building it and reports the diagnostics.
+func checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	dialectName := flags.String("dialect", "", "compile the file as this dialect, regardless of its extension")
+	jsonOutput := flags.Bool("json", false, "print the diagnostics as a JSON array")
+	filename, err := parseArgs(flags, args)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	d, err := findDialect(filename, *dialectName)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	_, diags := d.Compile(filename, outputModule, false)
+	printDiagnostics(diags, *jsonOutput)
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// explainCommand prints the explanation of an error code, or lists the codes if none is given.
+func explainCommand(args []string) int {
+	if len(args) == 0 {
+		for _, code := range explain.Codes() {
+			e, _ := explain.Lookup(code)
+			fmt.Println(code + "  " + e.Title)
+		}
+		fmt.Println("\nRun 'pigeon explain CODE' for the explanation of an error.")
+		return 0
+	}
+	if len(args) > 1 {
+		fmt.Println("pigeon explain takes one error code, e.g. pigeon explain P0201")
+		return 2
+	}
+	code := strings.ToUpper(args[0])
+	e, ok := explain.Lookup(code)
+	if !ok {
+		fmt.Println("No explanation for error code " + args[0] + ". Run 'pigeon explain' for a list of the codes.")
+		return 1
+	}
+	fmt.Print(e.Code + ": " + e.Title + "\n\n" + e.Text)
+	return 0
+}
+
+// prints the diagnostics as a JSON array (an empty array if there are none)
+func printDiagnosticsJSON(diags []diagnostic) {
+	if diags == nil {
+		diags = []diagnostic{}
+	}
+	data, err := json.MarshalIndent(diags, "", "  ")
+	if err != nil {
+		panic(err) // diagnostics are always marshalable
+	}
+	fmt.Println(string(data))
+}
+
+// formats each diagnostic with the source line it concerns and the offending code underlined, e.g.:
+//
+//	error[P0201]: Name is undefined: y
+//	  --> foo.pigeon:3:15
+//	   |
+//	 3 |     print x y
+//	   |             ^
+//	   = help: run `pigeon explain P0201` for details
+func formatDiagnostics(diags []diagnostic) string {
+	sources := map[string][]string{} // lines of each file (nil if the file can't be read)
+	return formatDiagnosticsOf(diags, func(file string) []string {
+		lines, ok := sources[file]
+		if !ok {
+			data, err := ioutil.ReadFile(file)
+			if err == nil {
+				lines = strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+			}
+			sources[file] = lines
+		}
+		return lines
+	})
+}
+
+// formats the diagnostics as formatDiagnostics does, but with the source lines returned by sourceLines.
+// The location is omitted for a diagnostic without a file (e.g. of input typed into the REPL).
+func formatDiagnosticsOf(diags []diagnostic, sourceLines func(file string) []string) string {
+	s := ""
+	for _, d := range diags {
+		s += d.Severity
+		if d.Code != "" {
+			s += "[" + d.Code + "]"
+		}
+		s += ": " + d.Message + "\n"
+		gutter := strings.Repeat(" ", len(strconv.Itoa(d.Start.Line)))
+		if d.Start.Line >= 1 {
+			if d.File != "" {
+				s += gutter + "--> " + d.File + ":" + strconv.Itoa(d.Start.Line) + ":" + strconv.Itoa(d.Start.Column) + "\n"
+			}
+			lines := sourceLines(d.File)
+			if d.Start.Line <= len(lines) {
+				s += gutter + " |\n" + excerpt(d, lines[d.Start.Line-1], gutter)
+			}
+		}
+		if _, ok := explain.Lookup(d.Code); ok {
+			s += gutter + " = help: run `pigeon explain " + d.Code + "` for details\n"
+		}
+	}
+	return s
+}
+
+// returns the numbered source line with the offending code underlined
+func excerpt(d diagnostic, text string, gutter string) string {
+	line := []rune(text)
+	// the underline copies any tabs before the offending code so that it lines up
+	underline := ""
+	for i := 0; i < d.Start.Column-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			underline += "\t"
+		} else {
+			underline += " "
+		}
+	}
+	width := 1
+	if d.End.Line == d.Start.Line && d.End.Column > d.Start.Column {
+		width = d.End.Column - d.Start.Column
+	}
+	return strconv.Itoa(d.Start.Line) + " | " + text + "\n" +
+		gutter + " | " + underline + strings.Repeat("^", width) + "\n"
+}
+
+// A diagnosticsError is a failed compilation reported as an error (e.g. by the debug adapter).
+type diagnosticsError []diagnostic
+
+func (diags diagnosticsError) Error() string {
+	return strings.TrimSuffix(formatDiagnostics(diags), "\n")
+}
+
+// prints the diagnostics (as JSON if jsonOutput is true)
+func printDiagnostics(diags []diagnostic, jsonOutput bool) {
+	if jsonOutput {
+		printDiagnosticsJSON(diags)
+		return
+	}
+	fmt.Print(formatDiagnostics(diags))
+}
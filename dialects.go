@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BrianWill/pigeon/goPigeon"
+	"github.com/BrianWill/pigeon/pigeon"
+)
+
+// A dialect is a language of the Pigeon family which compiles to Go.
+type dialect struct {
+	Name      string
+	Extension string // file extension of source files, including the dot
+	// compiles the source file into a Go program
+	// (outputDir is the import path prefix of the workspace module;
+	// if debug is true, the program is instrumented for the debugger)
+	Compile func(filename string, outputDir string, debug bool) (*program, []diagnostic)
+	// checks the source of the named file for editor tooling (without generating a program)
+	Analyze func(filename string, src []byte) *analysis
+	// runs the source file without the Go toolchain, returning the diagnostics if it fails to compile
+	// (nil if the dialect has no interpreter)
+	Interpret func(filename string) []diagnostic
+	// import path of the runtime package imported by the generated code
+	RuntimeImport string
+	// path of the generated main file, relative to the workspace
+	OutputFile string
+}
+
+// A program is the output of compiling a source file.
+type program struct {
+	Code   []byte // the generated Go code
+	Source string // the source file name, as given by the user
+	// source names of the generated Go functions, keyed by qualified Go name, e.g. "main.Foo": "foo"
+	FuncNames map[string]string
+	// lines of the source file on which breakpoints can be set, in ascending order
+	ValidBreakpoints []int
+	Packages         []programPackage // the generated packages of the files imported by the source file
+}
+
+// A programPackage is the Go package generated for an imported source file.
+type programPackage struct {
+	Dir    string // directory of the package, relative to the directory of the main package
+	Code   []byte // the generated Go code
+	Source string // the imported file name, relative to the directory of the program's source file
+}
+
+// An analysis is the result of checking a source file.
+type analysis struct {
+	Diagnostics []diagnostic
+	Symbols     []symbol // uses of names; empty if the dialect does not record them
+}
+
+// A diagnostic is a problem found by a compiler (as reported by 'pigeon check -json').
+type diagnostic struct {
+	File     string   `json:"file"`
+	Start    position `json:"start"`
+	End      position `json:"end"`      // just past the offending code, on the same line as Start
+	Severity string   `json:"severity"` // "error" or "warning"
+	Code     string   `json:"code"`     // e.g. "P0302" (empty if not about the source)
+	Message  string   `json:"message"`
+}
+
+// A position is a one-based line and column (Line is 0 if the position is unknown).
+type position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// A symbol is a use of a name in the source.
+type symbol struct {
+	Line      int
+	Column    int
+	Name      string
+	Info      string // description of the name, e.g. its type or signature
+	DefFile   string // the file of the name's definition ("" for the analyzed file itself)
+	DefLine   int    // position of the name's definition (0 if unknown)
+	DefColumn int
+}
+
+// returns the symbol whose name spans the position
+func (a *analysis) symbolAt(line int, column int) (symbol, bool) {
+	for _, s := range a.Symbols {
+		if s.Line == line && column >= s.Column && column < s.Column+len(s.Name) {
+			return s, true
+		}
+	}
+	return symbol{}, false
+}
+
+// registered dialects by name
+var dialects = map[string]*dialect{}
+
+// registered dialects by file extension
+var dialectExtensions = map[string]*dialect{}
+
+// import path prefix of packages in the workspace module
+const outputModule = "pigeon_output/"
+
+// registerDialect makes a dialect available to the driver.
+// Panics if the name or extension is already taken.
+func registerDialect(d *dialect) {
+	if _, ok := dialects[d.Name]; ok {
+		panic("Dialect registered twice: " + d.Name)
+	}
+	if _, ok := dialectExtensions[d.Extension]; ok {
+		panic("Dialect extension registered twice: " + d.Extension)
+	}
+	if d.OutputFile == "" {
+		d.OutputFile = "output.go"
+	}
+	dialects[d.Name] = d
+	dialectExtensions[d.Extension] = d
+}
+
+func init() {
+	registerDialect(&dialect{
+		Name:      "gopigeon",
+		Extension: ".gopigeon",
+		Compile: func(filename string, outputDir string, debug bool) (*program, []diagnostic) {
+			pkg, diags := goPigeon.Compile(filename, outputDir, debug)
+			if pkg == nil {
+				return nil, goPigeonDiagnostics(diags)
+			}
+			funcNames := map[string]string{}
+			addGoPigeonFuncNames(funcNames, "main", pkg)
+			var packages []programPackage
+			for _, imported := range pkg.Imports() {
+				addGoPigeonFuncNames(funcNames, outputDir+imported.Prefix, imported)
+				packages = append(packages, programPackage{
+					Dir:    imported.Prefix,
+					Code:   []byte(imported.Code),
+					Source: imported.Filename,
+				})
+			}
+			return &program{
+				Code:             []byte(pkg.Code),
+				Source:           filename,
+				FuncNames:        funcNames,
+				ValidBreakpoints: breakpointLines(pkg.ValidBreakpoints),
+				Packages:         packages,
+			}, nil
+		},
+		Analyze: func(filename string, src []byte) *analysis {
+			pkg, diags := goPigeon.Analyze(filename, src)
+			a := &analysis{Diagnostics: goPigeonDiagnostics(diags)}
+			if pkg != nil {
+				for _, s := range pkg.Symbols {
+					a.Symbols = append(a.Symbols, symbol{s.LineNumber, s.Column, s.Name, s.Info, s.DefFile, s.DefLine, s.DefColumn})
+				}
+			}
+			return a
+		},
+		RuntimeImport: runtimeModule + "/goPigeon/stdlib",
+	})
+	registerDialect(&dialect{
+		Name:      "pigeon",
+		Extension: ".pigeon",
+		Compile: func(filename string, outputDir string, debug bool) (*program, []diagnostic) {
+			pkg, diags := pigeon.Compile(filename, outputDir, debug)
+			if pkg == nil {
+				return nil, pigeonDiagnostics(diags)
+			}
+			funcNames := map[string]string{}
+			for name := range pkg.Funcs {
+				funcNames["main."+strings.Title(name)] = sourceFuncName(name)
+			}
+			return &program{
+				Code:             []byte(pkg.Code),
+				Source:           filename,
+				FuncNames:        funcNames,
+				ValidBreakpoints: breakpointLines(pkg.ValidBreakpoints),
+			}, nil
+		},
+		Analyze: func(filename string, src []byte) *analysis {
+			_, diags := pigeon.Analyze(filename, src)
+			return &analysis{Diagnostics: pigeonDiagnostics(diags)}
+		},
+		Interpret: func(filename string) []diagnostic {
+			pkg, diags := pigeon.Compile(filename, outputModule, false)
+			if pkg == nil {
+				return pigeonDiagnostics(diags)
+			}
+			pigeon.Interpret(pkg)
+			return nil
+		},
+		RuntimeImport: runtimeModule + "/pigeon/stdlib",
+	})
+}
+
+// adds the names of the functions and methods defined in the package's file, qualified by the Go package path
+func addGoPigeonFuncNames(funcNames map[string]string, path string, pkg *goPigeon.Package) {
+	for name, fn := range pkg.Funcs {
+		if fn.Pkg == pkg {
+			funcNames[path+"."+strings.Title(name)] = sourceFuncName(name)
+		}
+	}
+	for name, byStruct := range pkg.Methods {
+		for _, meth := range byStruct {
+			structName := meth.Receiver.Type.Type
+			if structName == "P" && len(meth.Receiver.Type.Params) == 1 {
+				structName = meth.Receiver.Type.Params[0].Type
+			}
+			if meth.Pkg == pkg {
+				funcNames[path+"."+structName+"."+strings.Title(name)] = structName + "." + name
+				funcNames[path+".(*"+structName+")."+strings.Title(name)] = structName + "." + name
+			}
+		}
+	}
+}
+
+func goPigeonDiagnostics(diags []goPigeon.Diagnostic) []diagnostic {
+	ds := make([]diagnostic, len(diags))
+	for i, d := range diags {
+		ds[i] = diagnostic{d.File, position{d.Start.Line, d.Start.Column}, position{d.End.Line, d.End.Column},
+			string(d.Severity), d.Code, d.Message}
+	}
+	return ds
+}
+
+func pigeonDiagnostics(diags []pigeon.Diagnostic) []diagnostic {
+	ds := make([]diagnostic, len(diags))
+	for i, d := range diags {
+		ds[i] = diagnostic{d.File, position{d.Start.Line, d.Start.Column}, position{d.End.Line, d.End.Column},
+			string(d.Severity), d.Code, d.Message}
+	}
+	return ds
+}
+
+// both compilers rename the main function to _main
+func sourceFuncName(name string) string {
+	if name == "_main" {
+		return "main"
+	}
+	return name
+}
+
+// converts the ValidBreakpoints of a compiled package to a sorted list of lines
+func breakpointLines(valid map[string]bool) []int {
+	lines := []int{}
+	for s := range valid {
+		line, err := strconv.Atoi(s)
+		if err == nil {
+			lines = append(lines, line)
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// returns the dialect of the named file. If name is not empty, it selects the dialect
+// regardless of the file's extension.
+func findDialect(filename string, name string) (*dialect, error) {
+	if name != "" {
+		d, ok := dialects[name]
+		if !ok {
+			return nil, errors.New("Unknown dialect: " + name + " (expecting one of: " + dialectNames() + ")")
+		}
+		return d, nil
+	}
+	d, ok := dialectExtensions[filepath.Ext(filename)]
+	if !ok {
+		return nil, errors.New("File has improper extension. Use --dialect to specify one of: " + dialectNames())
+	}
+	return d, nil
+}
+
+func dialectNames() string {
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	s := ""
+	for i, name := range names {
+		if i > 0 {
+			s += ", "
+		}
+		s += name
+	}
+	return s
+}
@@ -0,0 +1,153 @@
+package explain_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/BrianWill/pigeon/explain"
+	"github.com/BrianWill/pigeon/goPigeon"
+	"github.com/BrianWill/pigeon/pigeon"
+)
+
+var codePattern = regexp.MustCompile(`^P[0-9]{4}$`)
+
+// the files which GoPigeon examples can import, beside the example itself (example/example.gopigeon)
+var importable = fstest.MapFS{
+	"example/greeting.gopigeon": &fstest.MapFile{Data: []byte(
+		"func greet name Str : Str\n" +
+			"    return (concat \"hi, \" name)\n")},
+}
+
+// Each error code passed to msg or exprMsg in the goPigeon and pigeon packages has a catalog
+// entry, and each entry is for such a code.
+func TestCatalogCoversCodes(t *testing.T) {
+	emitted := map[string][]string{} // positions at which each code is reported
+	for _, dir := range []string{"../goPigeon", "../pigeon"} {
+		err := findCodes(dir, emitted)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(emitted) == 0 {
+		t.Fatal("No error codes found.")
+	}
+	codes := []string{}
+	for code := range emitted {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if _, ok := explain.Lookup(code); !ok {
+			t.Errorf("%s: no catalog entry (reported at %s)", code, emitted[code][0])
+		}
+	}
+	for _, code := range explain.Codes() {
+		if _, ok := emitted[code]; !ok {
+			t.Errorf("%s: catalog entry for a code the compilers never report", code)
+		}
+	}
+}
+
+// Each entry has both a wrong and a corrected example, and each example compiles as it claims:
+// a wrong example reports its entry's code, and a corrected example compiles without errors.
+func TestCatalogExamples(t *testing.T) {
+	for _, code := range explain.Codes() {
+		e, _ := explain.Lookup(code)
+		t.Run(code, func(t *testing.T) {
+			var wrong, corrected bool
+			for i, ex := range e.Examples {
+				if ex.Corrected {
+					corrected = true
+				} else {
+					wrong = true
+				}
+				reported, err := compile(ex)
+				if err != nil {
+					t.Errorf("example %d: %v", i+1, err)
+					continue
+				}
+				if ex.Corrected && len(reported) > 0 {
+					t.Errorf("corrected example %d reports %v", i+1, reported)
+				}
+				if !ex.Corrected && !contains(reported, code) {
+					t.Errorf("wrong example %d reports %v instead", i+1, reported)
+				}
+			}
+			if !wrong || !corrected {
+				t.Error("entry needs both a wrong and a corrected example")
+			}
+		})
+	}
+}
+
+// records the code literals of the msg and exprMsg calls in the Go files of the directory
+func findCodes(dir string, emitted map[string][]string) error {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				fn, ok := call.Fun.(*ast.Ident)
+				if !ok || (fn.Name != "msg" && fn.Name != "exprMsg") {
+					return true
+				}
+				for _, arg := range call.Args {
+					lit, ok := arg.(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					s, err := strconv.Unquote(lit.Value)
+					if err == nil && codePattern.MatchString(s) {
+						emitted[s] = append(emitted[s], fset.Position(lit.Pos()).String())
+					}
+				}
+				return true
+			})
+		}
+	}
+	return nil
+}
+
+// returns the codes of the errors reported by compiling the example
+func compile(ex explain.Example) ([]string, error) {
+	codes := []string{}
+	switch ex.Dialect {
+	case "Pigeon":
+		_, diags := pigeon.Analyze(filepath.Join("example", "example.pigeon"), []byte(ex.Source))
+		for _, d := range diags {
+			codes = append(codes, d.Code)
+		}
+	case "GoPigeon":
+		_, diags := goPigeon.CompileSource("example/example.gopigeon", []byte(ex.Source), goPigeon.Options{FS: importable})
+		for _, d := range diags {
+			codes = append(codes, d.Code)
+		}
+	default:
+		return nil, fmt.Errorf("unknown dialect %q", ex.Dialect)
+	}
+	return codes, nil
+}
+
+func contains(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+/*
+Package explain is the catalog of long-form explanations of the compile error codes
+(such as P0201) reported by the Pigeon and GoPigeon compilers.
+
+Each entry is a text file in the catalog directory, named for its code. The first line is
+a short title, and the rest is the explanation, which includes a wrong example and a corrected
+example. Each example starts with a line 'Wrong (Dialect):' or 'Corrected (Dialect):', where
+Dialect is Pigeon or GoPigeon, followed by a whole program indented four spaces. In examples,
+<TAB> and <CR> stand for a tab character and a lone carriage return.
+
+The tests of the package ('go test ./explain') check that every code the compilers
+can report has an entry, and that each example compiles with and without the error as it claims.
+*/
+package explain
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed catalog/*.txt
+var catalogFiles embed.FS
+
+// An Entry is the explanation of one error code.
+type Entry struct {
+	Code     string
+	Title    string
+	Text     string // everything after the title, including the examples
+	Examples []Example
+}
+
+// An Example is a program which does (or, if Corrected is true, does not) have the error.
+type Example struct {
+	Corrected bool
+	Dialect   string // "Pigeon" or "GoPigeon"
+	Source    string
+}
+
+// Lookup returns the entry for the code, e.g. "P0201".
+func Lookup(code string) (Entry, bool) {
+	data, err := catalogFiles.ReadFile("catalog/" + code + ".txt")
+	if err != nil {
+		return Entry{}, false
+	}
+	return parseEntry(code, string(data)), true
+}
+
+// Codes returns the codes which have entries, in ascending order.
+func Codes() []string {
+	files, err := catalogFiles.ReadDir("catalog")
+	if err != nil {
+		panic(err) // the directory is embedded
+	}
+	codes := []string{}
+	for _, f := range files {
+		codes = append(codes, strings.TrimSuffix(f.Name(), path.Ext(f.Name())))
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func parseEntry(code string, text string) Entry {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	lines := strings.Split(text, "\n")
+	e := Entry{Code: code, Title: strings.TrimSpace(lines[0])}
+	e.Text = strings.TrimSpace(strings.Join(lines[1:], "\n")) + "\n"
+	var example *Example
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "Wrong (") || strings.HasPrefix(line, "Corrected (") {
+			open := strings.Index(line, "(")
+			end := strings.Index(line, ")")
+			if end < open {
+				continue
+			}
+			e.Examples = append(e.Examples, Example{
+				Corrected: strings.HasPrefix(line, "Corrected"),
+				Dialect:   line[open+1 : end],
+			})
+			example = &e.Examples[len(e.Examples)-1]
+			continue
+		}
+		if example == nil {
+			continue
+		}
+		if strings.HasPrefix(line, "    ") {
+			example.Source += line[4:] + "\n"
+		} else if strings.TrimSpace(line) == "" {
+			if example.Source != "" {
+				example.Source += "\n"
+			}
+		} else {
+			example = nil
+		}
+	}
+	for i := range e.Examples {
+		src := strings.TrimRight(e.Examples[i].Source, "\n") + "\n"
+		src = strings.Replace(src, "<TAB>", "\t", -1)
+		e.Examples[i].Source = strings.Replace(src, "<CR>", "\r", -1)
+	}
+	return e
+}
@@ -3,9 +3,16 @@ package goPigeon
 import (
 	"errors"
 	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"io/fs"
 	"io/ioutil"
+	"math"
+	"path"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -13,22 +20,28 @@ import (
 /* All identifiers get prefixed with _ to avoid collisions with Go reserved words and predefined identifiers */
 // returns map of valid breakpoints
 func compile(pkg *Package, outputDir string) error {
-	code := "package main\n"
-
-	code += `import _fmt "fmt"
-import _std "github.com/BrianWill/pigeon/goPigeon/stdlib"
-`
+	file := &ast.File{Name: ast.NewIdent("main")}
+	if pkg.imported {
+		file.Name = ast.NewIdent(pkg.Prefix)
+	}
+	imports := &ast.GenDecl{Tok: token.IMPORT, Specs: []ast.Spec{
+		importSpec("_fmt", "fmt"),
+		importSpec("_std", "github.com/BrianWill/pigeon/goPigeon/stdlib"),
+	}}
+	imports.Specs = append(imports.Specs, compileImports(pkg.ImportedPackages, outputDir)...)
+	imports.Specs = append(imports.Specs, compileNativeImports(pkg)...)
+	file.Decls = append(file.Decls, imports)
 
 	err := processStructs(pkg)
 	if err != nil {
 		return err
 	}
-	c, err := compileInterfaces(pkg)
+	decls, err := compileInterfaces(pkg)
 	if err != nil {
 		return err
 	}
-	code += c
-	for _, st := range pkg.Structs {
+	file.Decls = append(file.Decls, decls...)
+	for _, st := range pkg.structsInOrder() {
 		if st.Pkg != pkg {
 			continue
 		}
@@ -36,66 +49,89 @@ import _std "github.com/BrianWill/pigeon/goPigeon/stdlib"
 		if err != nil {
 			return err
 		}
-		c, err := compileStruct(&st, pkg.Types)
+		decl, err := compileStruct(&st, pkg.Types)
 		if err != nil {
 			return err
 		}
-		code += c
+		file.Decls = append(file.Decls, decl)
+	}
+	// imported structs may implement the package's interfaces
+	for _, name := range pkg.typeNames() {
+		if st, ok := pkg.Types[name].(Struct); ok && st.Pkg != pkg {
+			err := findImplementors(&st, pkg)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	// check that all function parameter and return types are valid
-	for _, fn := range pkg.Funcs {
+	for _, fn := range pkg.funcsInOrder() {
 		_, err := getFunctionType(fn)
 		if err != nil {
 			return err
 		}
 	}
-	c, err = compileGlobals(pkg)
+	// an error stops the compilation of a global, function, or method but not of the others
+	var errs Diagnostics
+	decls, err = compileGlobals(pkg)
 	if err != nil {
-		return err
+		errs = errs.add(err)
 	}
-	code += c
-	for _, methByStruct := range pkg.Methods {
-		for _, m := range methByStruct {
-			c, err := compileMethod(m)
-			if err != nil {
-				return err
-			}
-			code += c
+	file.Decls = append(file.Decls, decls...)
+	for _, m := range pkg.methodsInOrder() {
+		decl, err := compileMethod(m)
+		if err != nil {
+			errs = errs.add(err)
+			continue
 		}
+		file.Decls = append(file.Decls, decl)
 	}
-	for _, fn := range pkg.Funcs {
+	for _, fn := range pkg.funcsInOrder() {
 		if fn.Pkg != pkg {
 			continue
 		}
-		c, err := compileFunc(fn)
+		decl, err := compileFunc(fn)
 		if err != nil {
-			return err
+			errs = errs.add(err)
+			continue
 		}
-		code += c
+		file.Decls = append(file.Decls, decl)
+	}
+	if len(errs) > 0 {
+		return errs.err()
 	}
 
-	code += `
-		
-	func main() {
-		_fmt.Println()
-		_std.NoOp()
-		_main()
+	if !pkg.imported {
+		file.Decls = append(file.Decls, &ast.FuncDecl{
+			Name: ast.NewIdent("main"),
+			Type: &ast.FuncType{Params: &ast.FieldList{}},
+			Body: block(
+				&ast.DeferStmt{Call: call("_std.Uncaught")},
+				callStmt("_fmt.Println"),
+				callStmt("_std.NoOp"),
+				callStmt("_main"),
+			),
+		})
+	}
+	// Go rejects unused imports
+	imports.Specs = usedImports(file, imports.Specs)
+	if len(imports.Specs) == 0 {
+		file.Decls = file.Decls[1:]
 	}
-	`
 
-	pkg.Code = code
-	return nil
+	pkg.Code, err = pkg.print(file)
+	return err
 }
 
 func findImplementors(st *Struct, pkg *Package) error {
 Outer:
-	for _, iface := range pkg.Interfaces {
+	for _, iface := range pkg.interfacesInOrder() {
 		for _, sig := range iface.Methods {
 			ft, ok := st.Methods[sig.Name]
 			if !ok {
 				continue Outer
 			}
-			mt, err := sig.getFunctionType(pkg)
+			mt, err := sig.getFunctionType(iface.Pkg)
 			if err != nil {
 				return err
 			}
@@ -103,86 +139,92 @@ Outer:
 				continue Outer
 			}
 		}
-		st.Implements[iface.Name] = true
+		st.Implements[iface.qualifiedName()] = true
 	}
 	return nil
 }
 
-func compileImports(imports map[string]ImportDefinition, packages map[string]*Package, outputDir string) (string, error) {
-	code := ""
-	for _, imp := range imports {
-		path, err := filepath.Abs(imp.Path)
-		if err != nil {
-			return "", err
-		}
-		code += "import _" + packages[path].Prefix + `"` + outputDir + packages[path].Prefix + `"` + "\n"
-	}
-	return code, nil
+func compileImports(packages map[string]*Package, outputDir string) []ast.Spec {
+	specs := []ast.Spec{}
+	paths := []string{}
+	for path := range packages {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return packages[paths[i]].Prefix < packages[paths[j]].Prefix
+	})
+	for _, path := range paths {
+		specs = append(specs, importSpec("_"+packages[path].Prefix, outputDir+packages[path].Prefix))
+	}
+	return specs
 }
 
-func compileNativeImports(imports map[string]string) string {
-	code := ""
-	for prefix, path := range imports {
-		code += "import " + prefix + `"` + path + `"` + "\n"
-	}
-	return code
+func compileNativeImports(pkg *Package) []ast.Spec {
+	specs := []ast.Spec{}
+	prefixes := []string{}
+	for prefix := range pkg.NativeImports {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		imp := pkg.NativeImports[prefix]
+		spec := importSpec(prefix, imp.Path)
+		pkg.mapLine(spec, imp.LineNumber)
+		specs = append(specs, spec)
+	}
+	return specs
 }
 
-func compileStruct(st *Struct, types map[string]DataType) (string, error) {
-	code := "type " + st.Name + " struct {\n"
+func compileStruct(st *Struct, types map[string]DataType) (ast.Decl, error) {
+	fields := &ast.FieldList{}
 	for i, n := range st.MemberNames {
-		t, err := compileType(st.MemberTypes[i], st.Pkg)
+		t, err := typeExpr(st.MemberTypes[i], st.Pkg)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		fields.List = append(fields.List, &ast.Field{Names: []*ast.Ident{ast.NewIdent(strings.Title(n))}, Type: t})
+	}
+	if st.NativeCode != "" {
+		native, err := st.Pkg.parseNative("type _ struct {", st.NativeCode, st.NativeLine)
+		if err != nil {
+			return nil, err
 		}
-		code += strings.Title(n) + " " + t + "\n"
+		nativeType := native.(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type
+		fields.List = append(fields.List, nativeType.(*ast.StructType).Fields.List...)
 	}
-	code += st.NativeCode
-	return code + "}\n", nil
+	decl := &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{
+		&ast.TypeSpec{Name: ast.NewIdent(st.Name), Type: &ast.StructType{Fields: fields}},
+	}}
+	st.Pkg.mapLine(decl, st.LineNumber)
+	return decl, nil
 }
 
-func compileInterfaces(pkg *Package) (string, error) {
-	code := "\n"
-	for _, inter := range pkg.Interfaces {
+func compileInterfaces(pkg *Package) ([]ast.Decl, error) {
+	decls := []ast.Decl{}
+	for _, inter := range pkg.interfacesInOrder() {
 		if inter.Pkg != pkg {
 			continue
 		}
-		code += "type " + inter.Name + " interface {\n"
+		methods := &ast.FieldList{}
 		for _, sig := range inter.Methods {
 			// validate each method
-			_, err := sig.getFunctionType(pkg)
+			ft, err := sig.getFunctionType(pkg)
 			if err != nil {
-				return "", err
-			}
-			code += sig.Name + "("
-			for _, pt := range sig.ParamTypes {
-				t, err := getDataType(pt, pkg)
-				if err != nil {
-					return "", err
-				}
-				c, err := compileType(t, pkg)
-				if err != nil {
-					return "", err
-				}
-				code += c + ", "
+				return nil, err
 			}
-			code += ") ("
-			for _, rt := range sig.ReturnTypes {
-				t, err := getDataType(rt, pkg)
-				if err != nil {
-					return "", err
-				}
-				c, err := compileType(t, pkg)
-				if err != nil {
-					return "", err
-				}
-				code += c + ", "
+			t, err := typeExpr(ft, pkg)
+			if err != nil {
+				return nil, err
 			}
-			code += ")\n"
+			methods.List = append(methods.List, &ast.Field{Names: []*ast.Ident{ast.NewIdent(strings.Title(sig.Name))}, Type: t})
 		}
-		code += "}\n"
+		decl := &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{
+			&ast.TypeSpec{Name: ast.NewIdent(inter.Name), Type: &ast.InterfaceType{Methods: methods}},
+		}}
+		pkg.mapLine(decl, inter.LineNumber)
+		decls = append(decls, decl)
 	}
-	return code, nil
+	return decls, nil
 }
 
 // populates pkg.Structs and verifies that no Struct is illegally recursive
@@ -199,9 +241,12 @@ func processStructs(pkg *Package) error {
 			s.MemberTypes[i] = dt
 			switch t := dt.(type) {
 			case Struct:
+				if t.Pkg != pkg {
+					break // (an imported struct can't contain a struct of this package)
+				}
 				for _, cst := range containingStructs {
 					if t.Name == cst.Name {
-						return msg(st.LineNumber, st.Column, "Struct cannot recursively contain itself.")
+						return msg(st.LineNumber, st.Column, "P0205", "Struct cannot recursively contain itself.")
 					}
 				}
 				err := processStruct(t, pkg, append(containingStructs, t))
@@ -212,7 +257,7 @@ func processStructs(pkg *Package) error {
 		}
 		return nil
 	}
-	for _, st := range pkg.StructDefs {
+	for _, st := range pkg.structDefsInOrder() {
 		s := Struct{
 			LineNumber:  st.LineNumber,
 			Column:      st.Column,
@@ -222,32 +267,41 @@ func processStructs(pkg *Package) error {
 			Implements:  map[string]bool{},
 			Methods:     map[string]FunctionType{},
 			NativeCode:  st.NativeCode,
+			NativeLine:  st.NativeLine,
 			Pkg:         st.Pkg,
 		}
 		pkg.Structs[s.Name] = s
 		pkg.Types[s.Name] = s
 	}
-	for _, st := range pkg.Structs {
+	for _, st := range pkg.structsInOrder() {
 		err := processStruct(st, pkg, []Struct{st})
 		if err != nil {
 			return err
 		}
 	}
-	for _, methByStruct := range pkg.Methods {
-		for _, meth := range methByStruct {
-			dt, err := getDataType(meth.Receiver.Type, pkg)
-			if err != nil {
-				return err
+	for _, meth := range pkg.methodsInOrder() {
+		dt, err := getDataType(meth.Receiver.Type, pkg)
+		if err != nil {
+			return err
+		}
+		if st, ok := dt.(Struct); ok {
+			if st.Pkg != pkg {
+				return msg(meth.LineNumber, meth.Column, "P0111", "Method receiver is a struct of an imported file: "+
+					"methods of a struct must be defined in the struct's file.")
 			}
-			if st, ok := dt.(Struct); ok {
-				funcType, err := meth.getFunctionType()
-				if err != nil {
-					return err
+			// (in Go, struct members and methods share names, so GoPigeon's do too)
+			for _, m := range st.MemberNames {
+				if strings.Title(m) == strings.Title(meth.Name) {
+					return msg(meth.LineNumber, meth.Column, "P0202", "Struct "+st.Name+" has both a member and a method named "+meth.Name+".")
 				}
-				st.Methods[meth.Name] = funcType
-			} else {
-				return msg(meth.LineNumber, meth.Column, "Method has non-struct receiver.")
 			}
+			funcType, err := meth.getFunctionType()
+			if err != nil {
+				return err
+			}
+			st.Methods[meth.Name] = funcType
+		} else {
+			return msg(meth.LineNumber, meth.Column, "P0111", "Method has non-struct receiver.")
 		}
 	}
 	return nil
@@ -279,7 +333,7 @@ func getFunctionType(fn FunctionDefinition) (FunctionType, error) {
 		}
 		returnTypes[i] = dt
 	}
-	return FunctionType{params, returnTypes}, nil
+	return FunctionType{params, returnTypes, len(fn.Parameters) > 0 && fn.Parameters[len(fn.Parameters)-1].Type.Variadic}, nil
 }
 
 func (s Signature) getFunctionType(pkg *Package) (FunctionType, error) {
@@ -299,7 +353,12 @@ func (s Signature) getFunctionType(pkg *Package) (FunctionType, error) {
 		}
 		returnTypes[i] = dt
 	}
-	return FunctionType{params, returnTypes}, nil
+	return FunctionType{params, returnTypes, isVariadic(s.ParamTypes)}, nil
+}
+
+// reports whether the last of the parameter types is variadic (...T)
+func isVariadic(params []ParsedDataType) bool {
+	return len(params) > 0 && params[len(params)-1].Variadic
 }
 
 // assumes both are valid types and that all type names are unique
@@ -311,7 +370,7 @@ func isType(child DataType, parent DataType, exact bool) bool {
 	case InterfaceDefinition:
 		switch p := parent.(type) {
 		case InterfaceDefinition:
-			return c.Name == p.Name
+			return c.Name == p.Name && c.Pkg == p.Pkg
 		}
 	case Struct:
 		switch p := parent.(type) {
@@ -321,13 +380,13 @@ func isType(child DataType, parent DataType, exact bool) bool {
 			}
 			// return true if the child implements the parent interface
 			for name, ok := range c.Implements {
-				if ok && name == p.Name {
+				if ok && name == p.qualifiedName() {
 					return true
 				}
 			}
 			return false
 		case StructDefinition:
-			return c.Name == p.Name
+			return c.Name == p.Name && c.Pkg == p.Pkg
 		}
 	case BuiltinType:
 		switch p := parent.(type) {
@@ -349,7 +408,7 @@ func isType(child DataType, parent DataType, exact bool) bool {
 func getDataType(parsed ParsedDataType, pkg *Package) (DataType, error) {
 	if parsed.Type == "A" {
 		if len(parsed.Params) != 2 {
-			return nil, msg(parsed.LineNumber, parsed.Column, "Array type must have two type parameters.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "Array type must have two type parameters.")
 		}
 		t, err := getDataType(parsed.Params[0], pkg)
 		if err != nil {
@@ -357,7 +416,7 @@ func getDataType(parsed ParsedDataType, pkg *Package) (DataType, error) {
 		}
 		size, err := strconv.Atoi(parsed.Params[1].Type)
 		if err != nil {
-			return nil, msg(parsed.LineNumber, parsed.Column, "Array type must have integer as second type parameter.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "Array type must have integer as second type parameter.")
 		}
 		return ArrayType{size, t}, nil
 	}
@@ -379,44 +438,53 @@ func getDataType(parsed ParsedDataType, pkg *Package) (DataType, error) {
 	}
 	switch parsed.Type {
 	case "Fn":
-		return FunctionType{params, returnTypes}, nil
+		return FunctionType{params, returnTypes, isVariadic(parsed.Params)}, nil
 	case "L":
 		if len(params) != 1 {
-			return nil, msg(parsed.LineNumber, parsed.Column, "List type has wrong number of type parameters.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "List type has wrong number of type parameters.")
 		}
 		return BuiltinType{"L", params}, nil
 	case "S":
 		if len(params) != 1 {
-			return nil, msg(parsed.LineNumber, parsed.Column, "List type has wrong number of type parameters.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "List type has wrong number of type parameters.")
 		}
 		return BuiltinType{"S", params}, nil
 	case "Ch":
 		if len(params) != 1 {
-			return nil, msg(parsed.LineNumber, parsed.Column, "Channel type has wrong number of type parameters.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "Channel type has wrong number of type parameters.")
 		}
 		return BuiltinType{"Ch", params}, nil
 	case "M":
 		if len(params) != 2 {
-			return nil, msg(parsed.LineNumber, parsed.Column, "Map type has wrong number of type parameters.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "Map type has wrong number of type parameters.")
 		}
 		return BuiltinType{"M", params}, nil
 	case "P":
 		if len(params) != 1 {
-			return nil, msg(parsed.LineNumber, parsed.Column, "Pointer type has wrong number of type parameters.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "Pointer type has wrong number of type parameters.")
 		}
 		return BuiltinType{"P", params}, nil
 	case "I", "F", "Byte", "Str", "Bool", "Err", "Any":
 		if len(params) != 0 {
-			return nil, msg(parsed.LineNumber, parsed.Column, "Type "+parsed.Type+" should not have any type parameters.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "Type "+parsed.Type+" should not have any type parameters.")
 		}
 		return BuiltinType{parsed.Type, params}, nil
 	default:
 		t, ok := pkg.Types[parsed.Type]
 		if !ok {
-			return nil, msg(parsed.LineNumber, parsed.Column, "Unknown type. "+fmt.Sprint(parsed.Type))
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0201", "Unknown type: "+parsed.Type+"."+
+				didYouMean(parsed.Type, builtinTypes, pkg.typeNames()))
 		}
 		if len(parsed.Params) > 0 || len(parsed.ReturnTypes) > 0 {
-			return nil, msg(parsed.LineNumber, parsed.Column, "Type "+parsed.Type+" should not have any type parameters.")
+			return nil, msg(parsed.LineNumber, parsed.Column, "P0106", "Type "+parsed.Type+" should not have any type parameters.")
+		}
+		switch t := t.(type) {
+		case Struct:
+			if st, ok := t.Pkg.StructDefs[t.Name]; ok {
+				pkg.addSymbolOf(t.Pkg, parsed.LineNumber, parsed.Column, parsed.Type, st.info(), st.LineNumber, st.Column)
+			}
+		case InterfaceDefinition:
+			pkg.addSymbolOf(t.Pkg, parsed.LineNumber, parsed.Column, parsed.Type, t.info(), t.LineNumber, t.Column)
 		}
 		return t, nil
 	}
@@ -436,20 +504,20 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 		switch t.Name {
 		case "I", "F", "Byte":
 			if len(t.Params) != 0 {
-				return "", nil, msg(line, column, "Invalid type expression: "+t.Name+" cannot have type parameters.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression: "+t.Name+" cannot have type parameters.")
 			}
 			if len(te.Operands) != 1 {
-				return "", nil, msg(line, column, "Invalid type expression: "+t.Name+" must have one (and just one) operand.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression: "+t.Name+" must have one (and just one) operand.")
 			}
 			expr, returnedTypes, err := compileExpression(te.Operands[0], pkg, locals)
 			if err != nil {
 				return "", nil, err
 			}
 			if len(returnedTypes) != 1 {
-				return "", nil, msg(line, column, "Invalid type expression: Operand expression must return one (and just one) value.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression: Operand expression must return one (and just one) value.")
 			}
 			if !isNumber(returnedTypes[0]) {
-				return "", nil, msg(line, column, "Invalid type expression: "+t.Name+" must have number operand.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression: "+t.Name+" must have number operand.")
 			}
 			numberTypes := map[string]string{
 				"I":    "int64",
@@ -460,17 +528,17 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 			return code, []DataType{t}, nil
 		case "Str":
 			if len(t.Params) != 0 {
-				return "", nil, msg(line, column, "Invalid type expression: "+t.Name+" cannot have type parameters.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression: "+t.Name+" cannot have type parameters.")
 			}
 			if len(te.Operands) != 1 {
-				return "", nil, msg(line, column, "Invalid type expression: "+t.Name+" must have one (and just one) operand.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression: "+t.Name+" must have one (and just one) operand.")
 			}
 			expr, returnedTypes, err := compileExpression(te.Operands[0], pkg, locals)
 			if err != nil {
 				return "", nil, err
 			}
 			if len(returnedTypes) != 1 {
-				return "", nil, msg(line, column, "Invalid type expression: Operand expression must return one (and just one) value.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression: Operand expression must return one (and just one) value.")
 			}
 			if isType(returnedTypes[0], BuiltinType{"L", []DataType{BuiltinType{"I", nil}}}, true) {
 				return "_std.Runelist2string(" + expr + ")", []DataType{t}, nil
@@ -487,10 +555,10 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 			} else if isType(returnedTypes[0], BuiltinType{"I", nil}, true) {
 				return "_std.FormatInt(" + expr + ")", []DataType{BuiltinType{"Str", nil}}, nil
 			}
-			return "", nil, msg(line, column, "Invalid type expression: Str operand must be a list or slice of strings or runes")
+			return "", nil, msg(line, column, "P0309", "Invalid type expression: Str operand must be a list or slice of strings or runes")
 		case "M":
 			if len(t.Params) != 2 {
-				return "", nil, msg(line, column, "Invalid type expression. Map must have two type parameters.")
+				return "", nil, msg(line, column, "P0106", "Invalid type expression. Map must have two type parameters.")
 			}
 			mapType, err := compileType(t, pkg)
 			if err != nil {
@@ -498,7 +566,7 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 			}
 			mapType += "{"
 			if len(te.Operands)%2 != 0 {
-				return "", nil, msg(line, column, "Invalid type expression. Map must have even number of operands.")
+				return "", nil, msg(line, column, "P0301", "Invalid type expression. Map must have even number of operands.")
 			}
 			for i := 0; i < len(te.Operands); i += 2 {
 				key, returnedTypes, err := compileExpression(te.Operands[i], pkg, locals)
@@ -506,14 +574,14 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 					return "", nil, err
 				}
 				if len(returnedTypes) != 1 || !isType(returnedTypes[0], t.Params[0], false) {
-					return "", nil, msg(line, column, "Invalid type expression. Map key of wrong type.")
+					return "", nil, msg(line, column, "P0309", "Invalid type expression. Map key of wrong type.")
 				}
 				val, returnedTypes, err := compileExpression(te.Operands[i+1], pkg, locals)
 				if err != nil {
 					return "", nil, err
 				}
 				if len(returnedTypes) != 1 || !isType(returnedTypes[0], t.Params[1], false) {
-					return "", nil, msg(line, column, "Invalid type expression. Map val of wrong type.")
+					return "", nil, msg(line, column, "P0309", "Invalid type expression. Map val of wrong type.")
 				}
 				mapType += key + ": " + val + ", "
 			}
@@ -521,7 +589,7 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 			return mapType, []DataType{t}, nil
 		case "L":
 			if len(t.Params) != 1 {
-				return "", nil, msg(line, column, "Invalid type expression. List must have one type parameter.")
+				return "", nil, msg(line, column, "P0106", "Invalid type expression. List must have one type parameter.")
 			}
 			expr := "(func () *_std.List {\n"
 			expr += "var _list _std.List = make([]interface{}, " + strconv.Itoa(len(te.Operands)) + ")\n"
@@ -531,7 +599,7 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 					return "", nil, err
 				}
 				if len(returnedTypes) != 1 || !isType(returnedTypes[0], t.Params[0], false) {
-					return "", nil, msg(line, column, "Invalid type expression. List val of wrong type.")
+					return "", nil, msg(line, column, "P0309", "Invalid type expression. List val of wrong type.")
 				}
 				expr += "_list[" + strconv.Itoa(i) + "] = " + val + "\n"
 			}
@@ -540,7 +608,7 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 			return expr, []DataType{t}, nil
 		case "S":
 			if len(t.Params) != 1 {
-				return "", nil, msg(line, column, "Invalid type expression. Slice must have one type parameter.")
+				return "", nil, msg(line, column, "P0106", "Invalid type expression. Slice must have one type parameter.")
 			}
 			param, err := compileType(t.Params[0], pkg)
 			if err != nil {
@@ -557,18 +625,41 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 					if isType(t.Params[0], BuiltinType{"Byte", nil}, true) && len(te.Operands) == 1 && isType(returnedTypes[0], BuiltinType{"Str", nil}, true) {
 						return "[]byte(" + val + ")", []DataType{BuiltinType{"S", []DataType{BuiltinType{"Byte", nil}}}}, nil
 					}
-					return "", nil, msg(line, column, "Invalid type expression. Slice value of wrong type.")
+					return "", nil, msg(line, column, "P0309", "Invalid type expression. Slice value of wrong type.")
 				}
 				code += val + ", "
 			}
 			code += "}"
 			return code, []DataType{t}, nil
+		case "Ch":
+			if len(t.Params) != 1 {
+				return "", nil, msg(line, column, "P0106", "Invalid type expression. Channel must have one type parameter.")
+			}
+			chanType, err := compileType(t, pkg)
+			if err != nil {
+				return "", nil, err
+			}
+			switch len(te.Operands) {
+			case 0:
+				return "make(" + chanType + ")", []DataType{t}, nil
+			case 1:
+				size, returnedTypes, err := compileExpression(te.Operands[0], pkg, locals)
+				if err != nil {
+					return "", nil, err
+				}
+				if len(returnedTypes) != 1 || !isInteger(returnedTypes[0]) {
+					return "", nil, msg(line, column, "P0309", "Invalid type expression. Channel capacity must be an integer.")
+				}
+				return "make(" + chanType + ", " + size + ")", []DataType{t}, nil
+			default:
+				return "", nil, msg(line, column, "P0301", "Invalid type expression. Channel has at most one operand (its capacity).")
+			}
 		default:
-			return "", nil, msg(line, column, "Invalid type expression. Cannot create type "+t.Name+".")
+			return "", nil, msg(line, column, "P0309", "Invalid type expression. Cannot create type "+t.Name+".")
 		}
 	case ArrayType:
 		if len(te.Operands) != t.Size {
-			return "", nil, msg(line, column, "Array expression must have number of operands that matches the length.")
+			return "", nil, msg(line, column, "P0301", "Array expression must have number of operands that matches the length.")
 		}
 		param, err := compileType(t.Type, pkg)
 		if err != nil {
@@ -581,36 +672,40 @@ func compileTypeExpression(te TypeExpression, pkg *Package, locals map[string]Va
 				return "", nil, err
 			}
 			if len(returnedTypes) != 1 || !isType(returnedTypes[0], t.Type, false) {
-				return "", nil, msg(line, column, "Invalid type expression. List val of wrong type.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression. List val of wrong type.")
 			}
 			code += val + ", "
 		}
 		code += "}"
 		return code, []DataType{t}, nil
 	case FunctionType:
-		return "", nil, msg(line, column, "Invalid type expression. Cannot create a function with a type expression.")
+		return "", nil, msg(line, column, "P0309", "Invalid type expression. Cannot create a function with a type expression.")
 	case Struct:
 		if len(t.MemberNames) != len(te.Operands) {
-			return "", nil, msg(line, column, "Invalid type expression. Wrong number of args for creating struct.")
+			return "", nil, msg(line, column, "P0301", "Invalid type expression. Wrong number of args for creating struct.")
 		}
-		code := t.Name + "{"
+		code := t.Pkg.qualify(pkg, t.Name) + "{"
 		for i, argType := range t.MemberTypes {
 			expr, returnTypes, err := compileExpression(te.Operands[i], pkg, locals)
 			if err != nil {
 				return "", nil, err
 			}
 			if len(returnTypes) != 1 || !isType(returnTypes[0], argType, false) {
-				return "", nil, msg(line, column, "Invalid type expression. Wrong type of arg for creating struct.")
+				return "", nil, msg(line, column, "P0309", "Invalid type expression. Wrong type of arg for creating struct.")
+			}
+			if t.NativeCode != "" {
+				// (the native fields are left zero)
+				code += strings.Title(t.MemberNames[i]) + ": "
 			}
 			code += expr + ", "
 		}
 		code += "}"
 		return code, []DataType{t}, nil
 	case InterfaceDefinition:
-		return "", nil, msg(line, column, "Invalid type expression. Cannot create interface value.")
+		return "", nil, msg(line, column, "P0309", "Invalid type expression. Cannot create interface value.")
 	}
 	// should be unreachable
-	return "", nil, msg(line, column, "Invalid type expression.")
+	return "", nil, msg(line, column, "P0309", "Invalid type expression.")
 }
 
 func compileExpression(e Expression, pkg *Package, locals map[string]Variable) (string, []DataType, error) {
@@ -659,30 +754,33 @@ func compileExpression(e Expression, pkg *Package, locals map[string]Variable) (
 					return "", nil, err
 				}
 				returnedTypes = []DataType{rt}
+				pkg.addSymbol(e.LineNumber, e.Column, name, "local "+name+" "+TypeString(rt), v.LineNumber, v.Column)
 			} else if v, ok := pkg.Globals[name]; ok {
-				if v.Pkg == pkg {
-					code = "G_" + name
-				} else {
-					code = "_" + v.Pkg.Prefix + ".G_" + name
+				if v.Invalid && v.Type.Type == "" {
+					return "", nil, errInvalidConst // (a constant of unknown type)
 				}
-				rt, err := getDataType(v.Type, pkg)
+				code = v.Pkg.qualify(pkg, "G_"+v.Name)
+				rt, err := getDataType(v.Type, v.Pkg)
 				if err != nil {
 					return "", nil, err
 				}
 				returnedTypes = []DataType{rt}
-			} else if v, ok := pkg.Funcs[name]; ok {
-				if v.Pkg == pkg {
-					code = strings.Title(name)
-				} else {
-					code = "_" + v.Pkg.Prefix + "." + name
+				kind := "global "
+				if v.Const {
+					kind = "const "
 				}
+				pkg.addSymbolOf(v.Pkg, e.LineNumber, e.Column, name, kind+name+" "+TypeString(rt), v.LineNumber, v.Column)
+			} else if v, ok := pkg.Funcs[name]; ok {
+				code = v.Pkg.qualify(pkg, strings.Title(v.Name))
 				rt, err := getFunctionType(v)
 				if err != nil {
 					return "", nil, err
 				}
 				returnedTypes = []DataType{rt}
+				pkg.addSymbolOf(v.Pkg, e.LineNumber, e.Column, name, v.info(), v.LineNumber, v.Column)
 			} else {
-				return "", nil, msg(e.LineNumber, e.Column, "Name is undefined: "+name)
+				return "", nil, msg(e.LineNumber, e.Column, "P0201", "Name is undefined: "+name+"."+
+					didYouMean(name, localNames(locals), pkg.topLevelNames()))
 			}
 		case NumberLiteral:
 			if strings.Index(e.Content, ".") == -1 {
@@ -705,36 +803,374 @@ func compileExpression(e Expression, pkg *Package, locals map[string]Variable) (
 	return code, returnedTypes, nil
 }
 
-func compileGlobals(pkg *Package) (string, error) {
-	code := ""
-	for _, g := range pkg.Globals {
-		if g.Pkg != pkg {
+func compileGlobals(pkg *Package) ([]ast.Decl, error) {
+	decls := []ast.Decl{}
+	// an error stops the compilation of a global but not of the others
+	var errs Diagnostics
+	err := resolveConstTypes(pkg)
+	if err != nil {
+		errs = errs.add(err)
+	}
+	for _, g := range pkg.globalsInOrder() {
+		if g.Pkg != pkg || g.Invalid {
 			continue
 		}
-		code += "var G_" + g.Name + " "
-		t, err := getDataType(g.Type, pkg)
+		decl, err := compileGlobal(g, pkg)
 		if err != nil {
-			return "", err
+			errs = errs.add(err)
+			continue
 		}
-		c, err := compileType(t, pkg)
+		decls = append(decls, decl)
+	}
+	return decls, errs.err()
+}
+
+func compileGlobal(g GlobalDefinition, pkg *Package) (ast.Decl, error) {
+	t, err := getDataType(g.Type, pkg)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := typeExpr(t, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if g.Const {
+		return compileConst(g, t, typ, pkg)
+	}
+	c, returnedTypes, err := compileExpression(g.Value, pkg, map[string]Variable{})
+	if err != nil {
+		return nil, err
+	}
+	if len(returnedTypes) != 1 {
+		return nil, exprMsg(g.Value, "P0307", "Initial value of global does not match the declared type.")
+	}
+	if !isType(returnedTypes[0], t, false) {
+		return nil, exprMsg(g.Value, "P0307", "Initial value of global does not match the declared type.")
+	}
+	val, err := parseExpr(c, g.LineNumber, g.Column)
+	if err != nil {
+		return nil, err
+	}
+	decl := varDecl("G_"+g.Name, typ, val)
+	pkg.mapLine(decl, g.LineNumber)
+	return decl, nil
+}
+
+// the operators whose generated code is a Go constant expression if their operands are
+var constOperators = map[string]bool{
+	"add": true, "sub": true, "mul": true, "div": true, "mod": true, "inc": true, "dec": true,
+	"eq": true, "neq": true, "lt": true, "gt": true, "lte": true, "gte": true,
+	"not": true, "and": true, "or": true, "band": true, "bor": true, "bxor": true, "concat": true,
+}
+
+// the types a constant can have
+var constTypes = map[string]bool{"I": true, "F": true, "Byte": true, "Str": true, "Bool": true}
+
+// returns the declaration of the constant (whose value is converted to the constant's type t,
+// so an integer value can initialize a Byte or F constant)
+func compileConst(g GlobalDefinition, t DataType, typ ast.Expr, pkg *Package) (ast.Decl, error) {
+	if bt, ok := t.(BuiltinType); !ok || !constTypes[bt.Name] {
+		return nil, msg(g.Type.LineNumber, g.Type.Column, "P0313", "A constant must be a number, string, or boolean (I, F, Byte, Str, or Bool).")
+	}
+	c, returnedTypes, err := compileExpression(g.Value, pkg, map[string]Variable{})
+	if err != nil {
+		return nil, err
+	}
+	if len(returnedTypes) != 1 {
+		return nil, exprMsg(g.Value, "P0307", "Value of constant does not match the declared type.")
+	}
+	switch {
+	case isType(returnedTypes[0], t, true):
+	case isInteger(returnedTypes[0]) && isNumber(t):
+		conversion, err := compileType(t, pkg)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		c = conversion + "(" + c + ")"
+	default:
+		return nil, exprMsg(g.Value, "P0307", "Value of constant does not match the declared type.")
+	}
+	val, err := parseExpr(c, g.LineNumber, g.Column)
+	if err != nil {
+		return nil, err
+	}
+	spec := &ast.ValueSpec{Names: []*ast.Ident{ast.NewIdent("G_" + g.Name)}, Type: typ, Values: []ast.Expr{val}}
+	decl := &ast.GenDecl{Tok: token.CONST, Specs: []ast.Spec{spec}}
+	pkg.mapLine(decl, g.LineNumber)
+	return decl, nil
+}
+
+// marks the error of a constant which uses a constant whose value has errors (which are reported
+// for that constant)
+var errInvalidConst = errors.New("the value of a constant used has errors")
+
+// checks that the value of each constant of the package is a constant expression, evaluates it,
+// and gives each constant defined without a type the type of its value. A constant whose value
+// has errors is marked Invalid (and the constants which use it are too, without more errors).
+func resolveConstTypes(pkg *Package) error {
+	resolving := map[string]bool{}
+	done := map[string]bool{} // the constants resolved (or marked Invalid)
+	var resolve func(g GlobalDefinition) error
+	// checks that the expression (in the value of constant g) is a constant expression,
+	// first resolving the constants it uses
+	var check func(g GlobalDefinition, e Expression) error
+	check = func(g GlobalDefinition, e Expression) error {
+		switch e := e.(type) {
+		case Token:
+			switch e.Type {
+			case NumberLiteral, StringLiteral, BooleanLiteral:
+				return nil
+			case IdentifierWord:
+				c, ok := pkg.Globals[e.Content]
+				if !ok {
+					return nil // reported as undefined when the value is compiled
+				}
+				if !c.Const {
+					return msg(e.LineNumber, e.Column, "P0313", "The value of constant "+g.Name+" must be a constant expression, but "+
+						e.Content+" is not a constant.")
+				}
+				if c.Invalid {
+					return errInvalidConst
+				}
+				if c.Pkg != pkg || done[c.Name] {
+					return nil
+				}
+				if resolving[c.Name] {
+					return msg(e.LineNumber, e.Column, "P0313", "Constant "+c.Name+" is defined in terms of itself.")
+				}
+				return resolve(c)
+			}
+		case Operation:
+			if !constOperators[e.Operator] {
+				return msg(e.LineNumber, e.Column, "P0313", "The value of constant "+g.Name+" must be a constant expression, "+
+					"but the "+e.Operator+" operation is evaluated only when the program runs.")
+			}
+			for _, operand := range e.Operands {
+				err := check(g, operand)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
 		}
-		code += c + " = "
-		c, returnedTypes, err := compileExpression(g.Value, pkg, map[string]Variable{})
+		line, column := position(e)
+		return msg(line, column, "P0313", "The value of constant "+g.Name+" must be a constant expression "+
+			"(made of literals, constants, and operations such as add).")
+	}
+	resolve = func(g GlobalDefinition) error {
+		resolving[g.Name] = true
+		defer delete(resolving, g.Name)
+		err := resolveConst(&g, check)
 		if err != nil {
-			return "", err
+			g.Invalid = true
+		}
+		pkg.Globals[g.Name] = g
+		done[g.Name] = true
+		return err
+	}
+	var errs Diagnostics
+	for _, g := range pkg.globalsInOrder() {
+		if g.Pkg == pkg && g.Const && !done[g.Name] {
+			err := resolve(pkg.Globals[g.Name])
+			if err != nil && err != errInvalidConst {
+				errs = errs.add(err)
+			}
 		}
+	}
+	return errs.err()
+}
+
+// checks the value of the constant (with check), then gives the constant its type (if it has none)
+// and its value
+func resolveConst(g *GlobalDefinition, check func(g GlobalDefinition, e Expression) error) error {
+	err := check(*g, g.Value)
+	if err != nil {
+		return err
+	}
+	_, returnedTypes, err := compileExpression(g.Value, g.Pkg, map[string]Variable{})
+	if err != nil {
+		return err
+	}
+	if g.Type.Type == "" {
 		if len(returnedTypes) != 1 {
-			return "", msg(g.LineNumber, g.Column, "Initial value of global does not match the declared type.")
+			return exprMsg(g.Value, "P0313", "A constant must be a number, string, or boolean (I, F, Byte, Str, or Bool).")
+		}
+		bt, ok := returnedTypes[0].(BuiltinType)
+		if !ok || !constTypes[bt.Name] {
+			return exprMsg(g.Value, "P0313", "A constant must be a number, string, or boolean (I, F, Byte, Str, or Bool).")
+		}
+		g.Type = ParsedDataType{g.LineNumber, g.Column, bt.Name, nil, nil, false}
+	}
+	val, _, err := evalConst(*g, g.Value)
+	if err != nil {
+		return err
+	}
+	// (a value of the wrong type is reported when the constant is compiled)
+	if val != nil && isNumericConst(val) && !representable(val, g.Type.Type) {
+		return exprMsg(g.Value, "P0315", "The value of constant "+g.Name+", "+val.String()+
+			", can't be represented as "+typeArticle(g.Type.Type)+".")
+	}
+	g.Constant = val
+	return nil
+}
+
+// evaluates an expression of the value of constant g, returning the value and the name of its type.
+// The value is nil if it isn't known (e.g. the expression has a type error, reported when the
+// constant is compiled).
+func evalConst(g GlobalDefinition, e Expression) (constant.Value, string, error) {
+	switch e := e.(type) {
+	case Token:
+		switch e.Type {
+		case NumberLiteral:
+			typ, tok := "I", token.INT
+			if strings.Contains(e.Content, ".") {
+				typ, tok = "F", token.FLOAT
+			}
+			val := constant.MakeFromLiteral(strings.TrimPrefix(e.Content, "-"), tok, 0)
+			if val.Kind() == constant.Unknown {
+				return nil, typ, nil
+			}
+			if strings.HasPrefix(e.Content, "-") {
+				val = constant.UnaryOp(token.SUB, val, 0)
+			}
+			return val, typ, nil
+		case StringLiteral:
+			s, err := strconv.Unquote(e.Content)
+			if err != nil {
+				return nil, "Str", nil
+			}
+			return constant.MakeString(s), "Str", nil
+		case BooleanLiteral:
+			return constant.MakeBool(e.Content == "true"), "Bool", nil
+		case IdentifierWord:
+			c, ok := g.Pkg.Globals[e.Content]
+			if !ok || c.Constant == nil {
+				return nil, "", nil
+			}
+			return c.Constant, c.Type.Type, nil
+		}
+	case Operation:
+		operands := make([]constant.Value, len(e.Operands))
+		var typ string
+		for i, operand := range e.Operands {
+			val, t, err := evalConst(g, operand)
+			if err != nil || val == nil {
+				return nil, "", err
+			}
+			if i == 0 {
+				typ = t
+			} else if val.Kind() != operands[0].Kind() {
+				return nil, "", nil
+			}
+			operands[i] = val
 		}
-		if !isType(returnedTypes[0], t, false) {
-			return "", msg(g.LineNumber, g.Column, "Initial value of global does not match the declared type.")
+		return evalConstOperation(g, e, operands, typ)
+	}
+	return nil, "", nil
+}
+
+// the Go operators of the operations whose operands are folded left to right
+var constBinaryOperators = map[string]token.Token{
+	"add": token.ADD, "sub": token.SUB, "mul": token.MUL, "div": token.QUO, "mod": token.REM,
+	"band": token.AND, "bor": token.OR, "bxor": token.XOR, "and": token.LAND, "or": token.LOR, "concat": token.ADD,
+}
+
+// the Go operators of the comparison operations (which compare each operand with the next)
+var constComparisons = map[string]token.Token{
+	"eq": token.EQL, "neq": token.NEQ, "lt": token.LSS, "gt": token.GTR, "lte": token.LEQ, "gte": token.GEQ,
+}
+
+// evaluates an operation of the value of constant g on the values of its operands (the first of type typ)
+func evalConstOperation(g GlobalDefinition, o Operation, operands []constant.Value, typ string) (constant.Value, string, error) {
+	if op, ok := constComparisons[o.Operator]; ok {
+		if op != token.EQL && op != token.NEQ && operands[0].Kind() == constant.Bool {
+			return nil, "", nil
+		}
+		result := true
+		for i := 0; i < len(operands)-1; i++ {
+			result = result && constant.Compare(operands[i], op, operands[i+1])
+		}
+		return constant.MakeBool(result), "Bool", nil
+	}
+	switch o.Operator {
+	case "not":
+		return constant.UnaryOp(token.NOT, operands[0], 0), "Bool", nil
+	case "inc":
+		operands = append(operands, constant.MakeInt64(1))
+		o.Operator = "add"
+	case "dec":
+		operands = append(operands, constant.MakeInt64(1))
+		o.Operator = "sub"
+	case "mod":
+		// (the operands are converted to I)
+		for i, val := range operands {
+			if operands[i] = constant.ToInt(val); operands[i].Kind() != constant.Int {
+				return nil, "", nil
+			}
+		}
+		typ = "I"
+	}
+	op, ok := constBinaryOperators[o.Operator]
+	if !ok {
+		return nil, "", nil
+	}
+	if op == token.QUO && operands[0].Kind() == constant.Int {
+		op = token.QUO_ASSIGN // (integer division)
+	}
+	val := operands[0]
+	for _, operand := range operands[1:] {
+		if (o.Operator == "div" || o.Operator == "mod") && constant.Sign(operand) == 0 {
+			return nil, "", exprMsg(o, "P0315", "Division by zero in the value of constant "+g.Name+".")
 		}
-		code += c + "\n"
-		pkg.ValidBreakpoints[strconv.Itoa(g.LineNumber)] = true
+		val = constant.BinaryOp(val, op, operand)
+	}
+	if isNumericConst(val) && !representable(val, typ) {
+		return nil, "", exprMsg(o, "P0315", "In the value of constant "+g.Name+", the "+o.Operator+" operation's result, "+
+			val.String()+", can't be represented as "+typeArticle(typ)+".")
 	}
-	return code, nil
+	return val, typ, nil
+}
+
+func isNumericConst(val constant.Value) bool {
+	return val.Kind() == constant.Int || val.Kind() == constant.Float
+}
+
+// reports whether the number is a value of the type (I, F, or Byte)
+func representable(val constant.Value, typ string) bool {
+	switch typ {
+	case "I":
+		_, exact := constant.Int64Val(constant.ToInt(val))
+		return exact
+	case "Byte":
+		n, exact := constant.Uint64Val(constant.ToInt(val))
+		return exact && n <= 255
+	case "F":
+		f, _ := constant.Float64Val(constant.ToFloat(val))
+		return !math.IsInf(f, 0)
+	}
+	return true
+}
+
+// returns the name of the type as it reads in a message, e.g. "an I"
+func typeArticle(typ string) string {
+	switch typ {
+	case "I", "F":
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+// returns the name of the constant if the expression is the name of a constant (which can't be changed)
+func constName(e Expression, pkg *Package, locals map[string]Variable) (string, bool) {
+	t, ok := e.(Token)
+	if !ok || t.Type != IdentifierWord {
+		return "", false
+	}
+	if _, ok := locals[t.Content]; ok {
+		return "", false
+	}
+	g, ok := pkg.Globals[t.Content]
+	return t.Content, ok && g.Const
 }
 
 func isList(dt DataType) (DataType, bool) {
@@ -753,6 +1189,26 @@ func isNumber(dt DataType) bool {
 	return t.Name == "I" || t.Name == "F" || t.Name == "Byte"
 }
 
+// reports whether values of the type can be compared with == (as in Go, slices, maps, and functions
+// can't be, nor can arrays and structs which hold them)
+func isComparable(dt DataType) bool {
+	switch t := dt.(type) {
+	case BuiltinType:
+		return t.Name != "S" && t.Name != "M"
+	case FunctionType:
+		return false
+	case ArrayType:
+		return isComparable(t.Type)
+	case Struct:
+		for _, mt := range t.MemberTypes {
+			if !isComparable(mt) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func isChannel(dt DataType) (bool, DataType) {
 	t, ok := dt.(BuiltinType)
 	if !ok || t.Name != "Ch" {
@@ -777,6 +1233,15 @@ func isMap(dt DataType) (DataType, DataType, bool) {
 	return t.Params[0], t.Params[1], true
 }
 
+// returns the syntax tree of the compiled type
+func typeExpr(dt DataType, pkg *Package) (ast.Expr, error) {
+	code, err := compileType(dt, pkg)
+	if err != nil {
+		return nil, err
+	}
+	return parseExpr(code, 0, 0)
+}
+
 // assumes a valid data type. Accepts Struct but not a StructDefinition
 func compileType(dt DataType, pkg *Package) (string, error) {
 	switch t := dt.(type) {
@@ -834,18 +1299,16 @@ func compileType(dt DataType, pkg *Package) (string, error) {
 		}
 		return "[" + strconv.Itoa(t.Size) + "]" + param, nil
 	case InterfaceDefinition:
-		if t.Pkg != pkg {
-			return "_" + t.Pkg.Prefix + "." + t.Name, nil
-		}
-		return t.Name, nil
+		return t.Pkg.qualify(pkg, t.Name), nil
 	case Struct:
-		if t.Pkg != pkg {
-			return "_" + t.Pkg.Prefix + "." + t.Name, nil
-		}
-		return t.Name, nil
+		return t.Pkg.qualify(pkg, t.Name), nil
 	case FunctionType:
 		typeStr := "func( "
-		for _, paramType := range t.Params {
+		for i, paramType := range t.Params {
+			if t.Variadic && i == len(t.Params)-1 {
+				paramType = paramType.(BuiltinType).Params[0]
+				typeStr += "..."
+			}
 			s, err := compileType(paramType, pkg)
 			if err != nil {
 				return "", err
@@ -866,350 +1329,660 @@ func compileType(dt DataType, pkg *Package) (string, error) {
 		}
 		return typeStr, nil
 	case StructDefinition:
-		return "", msg(t.LineNumber, t.Column, "Invalid type")
+		return "", msg(t.LineNumber, t.Column, "P0309", "Invalid type")
 	}
 
 	return "", nil
 }
 
-// returns code snippet ending with '\n\n'
-func compileFunc(fn FunctionDefinition) (string, error) {
-	locals := map[string]Variable{}
-	header := "func " + strings.Title(fn.Name) + "("
-	for i, param := range fn.Parameters {
-		dt, err := getDataType(param.Type, fn.Pkg)
+// returns the return types of a function and its Go result list
+func compileReturnTypes(parsed []ParsedDataType, pkg *Package) ([]DataType, *ast.FieldList, error) {
+	returnTypes := make([]DataType, len(parsed))
+	results := &ast.FieldList{}
+	for i, rt := range parsed {
+		dt, err := getDataType(rt, pkg)
 		if err != nil {
-			return "", err
+			return nil, nil, err
 		}
-		typeCode, err := compileType(dt, fn.Pkg)
+		returnTypes[i] = dt
+		typ, err := typeExpr(dt, pkg)
 		if err != nil {
-			return "", err
+			return nil, nil, err
 		}
-		header += param.Name + " " + typeCode
-		if i < len(fn.Parameters)-1 {
-			header += ", "
-		}
-		locals[param.Name] = param
+		results.List = append(results.List, &ast.Field{Type: typ})
 	}
-	header += ") ("
-	returnTypes := make([]DataType, len(fn.ReturnTypes))
-	for i, rt := range fn.ReturnTypes {
-		dt, err := getDataType(rt, fn.Pkg)
+	if len(parsed) == 0 {
+		return returnTypes, nil, nil
+	}
+	return returnTypes, results, nil
+}
+
+// returns the Go type of a parameter (for a variadic parameter ...T, whose type is S<T>, the Go type is ...T)
+func compileParamType(param Variable, pkg *Package) (ast.Expr, error) {
+	dt, err := getDataType(param.Type, pkg)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := typeExpr(dt, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if param.Type.Variadic {
+		return &ast.Ellipsis{Elt: typ.(*ast.ArrayType).Elt}, nil
+	}
+	return typ, nil
+}
+
+// returns the declarations of the variables of a locals statement (adding the variables to locals)
+func compileLocals(s LocalsStatement, pkg *Package, locals map[string]Variable) ([]ast.Stmt, error) {
+	stmts := []ast.Stmt{}
+	names := []string{}
+	for _, v := range s.Vars {
+		if _, ok := locals[v.Name]; ok {
+			return nil, msg(v.LineNumber, v.Column, "P0202", "Local variable "+v.Name+" is already defined as a parameter (or in an enclosing function).")
+		}
+		locals[v.Name] = v
+		dt, err := getDataType(v.Type, pkg)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		returnTypes[i] = dt
-		typeCode, err := compileType(dt, fn.Pkg)
+		typ, err := typeExpr(dt, pkg)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		header += typeCode
-		if i < len(fn.ReturnTypes)-1 {
-			header += ", "
+		var val ast.Expr
+		if t, ok := dt.(BuiltinType); ok {
+			switch t.Name {
+			case "L":
+				val = call("new", ident("_std.List"))
+			case "M", "Ch":
+				makeTyp, err := typeExpr(dt, pkg)
+				if err != nil {
+					return nil, err
+				}
+				val = call("make", makeTyp)
+			}
 		}
+		stmts = append(stmts, &ast.DeclStmt{Decl: varDecl(v.Name, typ, val)})
+		names = append(names, v.Name)
 	}
-	header += ") {\n"
-	if fn.NativeCode != "" {
-		return header + fn.NativeCode + "\n}\n", nil
-	}
-	if len(fn.Body) < 1 {
-		return "", msg(fn.LineNumber, fn.Column, "Function should contain at least one statement.")
+	stmts = append(stmts, callStmt("_std.NoOp", idents(names...)...))
+	pkg.mapLine(stmts[0], s.LineNumber)
+	return stmts, nil
+}
+
+func compileFunc(fn FunctionDefinition) (*ast.FuncDecl, error) {
+	locals := map[string]Variable{}
+	params := &ast.FieldList{}
+	for _, param := range fn.Parameters {
+		typ, err := compileParamType(param, fn.Pkg)
+		if err != nil {
+			return nil, err
+		}
+		params.List = append(params.List, &ast.Field{Names: []*ast.Ident{ast.NewIdent(param.Name)}, Type: typ})
+		locals[param.Name] = param
+	}
+	returnTypes, results, err := compileReturnTypes(fn.ReturnTypes, fn.Pkg)
+	if err != nil {
+		return nil, err
+	}
+	decl := &ast.FuncDecl{
+		Name: ast.NewIdent(strings.Title(fn.Name)),
+		Type: &ast.FuncType{Params: params, Results: results},
+		Body: block(),
+	}
+	fn.Pkg.mapLine(decl, fn.LineNumber)
+	if fn.NativeCode != "" {
+		native, err := fn.Pkg.parseNative("func _() {", fn.NativeCode, fn.NativeLine)
+		if err != nil {
+			return nil, err
+		}
+		decl.Body.List = native.(*ast.FuncDecl).Body.List
+		return decl, nil
+	}
+	if fn.BodyInvalid {
+		return decl, nil
+	}
+	if len(fn.Body) < 1 {
+		return nil, msg(fn.LineNumber, fn.Column, "P0110", "Function should contain at least one statement.")
 	}
 	bodyStatements := fn.Body
 	// account for locals statement
 	if localsStatement, ok := bodyStatements[0].(LocalsStatement); ok {
-		for _, v := range localsStatement.Vars {
-			header += "var "
-			if _, ok := locals[v.Name]; ok {
-				return "", msg(v.LineNumber, v.Column, "Local variable "+v.Name+" is already defined as a parameter.")
-			}
-			locals[v.Name] = v
-			dt, err := getDataType(v.Type, fn.Pkg)
-			if err != nil {
-				return "", err
-			}
-			typeCode, err := compileType(dt, fn.Pkg)
-			if err != nil {
-				return "", err
-			}
-			header += v.Name + " " + typeCode
-			if t, ok := dt.(BuiltinType); ok {
-				switch t.Name {
-				case "L":
-					header += " = new(_std.List)"
-				case "M", "Ch":
-					header += " = make(" + typeCode + ")"
-				}
-			}
-			header += "\n"
-		}
-		header += "_std.NoOp("
-		for _, v := range localsStatement.Vars {
-			header += v.Name + ","
+		stmts, err := compileLocals(localsStatement, fn.Pkg, locals)
+		if err != nil {
+			return nil, err
 		}
-		header += ")\n"
+		decl.Body.List = append(decl.Body.List, stmts...)
 		bodyStatements = bodyStatements[1:]
 	}
-	//header += genDebugFn(locals, fn.Pkg.Globals, fn.Pkg)
-	body, err := compileBody(bodyStatements, returnTypes, fn.Pkg, locals, false, len(returnTypes) > 0)
+	name := fn.Name
+	if name == "_main" {
+		name = "main"
+	}
+	stmts, bodyStatements, err := compileLocalFuncs(bodyStatements, name, fn.Pkg, locals)
+	if err != nil {
+		return nil, err
+	}
+	decl.Body.List = append(decl.Body.List, stmts...)
+	if fn.Pkg.Debug {
+		stmts, err := parseStmts(genDebugFn(locals, fn.Pkg.Globals, fn.Pkg)+genDebugEnter(name), fn.LineNumber, fn.Column)
+		if err != nil {
+			return nil, err
+		}
+		decl.Body.List = append(decl.Body.List, stmts...)
+	}
+	body, err := compileBody(bodyStatements, returnTypes, fn.Pkg, locals, nil, len(returnTypes) > 0)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return header + body + "\n}\n", nil
+	decl.Body.List = append(decl.Body.List, body...)
+	return decl, nil
 }
 
-// returns code snippet ending with '\n\n'
-func compileMethod(meth MethodDefinition) (string, error) {
+func compileMethod(meth MethodDefinition) (*ast.FuncDecl, error) {
 	locals := map[string]Variable{}
 	dt, err := getDataType(meth.Receiver.Type, meth.Pkg)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	receiverType, err := compileType(dt, meth.Pkg)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	header := "func (" + meth.Receiver.Name + " " + receiverType + ") " + meth.Name + "("
+	recvTyp, err := parseExpr(receiverType, meth.LineNumber, meth.Column)
+	if err != nil {
+		return nil, err
+	}
+	recv := &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(meth.Receiver.Name)}, Type: recvTyp}}}
 	locals[meth.Receiver.Name] = meth.Receiver
-	for i, param := range meth.Parameters {
+	params := &ast.FieldList{}
+	for _, param := range meth.Parameters {
 		if _, ok := locals[param.Name]; ok {
-			return "", msg(meth.LineNumber, meth.Column, "method cannot have two parameters of the same name")
+			return nil, msg(meth.LineNumber, meth.Column, "P0202", "method cannot have two parameters of the same name")
 		}
-		dt, err := getDataType(param.Type, meth.Pkg)
+		typ, err := compileParamType(param, meth.Pkg)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		params.List = append(params.List, &ast.Field{Names: []*ast.Ident{ast.NewIdent(param.Name)}, Type: typ})
+		locals[param.Name] = param
+	}
+	returnTypes, results, err := compileReturnTypes(meth.ReturnTypes, meth.Pkg)
+	if err != nil {
+		return nil, err
+	}
+	decl := &ast.FuncDecl{
+		Recv: recv,
+		Name: ast.NewIdent(strings.Title(meth.Name)),
+		Type: &ast.FuncType{Params: params, Results: results},
+		Body: block(),
+	}
+	meth.Pkg.mapLine(decl, meth.LineNumber)
+	if meth.BodyInvalid {
+		return decl, nil
+	}
+	if len(meth.Body) < 1 {
+		return nil, msg(meth.LineNumber, meth.Column, "P0110", "FMethod should contain at least one statement.")
+	}
+	bodyStatements := meth.Body
+	if localsStatement, ok := bodyStatements[0].(LocalsStatement); ok {
+		stmts, err := compileLocals(localsStatement, meth.Pkg, locals)
+		if err != nil {
+			return nil, err
 		}
-		typeCode, err := compileType(dt, meth.Pkg)
+		decl.Body.List = append(decl.Body.List, stmts...)
+		bodyStatements = bodyStatements[1:]
+	}
+	name := strings.TrimPrefix(receiverType, "*") + "." + meth.Name
+	stmts, bodyStatements, err := compileLocalFuncs(bodyStatements, name, meth.Pkg, locals)
+	if err != nil {
+		return nil, err
+	}
+	decl.Body.List = append(decl.Body.List, stmts...)
+	if meth.Pkg.Debug {
+		stmts, err := parseStmts(genDebugFn(locals, meth.Pkg.Globals, meth.Pkg)+genDebugEnter(name), meth.LineNumber, meth.Column)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		header += param.Name + " " + typeCode
-		if i < len(meth.Parameters)-1 {
-			header += ", "
+		decl.Body.List = append(decl.Body.List, stmts...)
+	}
+	body, err := compileBody(bodyStatements, returnTypes, meth.Pkg, locals, nil, len(returnTypes) > 0)
+	if err != nil {
+		return nil, err
+	}
+	decl.Body.List = append(decl.Body.List, body...)
+	return decl, nil
+}
+
+// Compiles the localfunc statements at the start of the statements (after any locals statement),
+// returning the remaining statements. Each local function is a variable of the enclosing function
+// (added to locals), which is assigned a closure. The closure's body can use the enclosing function's
+// parameters and locals (and the local functions, including itself), but can't redefine their names.
+// (enclosing names the enclosing function for the debugger)
+func compileLocalFuncs(statements []Statement, enclosing string,
+	pkg *Package, locals map[string]Variable) ([]ast.Stmt, []Statement, error) {
+	stmts := []ast.Stmt{}
+	for len(statements) > 0 {
+		s, ok := statements[0].(LocalFuncStatement)
+		if !ok {
+			break
 		}
-		if _, ok := locals[param.Name]; ok {
-			return "", msg(meth.LineNumber, meth.Column, "method cannot have two parameters of the same name")
+		statements = statements[1:]
+		if v, ok := locals[s.Name]; ok {
+			kind := "a parameter or local variable"
+			if isLocalFunc(v) {
+				kind = "a localfunc"
+			}
+			return nil, nil, msg(s.LineNumber, s.Column, "P0202", "localfunc "+s.Name+" is already defined as "+kind+".")
 		}
-		locals[param.Name] = param
-	}
-	header += ") ("
-	returnTypes := make([]DataType, len(meth.ReturnTypes))
-	for i, rt := range meth.ReturnTypes {
-		dt, err := getDataType(rt, meth.Pkg)
+		parsedType := ParsedDataType{s.LineNumber, s.Column, "Fn", nil, s.ReturnTypes, false}
+		for _, param := range s.Parameters {
+			parsedType.Params = append(parsedType.Params, param.Type)
+		}
+		fnType, err := getDataType(parsedType, pkg)
 		if err != nil {
-			return "", err
+			return nil, nil, err
 		}
-		returnTypes[i] = dt
-		typeCode, err := compileType(dt, meth.Pkg)
+		typ, err := typeExpr(fnType, pkg)
 		if err != nil {
-			return "", err
+			return nil, nil, err
 		}
-		header += typeCode
-		if i < len(meth.ReturnTypes)-1 {
-			header += ", "
+		locals[s.Name] = Variable{s.LineNumber, s.Column, s.Name, parsedType}
+		fnLocals := map[string]Variable{}
+		for k, v := range locals {
+			fnLocals[k] = v
 		}
-	}
-	header += ") {\n"
-	if len(meth.Body) < 1 {
-		return "", msg(meth.LineNumber, meth.Column, "FMethod should contain at least one statement.")
-	}
-	bodyStatements := meth.Body
-	if localsStatement, ok := bodyStatements[0].(LocalsStatement); ok {
-		for _, v := range localsStatement.Vars {
-			header += "var "
-			if _, ok := locals[v.Name]; ok {
-				return "", msg(v.LineNumber, v.Column, "Local variable "+v.Name+" is already defined as a parameter.")
+		params := &ast.FieldList{}
+		for _, param := range s.Parameters {
+			if _, ok := fnLocals[param.Name]; ok {
+				return nil, nil, msg(param.LineNumber, param.Column, "P0202", "Parameter "+param.Name+
+					" of local function "+s.Name+" is already defined in the enclosing function.")
 			}
-			locals[v.Name] = v
-			dt, err := getDataType(v.Type, meth.Pkg)
+			paramTyp, err := compileParamType(param, pkg)
 			if err != nil {
-				return "", err
+				return nil, nil, err
 			}
-			typeCode, err := compileType(dt, meth.Pkg)
+			params.List = append(params.List, &ast.Field{Names: []*ast.Ident{ast.NewIdent(param.Name)}, Type: paramTyp})
+			fnLocals[param.Name] = param
+		}
+		returnTypes, results, err := compileReturnTypes(s.ReturnTypes, pkg)
+		if err != nil {
+			return nil, nil, err
+		}
+		body := block()
+		bodyStatements := s.Body
+		if localsStatement, ok := bodyStatements[0].(LocalsStatement); ok {
+			localsStmts, err := compileLocals(localsStatement, pkg, fnLocals)
 			if err != nil {
-				return "", err
+				return nil, nil, err
 			}
-			header += v.Name + " " + typeCode
-			if t, ok := dt.(BuiltinType); ok {
-				switch t.Name {
-				case "L":
-					header += " = new(_std.List)"
-				case "M", "Ch":
-					header += " = make(" + typeCode + ")"
-				}
-			}
-			header += v.Name + " " + typeCode + "\n"
+			body.List = append(body.List, localsStmts...)
+			bodyStatements = bodyStatements[1:]
 		}
-		header += "_std.NoOp("
-		for _, v := range localsStatement.Vars {
-			header += v.Name + ","
+		name := enclosing + "." + s.Name
+		funcStmts, bodyStatements, err := compileLocalFuncs(bodyStatements, name, pkg, fnLocals)
+		if err != nil {
+			return nil, nil, err
 		}
-		header += ")\n"
-		bodyStatements = bodyStatements[1:]
-	}
-	//header += genDebugFn(locals, meth.Pkg.Globals, meth.Pkg)
-	body, err := compileBody(bodyStatements, returnTypes, meth.Pkg, locals, false, len(returnTypes) > 0)
-	if err != nil {
-		return "", err
-	}
-	return header + body + "\n}\n", nil
+		body.List = append(body.List, funcStmts...)
+		if pkg.Debug {
+			debug, err := parseStmts(genDebugFn(fnLocals, pkg.Globals, pkg)+genDebugEnter(name), s.LineNumber, s.Column)
+			if err != nil {
+				return nil, nil, err
+			}
+			body.List = append(body.List, debug...)
+		}
+		compiled, err := compileBody(bodyStatements, returnTypes, pkg, fnLocals, nil, len(returnTypes) > 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		body.List = append(body.List, compiled...)
+		decl := &ast.DeclStmt{Decl: varDecl(s.Name, typ, nil)}
+		pkg.mapLine(decl, s.LineNumber)
+		stmts = append(stmts,
+			decl,
+			&ast.AssignStmt{
+				Lhs: idents(s.Name),
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.FuncLit{Type: &ast.FuncType{Params: params, Results: results}, Body: body}},
+			},
+			callStmt("_std.NoOp", ast.NewIdent(s.Name)),
+		)
+	}
+	return stmts, statements, nil
+}
+
+// returns true if the local is a local function (whose type, unlike the type of a parameter or
+// local variable, is positioned at the name's definition: the localfunc statement)
+func isLocalFunc(v Variable) bool {
+	return v.Type.Type == "Fn" && v.Type.LineNumber == v.LineNumber && v.Type.Column == v.Column
 }
 
+// returns code declaring _locals, a func returning the current values of the local variables,
+// and _debug, a func which reports a stop at a line to the debugger
 func genDebugFn(locals map[string]Variable, globals map[string]GlobalDefinition, pkg *Package) string {
-	s := `_debug := func(line int) {
-	var globals = map[string]interface{}{
+	s := `_locals := func() map[string]interface{} {
+	return map[string]interface{}{
 `
-	for k, g := range globals {
-		if g.Pkg == pkg {
-			s += fmt.Sprintf("\"%s\": G_%s,\n", k, k)
-		} else {
-			s += fmt.Sprintf("\"%s\": _%s.G_%s,\n", k, g.Pkg.Prefix, k)
-		}
+	localNames := []string{}
+	for k := range locals {
+		localNames = append(localNames, k)
+	}
+	sort.Strings(localNames)
+	for _, k := range localNames {
+		s += fmt.Sprintf("\"%s\": %s,\n", k, k)
 	}
 	s += `}
-	var locals = map[string]interface{}{
+}
+_debug := func(line int) {
+	var globals = map[string]interface{}{
 `
-	for k := range locals {
-		s += fmt.Sprintf("\"%s\": %s,\n", k, k)
+	globalNames := []string{}
+	for k := range globals {
+		globalNames = append(globalNames, k)
+	}
+	sort.Strings(globalNames)
+	for _, k := range globalNames {
+		g := globals[k]
+		s += fmt.Sprintf("\"%s\": %s,\n", k, g.Pkg.qualify(pkg, "G_"+g.Name))
 	}
 	s += `}
-	_fmt.Println(globals, locals)
-	//_p.PollContinue(line, globals, locals)
+	_std.PollContinue(line, globals, _locals())
 }
+_std.NoOp(_debug)
 `
 	return s
 }
 
+// returns code which registers a call of the named function with the debugger
+func genDebugEnter(name string) string {
+	return "_std.Enter(\"" + name + "\", _locals)\ndefer _std.Exit()\n"
+}
+
 func compileIfStatement(s IfStatement, expectedReturnTypes []DataType,
-	pkg *Package, locals map[string]Variable, insideLoop bool) (string, error) {
+	pkg *Package, locals map[string]Variable, loops []*enclosingLoop) (ast.Stmt, error) {
 	c, returnedTypes, err := compileExpression(s.Condition, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if len(returnedTypes) != 1 || !isType(returnedTypes[0], BuiltinType{"Bool", nil}, true) {
-		return "", msg(s.LineNumber, s.Column, "if condition does not return one value or returns non-bool.")
+		return nil, exprMsg(s.Condition, "P0306", "if condition does not return one value or returns non-bool.")
 	}
-	code := "if interface{}(" + c + ").(bool) {\n"
-	c, err = compileBody(s.Body, expectedReturnTypes, pkg, locals, insideLoop, false)
+	cond, err := parseExprOf("interface{}("+c+").(bool)", s.Condition)
 	if err != nil {
-		return "", nil
+		return nil, err
 	}
-	code += c + "}"
+	body, err := compileBody(s.Body, expectedReturnTypes, pkg, locals, loops, false)
+	if err != nil {
+		return nil, err
+	}
+	stmt := &ast.IfStmt{Cond: cond, Body: block(body...)}
+	last := stmt
 	for _, elif := range s.Elifs {
 		c, returnedTypes, err := compileExpression(elif.Condition, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if !isType(returnedTypes[0], BuiltinType{"Bool", nil}, true) {
-			return "", msg(elif.LineNumber, elif.Column, "Elif condition expression does not return a boolean.")
+			return nil, exprMsg(elif.Condition, "P0306", "Elif condition expression does not return a boolean.")
 		}
-		code += " else if interface{}(" + c + ").(bool) {\n"
-		c, err = compileBody(elif.Body, expectedReturnTypes, pkg, locals, insideLoop, false)
+		cond, err := parseExprOf("interface{}("+c+").(bool)", elif.Condition)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		body, err := compileBody(elif.Body, expectedReturnTypes, pkg, locals, loops, false)
+		if err != nil {
+			return nil, err
 		}
-		code += c + "}"
+		next := &ast.IfStmt{Cond: cond, Body: block(body...)}
+		last.Else = next
+		last = next
 	}
 	if len(s.Else.Body) > 0 {
-		c, err := compileBody(s.Else.Body, expectedReturnTypes, pkg, locals, insideLoop, false)
+		body, err := compileBody(s.Else.Body, expectedReturnTypes, pkg, locals, loops, false)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += " else {\n" + c + "}"
+		last.Else = block(body...)
 	}
-	return code + "\n", nil
+	return stmt, nil
 }
 
 func compileTypeswitchStatement(s TypeswitchStatement, expectedReturnTypes []DataType,
-	pkg *Package, locals map[string]Variable, insideLoop bool) (string, error) {
+	pkg *Package, locals map[string]Variable, loops []*enclosingLoop) (ast.Stmt, error) {
 	expr, rts, err := compileExpression(s.Value, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if len(rts) != 1 {
-		return "", msg(s.LineNumber, s.Column, "typeswitch expression does not return one value.")
+		return nil, exprMsg(s.Value, "P0303", "typeswitch expression does not return one value.")
 	}
 	inter, ok := rts[0].(InterfaceDefinition)
 	if !ok {
-		return "", msg(s.LineNumber, s.Column, "typeswitch expression does not an interface value.")
+		return nil, exprMsg(s.Value, "P0312", "typeswitch expression does not an interface value.")
 	}
-	code := "{\n _inter := " + expr + "\n"
-	for i, c := range s.Cases {
+	val, err := parseExprOf(expr, s.Value)
+	if err != nil {
+		return nil, err
+	}
+	stmt := block(define("_inter", val))
+	var last *ast.IfStmt
+	for _, c := range s.Cases {
 		caseType, err := getDataType(c.Variable.Type, pkg)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if !isType(caseType, inter, false) {
-			return "", msg(s.LineNumber, s.Column, "typeswitch case type is not an implementor of the interface.")
+			return nil, exprMsg(c.Variable.Type, "P0312", "typeswitch case type is not an implementor of the interface.")
 		}
-		t, err := compileType(caseType, pkg)
+		t, err := typeExpr(caseType, pkg)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		name := c.Variable.Name
 		if _, ok := locals[name]; ok {
-			return "", msg(s.LineNumber, s.Column, "typeswitch variable name '"+name+"'conflicts with existing local variable")
+			return nil, msg(s.LineNumber, s.Column, "P0202", "typeswitch variable name '"+name+"'conflicts with existing local variable")
 		}
 		newLocals := map[string]Variable{}
 		for k, v := range locals {
 			newLocals[k] = v
 		}
 		newLocals[name] = c.Variable
-		body, err := compileBody(c.Body, expectedReturnTypes, pkg, newLocals, insideLoop, false)
+		body, err := compileBody(c.Body, expectedReturnTypes, pkg, newLocals, loops, false)
 		if err != nil {
-			return "", nil
+			return nil, err
 		}
-		if i > 0 {
-			code += " else "
+		stmts := []ast.Stmt{callStmt("_std.NoOp", ast.NewIdent(name))}
+		if pkg.Debug {
+			debug, err := parseStmts(genDebugFn(newLocals, pkg.Globals, pkg), c.LineNumber, c.Column)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, debug...)
 		}
-		code += "if " + name + ", _ok := _inter.(" + t + "); _ok { \n"
-		//code += genDebugFn(newLocals, pkg.Globals, pkg)
-		code += "_std.NoOp(" + name + ")\n"
-		code += body + "}"
+		next := &ast.IfStmt{
+			Init: &ast.AssignStmt{
+				Lhs: idents(name, "_ok"),
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{&ast.TypeAssertExpr{X: ast.NewIdent("_inter"), Type: t}},
+			},
+			Cond: ast.NewIdent("_ok"),
+			Body: block(append(stmts, body...)...),
+		}
+		if last == nil {
+			stmt.List = append(stmt.List, next)
+		} else {
+			last.Else = next
+		}
+		last = next
 	}
 	if s.Default != nil {
 		name := s.DefaultVariable
 		if _, ok := locals[name]; ok {
-			return "", msg(s.LineNumber, s.Column, "typeswitch variable name '"+name+"'conflicts with existing local variable")
+			return nil, msg(s.LineNumber, s.Column, "P0202", "typeswitch variable name '"+name+"'conflicts with existing local variable")
 		}
 		newLocals := map[string]Variable{}
 		for k, v := range locals {
 			newLocals[k] = v
 		}
 		newLocals[name] = Variable{s.LineNumber, s.Column, name, ParsedDataType{}}
-		body, err := compileBody(s.Default, expectedReturnTypes, pkg, newLocals, insideLoop, false)
+		body, err := compileBody(s.Default, expectedReturnTypes, pkg, newLocals, loops, false)
 		if err != nil {
-			return "", nil
+			return nil, err
+		}
+		stmts := []ast.Stmt{define(name, ast.NewIdent("_inter")), callStmt("_std.NoOp", ast.NewIdent(name))}
+		if pkg.Debug {
+			debug, err := parseStmts(genDebugFn(newLocals, pkg.Globals, pkg), s.LineNumber, s.Column)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, debug...)
+		}
+		if last == nil {
+			stmt.List = append(stmt.List, block(append(stmts, body...)...))
+		} else {
+			last.Else = block(append(stmts, body...)...)
 		}
-		code += " else { \n" + name + ":= _inter \n _std.NoOp(" + name + ") \n" + body + "}"
 	}
-	return code + "\n}\n", nil
+	return stmt, nil
+}
+
+// A switch is compiled as an if-else chain (so a break or continue in a case applies to the
+// enclosing loop), in which each value of a case is compared to the switch value with ==.
+func compileSwitchStatement(s SwitchStatement, expectedReturnTypes []DataType,
+	pkg *Package, locals map[string]Variable, loops []*enclosingLoop) (ast.Stmt, error) {
+	expr, rts, err := compileExpression(s.Value, pkg, locals)
+	if err != nil {
+		return nil, err
+	}
+	if len(rts) != 1 {
+		return nil, exprMsg(s.Value, "P0303", "switch expression does not return one value.")
+	}
+	if !isComparable(rts[0]) {
+		return nil, exprMsg(s.Value, "P0307", "switch expression is a slice, map, or function (or an array or struct holding one), which can't be compared to case values.")
+	}
+	val, err := parseExprOf(expr, s.Value)
+	if err != nil {
+		return nil, err
+	}
+	stmt := block(define("_switch", val))
+	if len(s.Cases) == 0 {
+		stmt.List = append(stmt.List, callStmt("_std.NoOp", ast.NewIdent("_switch")))
+	}
+	constCases := map[string]bool{} // the keys of the constant case values (see constKey)
+	var last *ast.IfStmt
+	for _, c := range s.Cases {
+		var cond ast.Expr
+		for _, v := range c.Values {
+			code, caseTypes, err := compileExpression(v, pkg, locals)
+			if err != nil {
+				return nil, err
+			}
+			if len(caseTypes) != 1 || !isType(caseTypes[0], rts[0], true) {
+				return nil, exprMsg(v, "P0307", "switch case value does not match the type of the switch expression.")
+			}
+			if key := constKey(v, pkg, locals); key != "" {
+				if constCases[key] {
+					return nil, exprMsg(v, "P0314", "Duplicate case value in switch: "+v.(Token).Content+".")
+				}
+				constCases[key] = true
+			}
+			eq, err := parseExprOf("_switch == "+code, v)
+			if err != nil {
+				return nil, err
+			}
+			if cond != nil {
+				eq = &ast.BinaryExpr{X: cond, Op: token.LOR, Y: eq}
+			}
+			cond = eq
+		}
+		body, err := compileBody(c.Body, expectedReturnTypes, pkg, locals, loops, false)
+		if err != nil {
+			return nil, err
+		}
+		next := &ast.IfStmt{Cond: cond, Body: block(body...)}
+		if last == nil {
+			stmt.List = append(stmt.List, next)
+		} else {
+			last.Else = next
+		}
+		last = next
+	}
+	if len(s.Default) > 0 {
+		body, err := compileBody(s.Default, expectedReturnTypes, pkg, locals, loops, false)
+		if err != nil {
+			return nil, err
+		}
+		if last == nil {
+			stmt.List = append(stmt.List, block(body...))
+		} else {
+			last.Else = block(body...)
+		}
+	}
+	return stmt, nil
+}
+
+// returns a key identifying the value of a switch case which is a literal or a constant
+// (defined, perhaps in terms of another constant, as a literal), or "" for any other value
+func constKey(e Expression, pkg *Package, locals map[string]Variable) string {
+	if name, ok := constName(e, pkg, locals); ok {
+		// (the value of a constant can't refer to locals)
+		return constKey(pkg.Globals[name].Value, pkg, map[string]Variable{})
+	}
+	t, ok := e.(Token)
+	if !ok {
+		return ""
+	}
+	switch t.Type {
+	case NumberLiteral:
+		if f, err := strconv.ParseFloat(t.Content, 64); err == nil {
+			if f == 0 {
+				f = 0 // (-0 equals 0)
+			}
+			return "number " + strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	case StringLiteral:
+		if str, err := strconv.Unquote(t.Content); err == nil {
+			return "string " + str
+		}
+	case BooleanLiteral:
+		return "bool " + t.Content
+	}
+	return ""
 }
 
 func compileWhileStatement(s WhileStatement, expectedReturnTypes []DataType,
-	pkg *Package, locals map[string]Variable) (string, error) {
+	pkg *Package, locals map[string]Variable, loops []*enclosingLoop) (ast.Stmt, error) {
 	c, returnedTypes, err := compileExpression(s.Condition, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if len(returnedTypes) != 1 {
-		return "", msg(s.LineNumber, s.Column, "while condition expression must one value (a boolean).")
+		return nil, exprMsg(s.Condition, "P0306", "while condition expression must one value (a boolean).")
 	}
 	if !isType(returnedTypes[0], BuiltinType{"Bool", nil}, true) {
-		return "", msg(s.LineNumber, s.Column, "while condition expression does not return a boolean.")
+		return nil, exprMsg(s.Condition, "P0306", "while condition expression does not return a boolean.")
+	}
+	cond, err := parseExprOf(c, s.Condition)
+	if err != nil {
+		return nil, err
+	}
+	loop, err := newLoop(s.Label, s.LineNumber, s.Column, loops)
+	if err != nil {
+		return nil, err
 	}
-	code := "for " + c + " {\n"
-	c, err = compileBody(s.Body, expectedReturnTypes, pkg, locals, true, false)
+	body, err := compileBody(s.Body, expectedReturnTypes, pkg, locals, append(loops, loop), false)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return code + c + "}\n", nil
+	return labelLoop(loop, &ast.ForStmt{Cond: cond, Body: block(body...)}), nil
 }
 
 func compileForincStatement(s ForincStatement, expectedReturnTypes []DataType,
-	pkg *Package, locals map[string]Variable) (string, error) {
+	pkg *Package, locals map[string]Variable, loops []*enclosingLoop) (ast.Stmt, error) {
 	if _, ok := locals[s.IndexName]; ok {
-		return "", msg(s.LineNumber, s.Column, "forinc index name conflicts with an existing local variable.")
+		return nil, msg(s.LineNumber, s.Column, "P0202", "forinc index name conflicts with an existing local variable.")
 	}
 	newLocals := map[string]Variable{}
 	for k, v := range locals {
@@ -1217,62 +1990,279 @@ func compileForincStatement(s ForincStatement, expectedReturnTypes []DataType,
 	}
 	newLocals[s.IndexName] = Variable{s.LineNumber, s.Column, s.IndexName, s.IndexType}
 	if s.IndexType.Type != "I" {
-		return "", msg(s.LineNumber, s.Column, "forinc index must start value expression must return a non-integer.")
+		return nil, exprMsg(s.IndexType, "P0311", "forinc index must start value expression must return a non-integer.")
 	}
 	startExpr, returnedTypes, err := compileExpression(s.StartVal, pkg, newLocals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if s.Dec {
 		startExpr += " - 1"
 	}
 	if len(returnedTypes) != 1 {
-		return "", msg(s.LineNumber, s.Column, "forinc start value expression improperly returns more than one value.")
+		return nil, exprMsg(s.StartVal, "P0303", "forinc start value expression improperly returns more than one value.")
 	}
 	if !isInteger(returnedTypes[0]) {
-		return "", msg(s.LineNumber, s.Column, "forinc start value expression must return a non-integer.")
+		return nil, exprMsg(s.StartVal, "P0311", "forinc start value expression must return a non-integer.")
 	}
 	endExpr, returnedTypes, err := compileExpression(s.EndVal, pkg, newLocals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if len(returnedTypes) != 1 {
-		return "", msg(s.LineNumber, s.Column, "forinc end value expression improperly returns more than one value.")
+		return nil, exprMsg(s.EndVal, "P0303", "forinc end value expression improperly returns more than one value.")
 	}
 	if !isInteger(returnedTypes[0]) {
-		return "", msg(s.LineNumber, s.Column, "forinc end value expression must return a non-integer.")
+		return nil, exprMsg(s.EndVal, "P0311", "forinc end value expression must return a non-integer.")
 	}
-	code := "for _i := " + startExpr + "; _i "
-	if s.Dec {
-		code += ">= "
-	} else {
-		code += "< "
+	start, err := parseExprOf(startExpr, s.StartVal)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseExprOf(endExpr, s.EndVal)
+	if err != nil {
+		return nil, err
+	}
+	stmt := &ast.ForStmt{
+		Init: define("_i", start),
+		Cond: &ast.BinaryExpr{X: ast.NewIdent("_i"), Op: token.LSS, Y: end},
+		Post: &ast.IncDecStmt{X: ast.NewIdent("_i"), Tok: token.INC},
 	}
-	code += endExpr + " ; _i"
 	if s.Dec {
-		code += "--"
-	} else {
-		code += "++"
-	}
-	code += " { \n"
-	code += s.IndexName + " := _i \n"
-	code += "_std.NoOp(" + s.IndexName + ")\n"
-	//code += genDebugFn(newLocals, pkg.Globals, pkg)
-	body, err := compileBody(s.Body, expectedReturnTypes, pkg, newLocals, true, false)
+		stmt.Cond.(*ast.BinaryExpr).Op = token.GEQ
+		stmt.Post.(*ast.IncDecStmt).Tok = token.DEC
+	}
+	stmts := []ast.Stmt{
+		define(s.IndexName, ast.NewIdent("_i")),
+		callStmt("_std.NoOp", ast.NewIdent(s.IndexName)),
+	}
+	if pkg.Debug {
+		debug, err := parseStmts(genDebugFn(newLocals, pkg.Globals, pkg), s.LineNumber, s.Column)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, debug...)
+	}
+	loop, err := newLoop(s.Label, s.LineNumber, s.Column, loops)
+	if err != nil {
+		return nil, err
+	}
+	body, err := compileBody(s.Body, expectedReturnTypes, pkg, newLocals, append(loops, loop), false)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Body = block(append(stmts, body...)...)
+	return labelLoop(loop, stmt), nil
+}
+
+func compileGoStatement(s GoStatement, pkg *Package, locals map[string]Variable) (ast.Stmt, error) {
+	call, err := compileCallOf(s.Call, "go", pkg, locals)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.GoStmt{Call: call}, nil
+}
+
+func compileDeferStatement(s DeferStatement, pkg *Package, locals map[string]Variable) (ast.Stmt, error) {
+	call, err := compileCallOf(s.Call, "defer", pkg, locals)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.DeferStmt{Call: call}, nil
+}
+
+// returns the Go call of a go or defer statement (named by word), whose arguments are evaluated
+// when the statement runs: the call of a function or method, or an operation of an operator which
+// can be deferred (see statementOperators)
+func compileCallOf(e Expression, word string, pkg *Package, locals map[string]Variable) (*ast.CallExpr, error) {
+	var c string
+	var err error
+	switch e := e.(type) {
+	case FunctionCall:
+		c, _, err = compileFunctionCall(e, pkg, locals)
+	case MethodCall:
+		c, _, err = compileMethodCall(e, pkg, locals)
+	case Operation:
+		deferrable, ok := statementOperators[e.Operator]
+		if !ok {
+			return nil, exprMsg(e, "P0310", "The "+e.Operator+" operation merely computes a value, so it can't be used in a "+word+" statement.")
+		}
+		if e.Operator == "recover" {
+			// (a deferred recover would be evaluated when deferred rather than while panicking)
+			return nil, exprMsg(e, "P0310", "recover can't be used in a "+word+" statement. "+
+				"To stop a panic, call recover in a function which is deferred.")
+		}
+		if !deferrable {
+			return nil, exprMsg(e, "P0310", "The "+e.Operator+" operation can't be used in a "+word+" statement. "+
+				"Call a function which performs the operation instead.")
+		}
+		c, _, err = compileOperation(e, pkg, locals)
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	code += body + "}\n"
-	return code, nil
+	x, err := parseExprOf(c, e)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		paren, ok := x.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		x = paren.X
+	}
+	call, ok := x.(*ast.CallExpr)
+	if !ok {
+		line, column := position(e)
+		return nil, internalError(line, column, errors.New("the code of a deferrable operation isn't a call"))
+	}
+	return call, nil
+}
+
+// A select is compiled as a Go select whose cases just record which case was chosen (and the value
+// received), followed by an if-else chain which runs the body of the chosen case. (A break or continue
+// in the body of a Go select case would break out of the select rather than the enclosing loop.)
+func compileSelectStatement(s SelectStatement, expectedReturnTypes []DataType,
+	pkg *Package, locals map[string]Variable, loops []*enclosingLoop) (ast.Stmt, error) {
+	stmt := block()
+	sel := &ast.SelectStmt{Body: block()}
+	hasDefault := s.Default.LineNumber > 0
+	if len(s.Clauses) > 0 || hasDefault {
+		stmt.List = append(stmt.List, &ast.DeclStmt{Decl: varDecl("_case", ast.NewIdent("int"), nil)})
+	}
+	chosen := func(i int) ast.Stmt {
+		return &ast.AssignStmt{
+			Lhs: idents("_case"),
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(i)}},
+		}
+	}
+	var first, last *ast.IfStmt
+	addBody := func(i int, body []ast.Stmt) {
+		next := &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: ast.NewIdent("_case"), Op: token.EQL, Y: &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(i)}},
+			Body: block(body...),
+		}
+		if last == nil {
+			first = next
+		} else {
+			last.Else = next
+		}
+		last = next
+	}
+	compileChannel := func(e Expression) (ast.Expr, DataType, error) {
+		c, rts, err := compileExpression(e, pkg, locals)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rts) != 1 {
+			return nil, nil, exprMsg(e, "P0303", "select case channel expression does not return one value.")
+		}
+		ok, elemType := isChannel(rts[0])
+		if !ok {
+			return nil, nil, exprMsg(e, "P0302", "select case expression is not a channel.")
+		}
+		ch, err := parseExprOf(c, e)
+		return ch, elemType, err
+	}
+	for i, clause := range s.Clauses {
+		var comm ast.Stmt
+		var body []ast.Stmt
+		switch c := clause.(type) {
+		case SelectRcvClause:
+			ch, elemType, err := compileChannel(c.Channel)
+			if err != nil {
+				return nil, err
+			}
+			dt, err := getDataType(c.Target.Type, pkg)
+			if err != nil {
+				return nil, err
+			}
+			if !isType(dt, elemType, true) {
+				return nil, msg(c.Target.LineNumber, c.Target.Column, "P0307", "rcving variable type does not match the channel's element type.")
+			}
+			t, err := typeExpr(dt, pkg)
+			if err != nil {
+				return nil, err
+			}
+			name := c.Target.Name
+			if _, ok := locals[name]; ok {
+				return nil, msg(c.Target.LineNumber, c.Target.Column, "P0202", "rcving variable name '"+name+"' conflicts with existing local variable")
+			}
+			rcv := "_rcv" + strconv.Itoa(i)
+			stmt.List = append(stmt.List, &ast.DeclStmt{Decl: varDecl(rcv, t, nil)})
+			comm = &ast.AssignStmt{
+				Lhs: idents(rcv),
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.UnaryExpr{Op: token.ARROW, X: ch}},
+			}
+			newLocals := map[string]Variable{}
+			for k, v := range locals {
+				newLocals[k] = v
+			}
+			newLocals[name] = c.Target
+			stmts, err := compileBody(c.Body, expectedReturnTypes, pkg, newLocals, loops, false)
+			if err != nil {
+				return nil, err
+			}
+			body = []ast.Stmt{define(name, ast.NewIdent(rcv)), callStmt("_std.NoOp", ast.NewIdent(name))}
+			if pkg.Debug {
+				debug, err := parseStmts(genDebugFn(newLocals, pkg.Globals, pkg), c.LineNumber, c.Column)
+				if err != nil {
+					return nil, err
+				}
+				body = append(body, debug...)
+			}
+			body = append(body, stmts...)
+		case SelectSendClause:
+			ch, elemType, err := compileChannel(c.Channel)
+			if err != nil {
+				return nil, err
+			}
+			v, rts, err := compileExpression(c.Value, pkg, locals)
+			if err != nil {
+				return nil, err
+			}
+			if len(rts) != 1 || !isType(rts[0], elemType, false) {
+				return nil, exprMsg(c.Value, "P0307", "snding value is not valid for the channel.")
+			}
+			val, err := parseExprOf(v, c.Value)
+			if err != nil {
+				return nil, err
+			}
+			comm = &ast.SendStmt{Chan: ch, Value: val}
+			body, err = compileBody(c.Body, expectedReturnTypes, pkg, locals, loops, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		sel.Body.List = append(sel.Body.List, &ast.CommClause{Comm: comm, Body: []ast.Stmt{chosen(i)}})
+		addBody(i, body)
+	}
+	if hasDefault {
+		i := len(s.Clauses)
+		sel.Body.List = append(sel.Body.List, &ast.CommClause{Body: []ast.Stmt{chosen(i)}})
+		body, err := compileBody(s.Default.Body, expectedReturnTypes, pkg, locals, loops, false)
+		if err != nil {
+			return nil, err
+		}
+		addBody(i, body)
+	}
+	stmt.List = append(stmt.List, sel)
+	if first != nil {
+		stmt.List = append(stmt.List, first)
+	}
+	return stmt, nil
 }
 
 func compileForeachStatement(s ForeachStatement, expectedReturnTypes []DataType,
-	pkg *Package, locals map[string]Variable) (string, error) {
+	pkg *Package, locals map[string]Variable, loops []*enclosingLoop) (ast.Stmt, error) {
 	if _, ok := locals[s.IndexName]; ok {
-		return "", msg(s.LineNumber, s.Column, "foreach index name conflicts with an existing local variable.")
+		return nil, msg(s.LineNumber, s.Column, "P0202", "foreach index name conflicts with an existing local variable.")
 	}
 	if _, ok := locals[s.ValName]; ok {
-		return "", msg(s.LineNumber, s.Column, "foreach val name conflicts with an existing local variable.")
+		return nil, msg(s.LineNumber, s.Column, "P0202", "foreach val name conflicts with an existing local variable.")
 	}
 	newLocals := map[string]Variable{}
 	for k, v := range locals {
@@ -1282,213 +2272,346 @@ func compileForeachStatement(s ForeachStatement, expectedReturnTypes []DataType,
 	newLocals[s.ValName] = Variable{s.LineNumber, s.Column, s.ValName, s.ValType}
 	collExpr, returnedTypes, err := compileExpression(s.Collection, pkg, newLocals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if len(returnedTypes) != 1 {
-		return "", msg(s.LineNumber, s.Column, "foreach collection expression improperly returns more than one value.")
+		return nil, exprMsg(s.Collection, "P0303", "foreach collection expression improperly returns more than one value.")
 	}
 	indexType, err := getDataType(s.IndexType, pkg)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	valType, err := getDataType(s.ValType, pkg)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	code := "for _i, _v := range "
 	isList := false
 	switch t := returnedTypes[0].(type) {
 	case BuiltinType:
 		if t.Name != "L" && t.Name != "M" && t.Name != "S" {
-			return "", msg(s.LineNumber, s.Column, "foreach collection type must be a list or map.")
+			return nil, exprMsg(s.Collection, "P0311", "foreach collection type must be a list or map.")
 		}
 		if t.Name == "L" {
 			if !isNumber(indexType) {
-				return "", msg(s.LineNumber, s.Column, "Expected foreach index variable to be a number.")
+				return nil, exprMsg(s.IndexType, "P0311", "Expected foreach index variable to be a number.")
 			}
 			if !isType(t.Params[0], valType, false) {
-				return "", msg(s.LineNumber, s.Column, "Improper foreach val type for list.")
+				return nil, exprMsg(s.ValType, "P0311", "Improper foreach val type for list.")
 			}
-			code += "*"
+			collExpr = "*" + collExpr
 			isList = true
 		} else if t.Name == "M" {
 			if !isType(t.Params[0], indexType, false) {
-				return "", msg(s.LineNumber, s.Column, "Improper foreach index type for map.")
+				return nil, exprMsg(s.IndexType, "P0311", "Improper foreach index type for map.")
 			}
 			if !isType(t.Params[1], valType, false) {
-				return "", msg(s.LineNumber, s.Column, "Improper foreach val type for map.")
+				return nil, exprMsg(s.ValType, "P0311", "Improper foreach val type for map.")
 			}
 		}
 	case ArrayType:
 		if !isNumber(indexType) {
-			return "", msg(s.LineNumber, s.Column, "Expected foreach index variable to be a number.")
+			return nil, exprMsg(s.IndexType, "P0311", "Expected foreach index variable to be a number.")
 		}
 		if !isType(t.Type, valType, false) {
-			return "", msg(s.LineNumber, s.Column, "Improper foreach val type for array.")
+			return nil, exprMsg(s.ValType, "P0311", "Improper foreach val type for array.")
 		}
 	default:
-		return "", msg(s.LineNumber, s.Column, "foreach collection type must be a list, map, slice, or array.")
+		return nil, exprMsg(s.Collection, "P0311", "foreach collection type must be a list, map, slice, or array.")
+	}
+	coll, err := parseExprOf(collExpr, s.Collection)
+	if err != nil {
+		return nil, err
 	}
-	code += collExpr + " { \n"
-	code += s.IndexName + " := int64(_i) \n"
+	var val ast.Expr = ast.NewIdent("_v")
 	if isList {
-		dt, err := getDataType(s.ValType, pkg)
+		t, err := typeExpr(valType, pkg)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		typeCode, err := compileType(dt, pkg)
+		val = &ast.TypeAssertExpr{X: val, Type: t}
+	}
+	stmts := []ast.Stmt{
+		define(s.IndexName, call("int64", ast.NewIdent("_i"))),
+		define(s.ValName, val),
+		callStmt("_std.NoOp", idents(s.IndexName, s.ValName)...),
+	}
+	if pkg.Debug {
+		debug, err := parseStmts(genDebugFn(newLocals, pkg.Globals, pkg), s.LineNumber, s.Column)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += s.ValName + " := _v.(" + typeCode + ") \n"
-	} else {
-		code += s.ValName + " := _v \n"
+		stmts = append(stmts, debug...)
+	}
+	loop, err := newLoop(s.Label, s.LineNumber, s.Column, loops)
+	if err != nil {
+		return nil, err
 	}
-	code += "_std.NoOp(" + s.IndexName + ", " + s.ValName + ")\n"
-	//code += genDebugFn(newLocals, pkg.Globals, pkg)
-	body, err := compileBody(s.Body, expectedReturnTypes, pkg, newLocals, true, false)
+	body, err := compileBody(s.Body, expectedReturnTypes, pkg, newLocals, append(loops, loop), false)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return labelLoop(loop, &ast.RangeStmt{
+		Key:   ast.NewIdent("_i"),
+		Value: ast.NewIdent("_v"),
+		Tok:   token.DEFINE,
+		X:     coll,
+		Body:  block(append(stmts, body...)...),
+	}), nil
+}
+
+// A loop enclosing the statements being compiled, which a break or continue can target
+type enclosingLoop struct {
+	label    string // the label of the loop in the source ("" if none)
+	goLabel  string // the label of the generated loop
+	targeted bool   // a break or continue targets the loop by its label
+}
+
+// returns the enclosingLoop of a loop statement, whose label can't be that of a loop enclosing it
+func newLoop(label string, line int, column int, loops []*enclosingLoop) (*enclosingLoop, error) {
+	for _, l := range loops {
+		if label != "" && l.label == label {
+			return nil, msg(line, column, "P0202", "Loop label "+label+" is already the label of an enclosing loop.")
+		}
+	}
+	// (two loops of a function with the same label would have the same Go label without the line)
+	return &enclosingLoop{label: label, goLabel: "_" + label + "_" + strconv.Itoa(line)}, nil
+}
+
+// returns the generated loop, labeled if a break or continue targets it (Go doesn't allow unused labels)
+func labelLoop(loop *enclosingLoop, stmt ast.Stmt) ast.Stmt {
+	if !loop.targeted {
+		return stmt
+	}
+	return &ast.LabeledStmt{Label: ast.NewIdent(loop.goLabel), Stmt: stmt}
+}
+
+// returns a break or continue (tok) of the innermost loop, or of the enclosing loop with the label
+func compileBranch(tok token.Token, label string, line int, column int, loops []*enclosingLoop) (ast.Stmt, error) {
+	if len(loops) == 0 {
+		return nil, msg(line, column, "P0113", "cannot have "+tok.String()+" statement outside a loop.")
+	}
+	if label == "" {
+		return &ast.BranchStmt{Tok: tok}, nil
+	}
+	for i := len(loops) - 1; i >= 0; i-- {
+		if loops[i].label == label {
+			loops[i].targeted = true
+			return &ast.BranchStmt{Tok: tok, Label: ast.NewIdent(loops[i].goLabel)}, nil
+		}
 	}
-	code += body + "}\n"
-	return code, nil
+	return nil, msg(line, column, "P0113", "cannot "+tok.String()+" "+label+": no enclosing loop has the label "+label+".")
 }
 
 func compileBody(statements []Statement, expectedReturnTypes []DataType,
-	pkg *Package, locals map[string]Variable, insideLoop bool, requiresReturn bool) (string, error) {
-	var code string
+	pkg *Package, locals map[string]Variable, loops []*enclosingLoop, requiresReturn bool) ([]ast.Stmt, error) {
+	stmts := []ast.Stmt{}
 	if requiresReturn {
-		// len(statments) will not be 0
-		if st, ok := statements[len(statements)-1].(ReturnStatement); !ok {
-			return "", msg(st.LineNumber, st.Column, "this function must end with a return statement.")
+		// (a function may instead end by panicking)
+		var last Statement
+		line, column := 0, 0
+		if len(statements) > 0 {
+			last = statements[len(statements)-1]
+			line = last.Line()
+			// (every kind of statement has a Column)
+			column = int(reflect.ValueOf(last).FieldByName("Column").Int())
+		}
+		if o, ok := last.(Operation); !ok || o.Operator != "panic" {
+			if _, ok := last.(ReturnStatement); !ok {
+				return nil, msg(line, column, "P0308", "this function must end with a return statement.")
+			}
 		}
 	}
 	for _, s := range statements {
 		line := s.Line()
 		lineStr := strconv.Itoa(line)
 		pkg.ValidBreakpoints[lineStr] = true
-		//code += fmt.Sprintf("if _std.Breakpoints[%d] {_debug(%d)}\n", line, line)
-		var c string
+		first := len(stmts)
+		if pkg.Debug {
+			debug, err := parseStmts(fmt.Sprintf("if _std.Break(%d) {_debug(%d)}", line, line), line, 0)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, debug...)
+		}
+		var st ast.Stmt
 		var err error
 		switch s := s.(type) {
 		case IfStatement:
-			c, err = compileIfStatement(s, expectedReturnTypes, pkg, locals, insideLoop)
+			st, err = compileIfStatement(s, expectedReturnTypes, pkg, locals, loops)
 		case WhileStatement:
-			c, err = compileWhileStatement(s, expectedReturnTypes, pkg, locals)
+			st, err = compileWhileStatement(s, expectedReturnTypes, pkg, locals, loops)
 		case ForeachStatement:
-			c, err = compileForeachStatement(s, expectedReturnTypes, pkg, locals)
+			st, err = compileForeachStatement(s, expectedReturnTypes, pkg, locals, loops)
 		case ForincStatement:
-			c, err = compileForincStatement(s, expectedReturnTypes, pkg, locals)
+			st, err = compileForincStatement(s, expectedReturnTypes, pkg, locals, loops)
 		case AssignmentStatement:
-			c, err = compileAssignmentStatement(s, pkg, locals)
+			st, err = compileAssignmentStatement(s, pkg, locals)
 		case TypeswitchStatement:
-			c, err = compileTypeswitchStatement(s, expectedReturnTypes, pkg, locals, insideLoop)
+			st, err = compileTypeswitchStatement(s, expectedReturnTypes, pkg, locals, loops)
+		case SwitchStatement:
+			st, err = compileSwitchStatement(s, expectedReturnTypes, pkg, locals, loops)
+		case SelectStatement:
+			st, err = compileSelectStatement(s, expectedReturnTypes, pkg, locals, loops)
+		case GoStatement:
+			st, err = compileGoStatement(s, pkg, locals)
+		case DeferStatement:
+			st, err = compileDeferStatement(s, pkg, locals)
 		case ReturnStatement:
-			c, err = compileReturnStatement(s, expectedReturnTypes, pkg, locals)
+			st, err = compileReturnStatement(s, expectedReturnTypes, pkg, locals)
 		case BreakStatement:
-			if insideLoop {
-				c += "break \n"
-			} else {
-				err = msg(s.LineNumber, s.Column, "cannot have break statement outside a loop.")
-			}
+			st, err = compileBranch(token.BREAK, s.Label, s.LineNumber, s.Column, loops)
 		case ContinueStatement:
-			if insideLoop {
-				c += "continue \n"
-			} else {
-				err = msg(s.LineNumber, s.Column, "cannot have continue statement outside a loop.")
-			}
+			st, err = compileBranch(token.CONTINUE, s.Label, s.LineNumber, s.Column, loops)
 		case FunctionCall:
+			var c string
 			c, _, err = compileFunctionCall(s, pkg, locals)
-			c += "\n"
+			if err == nil {
+				st, err = exprStmt(c, s)
+			}
 		case MethodCall:
+			var c string
 			c, _, err = compileMethodCall(s, pkg, locals)
-			c += "\n"
+			if err == nil {
+				st, err = exprStmt(c, s)
+			}
 		case Operation:
-			if s.Operator != "set" && s.Operator != "print" && s.Operator != "println" &&
-				s.Operator != "prompt" && s.Operator != "push" && s.Operator != "sr" {
-				return "", msg(s.LineNumber, s.Column, "Improper operation as statement. Only set, sr, push, print, println, "+
-					"and prompt can be standalone statements.")
+			if _, ok := statementOperators[s.Operator]; !ok {
+				return nil, msg(s.LineNumber, s.Column, "P0310", "Improper operation as statement. Only set, sr, push, print, println, "+
+					"prompt, send, rcv, close, panic, recover, and closeFile can be standalone statements.")
 			}
+			var c string
 			c, _, err = compileOperation(s, pkg, locals)
-			c += "\n"
+			if err == nil {
+				st, err = exprStmt(c, s)
+			}
 		case LocalsStatement:
-			return "", msg(s.LineNumber, s.Column, "only the first statement of a function can be a locals statement.")
+			return nil, msg(s.LineNumber, s.Column, "P0109", "only the first statement of a function can be a locals statement.")
+		case LocalFuncStatement:
+			return nil, msg(s.LineNumber, s.Column, "P0109", "localfunc statements must come after the locals statement (if any) "+
+				"and before all other statements of a function.")
 		}
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += c
+		stmts = append(stmts, st)
+		pkg.mapLine(stmts[first], line)
 	}
-	return code, nil
+	return stmts, nil
 }
 
-func compileAssignmentStatement(s AssignmentStatement, pkg *Package, locals map[string]Variable) (string, error) {
+func compileAssignmentStatement(s AssignmentStatement, pkg *Package, locals map[string]Variable) (ast.Stmt, error) {
 	valCode, valueTypes, err := compileExpression(s.Value, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if len(valueTypes) != len(s.Targets) {
-		return "", msg(s.LineNumber, s.Column, "Wrong number of targets in assignment.")
+		return nil, exprMsg(s.Value, "P0307", "Wrong number of targets in assignment.")
 	}
-	code := ""
+	stmt := &ast.AssignStmt{Tok: token.ASSIGN}
 	for i, target := range s.Targets {
 		switch t := target.(type) {
 		case Token:
 			if t.Type != IdentifierWord {
-				return "", msg(s.LineNumber, s.Column, "Assignment to non-identifier.")
+				return nil, exprMsg(t, "P0108", "Assignment to non-identifier.")
+			}
+			if name, ok := constName(t, pkg, locals); ok {
+				return nil, exprMsg(t, "P0108", "Cannot assign to constant "+name+".")
 			}
 		case Operation:
 			if t.Operator != "dr" && t.Operator != "get" && t.Operator != "ref" {
-				return "", msg(s.LineNumber, s.Column, "Improper target of assignment.")
+				return nil, exprMsg(target, "P0108", "Improper target of assignment.")
 			}
 			if t.Operator == "get" {
 				t.Operator = "asget"
 				target = t
 			}
 		default:
-			return "", msg(s.LineNumber, s.Column, "Improper target of assignment.")
+			return nil, exprMsg(target, "P0108", "Improper target of assignment.")
 		}
 		expr, rts, err := compileExpression(target, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		// shouldn't be the case that any target expression returns more than one value
 		if len(rts) != 1 {
-			return "", msg(s.LineNumber, s.Column, "Improper target of assignment on line")
+			return nil, exprMsg(target, "P0108", "Improper target of assignment.")
 		}
 		if !isType(valueTypes[i], rts[0], false) {
-			return "", msg(s.LineNumber, s.Column, "Value in assignment does not match expected type.")
+			return nil, exprMsg(s.Value, "P0307", "Value in assignment does not match expected type.")
 		}
-		code += expr
-		if i < len(s.Targets)-1 {
-			code += ", "
+		lhs, err := parseExprOf(expr, target)
+		if err != nil {
+			return nil, err
 		}
+		stmt.Lhs = append(stmt.Lhs, lhs)
+	}
+	val, err := parseExprOf(valCode, s.Value)
+	if err != nil {
+		return nil, err
 	}
-	return code + " = " + valCode + "\n", nil
+	stmt.Rhs = []ast.Expr{val}
+	return stmt, nil
 }
 
-func compileReturnStatement(s ReturnStatement, expectedReturnTypes []DataType, pkg *Package, locals map[string]Variable) (string, error) {
+func compileReturnStatement(s ReturnStatement, expectedReturnTypes []DataType, pkg *Package, locals map[string]Variable) (ast.Stmt, error) {
 	if len(s.Values) != len(expectedReturnTypes) {
-		return "", msg(s.LineNumber, s.Column, "Return statement has wrong number of values.")
+		return nil, msg(s.LineNumber, s.Column, "P0308", "Return statement has wrong number of values.")
 	}
-	code := "return "
+	stmt := &ast.ReturnStmt{}
 	for i, v := range s.Values {
 		c, returnedTypes, err := compileExpression(v, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if len(returnedTypes) != 1 {
-			return "", msg(s.LineNumber, s.Column, "Expression in return statement returns more than one value.")
+			return nil, exprMsg(v, "P0303", "Expression in return statement returns more than one value.")
 		}
 		if !isType(returnedTypes[0], expectedReturnTypes[i], false) {
-			return "", msg(s.LineNumber, s.Column, "Wrong type in return statement.")
+			return nil, exprMsg(v, "P0308", "Wrong type in return statement.")
 		}
-		code += c
-		if i < len(s.Values)-1 {
-			code += ", "
+		result, err := parseExprOf(c, v)
+		if err != nil {
+			return nil, err
 		}
+		stmt.Results = append(stmt.Results, result)
 	}
-	return code + "\n", nil
+	return stmt, nil
+}
+
+// returns an error unless a call with the number of arguments can call a function of the type.
+// A variadic function takes any number of arguments after its other parameters, or else (if spread)
+// a slice as its last argument.
+func checkArgumentCount(ft FunctionType, nArgs int, spread bool, line int, column int) error {
+	nParams := len(ft.Params)
+	if spread && !ft.Variadic {
+		return msg(line, column, "P0304", "Only the last argument of a call to a variadic function can be spread (...x).")
+	}
+	if ft.Variadic && !spread {
+		if nArgs < nParams-1 {
+			return msg(line, column, "P0301", "Wrong number of arguments in call: expected at least "+
+				strconv.Itoa(nParams-1)+", got "+strconv.Itoa(nArgs)+".")
+		}
+		return nil
+	}
+	if spread && nArgs != nParams {
+		return msg(line, column, "P0301", "A spread slice (...x) takes the place of all the variadic arguments: expected "+
+			strconv.Itoa(nParams)+" arguments, got "+strconv.Itoa(nArgs)+".")
+	}
+	if nArgs != nParams {
+		return msg(line, column, "P0301", "Wrong number of arguments in call: expected "+
+			strconv.Itoa(nParams)+", got "+strconv.Itoa(nArgs)+".")
+	}
+	return nil
+}
+
+// returns the type of the ith argument of a call to a function of the type (spread is whether the
+// call's last argument is a slice passed as the variadic arguments)
+func (ft FunctionType) argumentType(i int, spread bool) DataType {
+	last := len(ft.Params) - 1
+	if ft.Variadic && !spread && i >= last {
+		return ft.Params[last].(BuiltinType).Params[0]
+	}
+	return ft.Params[i]
 }
 
 func compileMethodCall(s MethodCall, pkg *Package, locals map[string]Variable) (string, []DataType, error) {
@@ -1497,7 +2620,7 @@ func compileMethodCall(s MethodCall, pkg *Package, locals map[string]Variable) (
 		return "", nil, err
 	}
 	if len(receiverTypes) != 1 {
-		return "", nil, msg(s.LineNumber, s.Column, "Method call receiver expression does not return one value.")
+		return "", nil, msg(s.LineNumber, s.Column, "P0303", "Method call receiver expression does not return one value.")
 	}
 	var ft FunctionType
 Outer:
@@ -1506,35 +2629,48 @@ Outer:
 		var ok bool
 		ft, ok = receiverType.Methods[s.MethodName]
 		if !ok {
-			return "", nil, msg(s.LineNumber, s.Column, "Method call struct receiver does not have such a method.")
+			return "", nil, msg(s.MethodLine, s.MethodColumn, "P0204", "Method call struct receiver does not have such a method."+
+				didYouMean(s.MethodName, receiverType.methodNames()))
+		}
+		if meth, ok := findMethod(receiverType.Pkg, receiverType.Name, s.MethodName); ok {
+			pkg.addSymbolOf(receiverType.Pkg, s.MethodLine, s.MethodColumn, s.MethodName, meth.info(), meth.LineNumber, meth.Column)
 		}
 	case InterfaceDefinition:
 		for _, sig := range receiverType.Methods {
 			if sig.Name == s.MethodName {
 				var err error
-				ft, err = sig.getFunctionType(pkg)
+				ft, err = sig.getFunctionType(receiverType.Pkg)
 				if err != nil {
 					return "", nil, err
 				}
+				pkg.addSymbolOf(receiverType.Pkg, s.MethodLine, s.MethodColumn, s.MethodName,
+					"method "+sig.info()+" (interface "+receiverType.Name+")", sig.LineNumber, sig.Column)
 				break Outer
 			}
 		}
-		return "", nil, msg(s.LineNumber, s.Column, "Method call receiver does not have a method of that name.")
+		return "", nil, msg(s.MethodLine, s.MethodColumn, "P0204", "Method call receiver does not have a method of that name."+
+			didYouMean(s.MethodName, receiverType.methodNames()))
 	default:
-		return "", nil, msg(s.LineNumber, s.Column, "Method call receiver must be a struct or interface value.")
+		return "", nil, msg(s.LineNumber, s.Column, "P0312", "Method call receiver must be a struct or interface value.")
 	}
 
-	code := receiver + "." + s.MethodName + "("
+	if err := checkArgumentCount(ft, len(s.Arguments), s.Spread, s.LineNumber, s.Column); err != nil {
+		return "", nil, err
+	}
+	code := receiver + "." + strings.Title(s.MethodName) + "("
 	for i, exp := range s.Arguments {
 		c, returnedTypes, err := compileExpression(exp, pkg, locals)
 		if err != nil {
 			return "", nil, err
 		}
 		if len(returnedTypes) != 1 {
-			return "", nil, msg(s.LineNumber, s.Column, "Method call argument does not return one value.")
+			return "", nil, msg(s.LineNumber, s.Column, "P0303", "Method call argument does not return one value.")
+		}
+		if !isType(returnedTypes[0], ft.argumentType(i, s.Spread), false) {
+			return "", nil, msg(s.LineNumber, s.Column, "P0304", "Method call argument is wrong type.")
 		}
-		if !isType(returnedTypes[0], ft.Params[i], false) {
-			return "", nil, msg(s.LineNumber, s.Column, "Method call argument is wrong type.")
+		if s.Spread && i == len(s.Arguments)-1 {
+			c += "..."
 		}
 		code += c + ", " // Go is OK with comma after last arg, so don't need special case for last arg
 	}
@@ -1552,11 +2688,11 @@ func compileFunctionCall(s FunctionCall, pkg *Package, locals map[string]Variabl
 			return "", nil, err
 		}
 		if len(returnedTypes) != 1 {
-			return "", nil, msg(s.LineNumber, s.Column, "operation at start of parens must return a function to call.")
+			return "", nil, msg(s.LineNumber, s.Column, "P0305", "operation at start of parens must return a function to call.")
 		}
 		ft, ok = returnedTypes[0].(FunctionType)
 		if !ok {
-			return "", nil, msg(s.LineNumber, s.Column, "operation at start of parens returned something other than a function.")
+			return "", nil, msg(s.LineNumber, s.Column, "P0305", "operation at start of parens returned something other than a function.")
 		}
 		code += c
 	case FunctionCall:
@@ -1565,11 +2701,11 @@ func compileFunctionCall(s FunctionCall, pkg *Package, locals map[string]Variabl
 			return "", nil, err
 		}
 		if len(returnedTypes) != 1 {
-			return "", nil, msg(s.LineNumber, s.Column, "function call at start of parens must return a function to call.")
+			return "", nil, msg(s.LineNumber, s.Column, "P0305", "function call at start of parens must return a function to call.")
 		}
 		ft, ok = returnedTypes[0].(FunctionType)
 		if !ok {
-			return "", nil, msg(s.LineNumber, s.Column, "function call at start of parens returned something other than a function.")
+			return "", nil, msg(s.LineNumber, s.Column, "P0305", "function call at start of parens returned something other than a function.")
 		}
 		code += c
 	case Token: // will always be an identifier
@@ -1582,26 +2718,28 @@ func compileFunctionCall(s FunctionCall, pkg *Package, locals map[string]Variabl
 			}
 			ft, ok = dt.(FunctionType)
 			if !ok {
-				return "", nil, msg(s.LineNumber, s.Column, "calling non-function.")
+				return "", nil, msg(s.LineNumber, s.Column, "P0305", "calling non-function.")
 			}
 			code += s.Content
+			pkg.addSymbol(s.LineNumber, s.Column, s.Content, "local "+s.Content+" "+TypeString(dt), v.LineNumber, v.Column)
 		} else {
 			fnDef, ok := pkg.Funcs[s.Content] // previous check means we don't have to check for zero val
 			if !ok {
-				return "", nil, msg(s.LineNumber, s.Column, "calling non-existent function.")
+				return "", nil, msg(s.LineNumber, s.Column, "P0201", "calling non-existent function: "+s.Content+"."+
+					didYouMean(s.Content, localNames(locals), pkg.topLevelNames(), operators))
 			}
 			var err error
 			ft, err = getFunctionType(fnDef)
 			if err != nil {
 				return "", nil, err
 			}
-			if fnDef.Pkg == pkg {
-				code += strings.Title(s.Content)
-			} else {
-				code += "_" + fnDef.Pkg.Prefix + "." + strings.Title(s.Content) // calling imported function
-			}
+			pkg.addSymbolOf(fnDef.Pkg, s.LineNumber, s.Column, s.Content, fnDef.info(), fnDef.LineNumber, fnDef.Column)
+			code += fnDef.Pkg.qualify(pkg, strings.Title(fnDef.Name))
 		}
 	}
+	if err := checkArgumentCount(ft, len(s.Arguments), s.Spread, s.LineNumber, s.Column); err != nil {
+		return "", nil, err
+	}
 	code += "(" // start of arguments
 	for i, exp := range s.Arguments {
 		c, returnedTypes, err := compileExpression(exp, pkg, locals)
@@ -1609,10 +2747,13 @@ func compileFunctionCall(s FunctionCall, pkg *Package, locals map[string]Variabl
 			return "", nil, err
 		}
 		if len(returnedTypes) != 1 {
-			return "", nil, msg(s.LineNumber, s.Column, "argument expression in function call doesn't return one value.")
+			return "", nil, msg(s.LineNumber, s.Column, "P0303", "argument expression in function call doesn't return one value.")
 		}
-		if !isType(returnedTypes[0], ft.Params[i], false) {
-			return "", nil, msg(s.LineNumber, s.Column, "argument of wrong type in function call.")
+		if !isType(returnedTypes[0], ft.argumentType(i, s.Spread), false) {
+			return "", nil, msg(s.LineNumber, s.Column, "P0304", "argument of wrong type in function call.")
+		}
+		if s.Spread && i == len(s.Arguments)-1 {
+			c += "..."
 		}
 		code += c + ", " // Go is OK with comma after last arg, so don't need special case for last arg
 	}
@@ -1622,35 +2763,87 @@ func compileFunctionCall(s FunctionCall, pkg *Package, locals map[string]Variabl
 	return code + ")", ft.ReturnTypes, nil
 }
 
-func (s Struct) getMemberType(name string) (DataType, error) {
+func (s Struct) getMemberType(member Token) (DataType, error) {
 	for i, n := range s.MemberNames {
-		if n == name {
+		if n == member.Content {
 			return s.MemberTypes[i], nil
 		}
 	}
-	return nil, msg(s.LineNumber, s.Column, "Struct does not contain member '"+name+"'")
+	return nil, msg(member.LineNumber, member.Column, "P0203", "Struct does not contain member '"+member.Content+"'."+
+		didYouMean(member.Content, s.MemberNames))
 }
 
-func Compile(filename string, outputDir string) (*Package, error) {
-	path, err := filepath.Abs(filename)
+// Compile compiles the GoPigeon source file into a Go program (Package.Code).
+// If debug is true, the program is instrumented for the debugger (see stdlib/debug.go).
+// If compilation fails, the package is nil, and the diagnostics report every error found.
+func Compile(filename string, outputDir string, debug bool) (*Package, []Diagnostic) {
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return nil, err
+		return nil, fileDiagnostics(err, filename, nil)
 	}
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
+	result, diags := CompileSource(filename, data, Options{OutputDir: outputDir, Debug: debug})
+	if result == nil {
+		return nil, diags
 	}
-	tokens, err := lex(string(data) + "\r\n")
+	return result.Package, diags
+}
+
+// Options configure a compilation by CompileSource.
+type Options struct {
+	// OutputDir is the import path prefix of the generated packages: the path of the module
+	// in which the program is built, with a trailing slash (e.g. "pigeon_output/").
+	OutputDir string
+	// Debug instruments the program for the debugger (see stdlib/debug.go).
+	Debug bool
+	// FS is the file tree from which the files imported by the source are read. The name of the
+	// source is then a path in FS (see fs.ValidPath), and imports are relative to its directory.
+	// If FS is nil, imports are read from the operating system's file system.
+	FS fs.FS
+}
+
+// A Result is a compiled program.
+type Result struct {
+	Code    string   // the generated Go program
+	Package *Package // the compiled definitions (e.g. for editor tooling)
+}
+
+// CompileSource compiles the source of the named file into a Go program without reading the file.
+// If compilation fails, the result is nil, and the diagnostics report every error found.
+func CompileSource(name string, src []byte, opts Options) (*Result, []Diagnostic) {
+	path := name
+	if opts.FS == nil {
+		var err error
+		path, err = filepath.Abs(name)
+		if err != nil {
+			return nil, fileDiagnostics(err, name, src)
+		}
+	} else if !fs.ValidPath(name) {
+		return nil, fileDiagnostics(errors.New("Invalid file name (not a path in the file system): "+name), name, src)
+	}
+	pkg := newPackage(path, opts.Debug)
+	pkg.Filename = name
+	pkg.fs = opts.FS
+	err := compileSource(pkg, src, opts.OutputDir)
 	if err != nil {
-		return nil, err
+		return nil, fileDiagnostics(err, name, src)
 	}
+	return &Result{Code: pkg.Code, Package: pkg}, nil
+}
 
-	// debug
-	// for i, t := range tokens {
-	// 	fmt.Println(i, t)
-	// }
+// Analyze compiles the source of the named file (without reading the file) for editor tooling.
+// The package is returned even if compilation fails.
+func Analyze(filename string, src []byte) (*Package, []Diagnostic) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, fileDiagnostics(err, filename, src)
+	}
+	pkg := newPackage(path, false)
+	pkg.Filename = filename
+	return pkg, fileDiagnostics(compileSource(pkg, src, ""), filename, src)
+}
 
-	pkg := &Package{
+func newPackage(path string, debug bool) *Package {
+	return &Package{
 		Globals:          map[string]GlobalDefinition{},
 		Types:            map[string]DataType{},
 		ImportedTypes:    map[DataType]bool{},
@@ -1662,13 +2855,35 @@ func Compile(filename string, outputDir string) (*Package, error) {
 		Interfaces:       map[string]InterfaceDefinition{},
 		ImportDefs:       map[string]ImportDefinition{},
 		ImportedPackages: map[string]*Package{},
-		NativeImports:    map[string]string{},
+		NativeImports:    map[string]NativeImportDefinition{},
 		FullPath:         path,
 		Prefix:           "p0",
+		Debug:            debug,
+	}
+}
+
+func compileSource(pkg *Package, src []byte, outputDir string) error {
+	// lex, parse, and compile errors are all reported together
+	var errs Diagnostics
+	tokens, err := lex(string(src) + "\r\n")
+	if err != nil {
+		errs = errs.add(err)
+		if tokens == nil {
+			return errs.err()
+		}
 	}
 	definitions, err := parse(tokens, pkg)
 	if err != nil {
-		return nil, err
+		// a line with a lex error likely also has a parse error, but only the lex error is reported
+		lexErrorLines := map[int]bool{}
+		for _, e := range errs {
+			lexErrorLines[e.Start.Line] = true
+		}
+		for _, e := range (Diagnostics{}).add(err) {
+			if !lexErrorLines[e.Start.Line] {
+				errs = append(errs, e)
+			}
+		}
 	}
 
 	packageNames := map[string]bool{}
@@ -1677,28 +2892,32 @@ func Compile(filename string, outputDir string) (*Package, error) {
 		case GlobalDefinition:
 			un := strings.ToUpper(d.Name)
 			if packageNames[un] {
-				return nil, msg(d.LineNumber, d.Column, "Duplicate top-level name: "+d.Name)
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate top-level name: "+d.Name))
+				continue
 			}
 			pkg.Globals[d.Name] = d
 			packageNames[un] = true
 		case FunctionDefinition:
 			un := strings.ToUpper(d.Name)
 			if packageNames[un] {
-				return nil, msg(d.LineNumber, d.Column, "Duplicate top-level name: "+d.Name)
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate top-level name: "+d.Name))
+				continue
 			}
 			pkg.Funcs[d.Name] = d
 			packageNames[un] = true
 		case StructDefinition:
 			un := strings.ToUpper(d.Name)
 			if packageNames[un] {
-				return nil, msg(d.LineNumber, d.Column, "Duplicate top-level name: "+d.Name)
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate top-level name: "+d.Name))
+				continue
 			}
 			pkg.StructDefs[d.Name] = d
 			packageNames[un] = true
 		case InterfaceDefinition:
 			un := strings.ToUpper(d.Name)
 			if packageNames[un] {
-				return nil, msg(d.LineNumber, d.Column, "Duplicate top-level name: "+d.Name)
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate top-level name: "+d.Name))
+				continue
 			}
 			pkg.Interfaces[d.Name] = d
 			pkg.Types[d.Name] = d
@@ -1711,16 +2930,42 @@ func Compile(filename string, outputDir string) (*Package, error) {
 			}
 			_, ok = st[d.Receiver.Name]
 			if ok {
-				return nil, msg(d.LineNumber, d.Column, "Duplicate method "+d.Name+" defined for type "+d.Receiver.Name)
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate method "+d.Name+" defined for type "+d.Receiver.Name))
+				continue
 			}
 			st[d.Receiver.Name] = d
+		case NativeImportDefinition:
+			name := d.Alias
+			if name == "" {
+				name = path.Base(d.Path)
+			}
+			if _, ok := pkg.NativeImports[name]; ok {
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate native import name: "+name))
+				continue
+			}
+			pkg.NativeImports[name] = d
+		case ImportDefinition:
+			if _, ok := pkg.ImportDefs[d.Path]; ok {
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate import of file "+d.Path))
+				continue
+			}
+			pkg.ImportDefs[d.Path] = d
 		default:
-			return nil, errors.New("Unrecognized definition")
+			return errors.New("Unrecognized definition")
 		}
 	}
+	// (err is that of the parse: a main which fails to parse is not missing)
+	if _, ok := pkg.Funcs["_main"]; !ok && !pkg.imported && err == nil {
+		errs = errs.add(msg(1, 1, "P0208", "Missing function main: a program starts by calling its main function."))
+	}
+	err = importDefinitions(pkg, packageNames, outputDir)
+	if err != nil {
+		// (without the definitions of a failed import, compiling would report each use of them)
+		return errs.add(err).err()
+	}
 	err = compile(pkg, outputDir)
 	if err != nil {
-		return nil, err
+		errs = errs.add(err)
 	}
-	return pkg, nil
+	return errs.err()
 }
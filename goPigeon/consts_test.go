@@ -0,0 +1,81 @@
+package goPigeon
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// compiles the source, returning its diagnostics each formatted as "line:column code message"
+func compileDiagnostics(t *testing.T, src string) []string {
+	_, diags := CompileSource("test.gopigeon", []byte(src), Options{})
+	strs := []string{}
+	for _, d := range diags {
+		strs = append(strs, fmt.Sprintf("%d:%d %s %s", d.Start.Line, d.Start.Column, d.Code, d.Message))
+	}
+	return strs
+}
+
+func TestConstErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"division by zero", "const a (div 1 0)\n",
+			[]string{"1:9 P0315 Division by zero in the value of constant a."}},
+		{"float division by zero", "const a F (div 1.0 0.0)\n",
+			[]string{"1:11 P0315 Division by zero in the value of constant a."}},
+		{"mod by zero", "const a (mod 5 (sub 2 2))\n",
+			[]string{"1:9 P0315 Division by zero in the value of constant a."}},
+		{"byte out of range", "const b Byte 300\n",
+			[]string{"1:14 P0315 The value of constant b, 300, can't be represented as a Byte."}},
+		{"negative byte", "const b Byte (sub 1 2)\n",
+			[]string{"1:14 P0315 The value of constant b, -1, can't be represented as a Byte."}},
+		{"byte operation out of range", "const m Byte 200\nconst n (add m m)\n",
+			[]string{"2:9 P0315 In the value of constant n, the add operation's result, 400, can't be represented as a Byte."}},
+		{"integer overflow", "const big (mul 9223372036854775807 2)\n",
+			[]string{"1:11 P0315 In the value of constant big, the mul operation's result, 18446744073709551614, can't be represented as an I."}},
+		// the errors of a constant aren't repeated for the constants and functions which use it
+		{"global in a constant", "global x I 3\nconst a (add x 1)\nconst b (add a 1)\nconst c 5\n\nfunc main\n    (println a b c)\n",
+			[]string{"2:14 P0313 The value of constant a must be a constant expression, but x is not a constant."}},
+		{"cycle", "const a (add b 1)\nconst b (add a 1)\nconst c (add b 1)\n\nfunc main\n    (println a b c)\n",
+			[]string{"2:14 P0313 Constant a is defined in terms of itself."}},
+		{"cycle of typed constants", "const a I (add b 1)\nconst b I (add a 1)\n",
+			[]string{"2:16 P0313 Constant a is defined in terms of itself."}},
+		// each constant is checked, not just the first with an error
+		{"several errors", "const a (div 1 0)\nconst b Byte 256\nconst c (len \"x\")\n",
+			[]string{
+				"1:9 P0315 Division by zero in the value of constant a.",
+				"2:14 P0315 The value of constant b, 256, can't be represented as a Byte.",
+				"3:9 P0313 The value of constant c must be a constant expression, but the len operation is evaluated only when the program runs.",
+			}},
+	}
+	for _, test := range tests {
+		src := test.src
+		if !strings.Contains(src, "func main") {
+			src += "\nfunc main\n    (println \"hi\")\n"
+		}
+		got := compileDiagnostics(t, src)
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("%s: got diagnostics:\n%s\nwant:\n%s", test.name, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
+
+func TestConstValues(t *testing.T) {
+	src := "const a (div 7 2)\nconst b F (div 7 2)\nconst c (div 7.0 2.0)\nconst d (mod -7 2)\n" +
+		"const e Byte (add 200 55)\nconst f (and (lt 1 2) (eq \"a\" \"a\") (not false))\nconst g (concat \"ab\" \"c\")\n" +
+		"const h (dec (band 6 5))\nconst i (bor (bxor 6 3) 8)\n\nfunc main\n    (println a b c d e f g h i)\n"
+	result, diags := CompileSource("test.gopigeon", []byte(src), Options{})
+	if result == nil {
+		t.Fatalf("compilation failed: %v", diags)
+	}
+	want := map[string]string{"a": "3", "b": "3", "c": "3.5", "d": "-1", "e": "255", "f": "true", "g": `"abc"`, "h": "3", "i": "13"}
+	for name, value := range want {
+		c := result.Package.Globals[name].Constant
+		if c == nil || c.String() != value {
+			t.Errorf("constant %s is %v, want %s", name, c, value)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package goPigeon
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// the files of a program, which exist only in memory
+var programFS = fstest.MapFS{
+	"app/lib/greeting.gopigeon": &fstest.MapFile{Data: []byte(
+		"import \"words.gopigeon\"\n" +
+			"    hello\n\n" +
+			"func greet name Str : Str\n" +
+			"    return (concat (hello) \", \" name)\n")},
+	"app/lib/words.gopigeon": &fstest.MapFile{Data: []byte(
+		"func hello : Str\n" +
+			"    return \"hi\"\n")},
+}
+
+// A program whose imports (and their imports) are read from an fs.FS, not the operating system's file system.
+func TestCompileFromFS(t *testing.T) {
+	src := "import \"lib/greeting.gopigeon\"\n" +
+		"    greet\n\n" +
+		"func main\n" +
+		"    (println (greet \"Alice\"))\n"
+	result, diags := CompileSource("app/main.gopigeon", []byte(src), Options{OutputDir: "pigeon_output/", FS: programFS})
+	if result == nil {
+		t.Fatalf("compilation failed: %v", diags)
+	}
+	imported := []string{}
+	for _, p := range result.Package.Imports() {
+		imported = append(imported, p.FullPath)
+	}
+	if got := strings.Join(imported, " "); got != "app/lib/greeting.gopigeon app/lib/words.gopigeon" &&
+		got != "app/lib/words.gopigeon app/lib/greeting.gopigeon" {
+		t.Errorf("imported files %q, want app/lib/greeting.gopigeon and app/lib/words.gopigeon", got)
+	}
+	if !strings.Contains(result.Code, ".Greet(\"Alice\")") {
+		t.Errorf("the generated code doesn't call the imported greet:\n%s", result.Code)
+	}
+}
+
+// An imported file missing from the fs.FS is reported at the import, even if a file of that
+// path exists in the operating system's file system.
+func TestCompileFromFSMissingFile(t *testing.T) {
+	// (fs_test.go exists in the directory in which the test runs)
+	src := "import \"fs_test.go\"\n" +
+		"    greet\n\n" +
+		"func main\n" +
+		"    (println (greet \"Alice\"))\n"
+	result, diags := CompileSource("main.gopigeon", []byte(src), Options{FS: programFS})
+	if result != nil {
+		t.Fatal("compilation succeeded, want an error for the missing import")
+	}
+	if len(diags) != 1 || diags[0].Code != "P0207" || diags[0].Start.Line != 1 ||
+		!strings.Contains(diags[0].Message, "Cannot read imported file fs_test.go") ||
+		!strings.Contains(diags[0].Message, "file does not exist") {
+		t.Errorf("got diagnostics %v, want one P0207 for the missing file on line 1", diags)
+	}
+}
@@ -0,0 +1,298 @@
+package goPigeon
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The generated program is built as a go/ast syntax tree, which go/printer formats.
+// Declarations and statements are built as nodes. Expressions (and types) are compiled to
+// Go source text (see compileExpression), and each is parsed into nodes where it's used,
+// so malformed code is caught as soon as it's generated rather than by the Go compiler.
+//
+// Each declaration and statement is mapped to its line of the source (see mapLine), and the
+// printed code is given a //line directive before each of them (so that panics and Go compiler
+// errors report source lines instead of lines of the generated code). A declaration with no source
+// line gets a directive back to its own line of the generated file.
+
+// the name of the file to which the driver writes the generated code
+// (every package of the program is written to a file of this name in its own directory)
+const outputFile = "output.go"
+
+// records the source line of a generated declaration or statement
+func (p *Package) mapLine(node ast.Node, line int) {
+	if p.lines == nil {
+		p.lines = map[ast.Node]int{}
+	}
+	p.lines[node] = line
+}
+
+// formats the generated file as Go source (as gofmt would), with the //line directives
+func (p *Package) print(file *ast.File) (string, error) {
+	fset := token.NewFileSet()
+	fset.AddFile("", -1, 1) // holds parsedPos
+	sortImports(file)
+	var buf bytes.Buffer
+	err := (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&buf, fset, file)
+	if err != nil {
+		return "", internalError(0, 0, err)
+	}
+	// the nodes have no positions, so the printed lines of the mapped nodes are found by parsing
+	// the printed code, whose declarations and statements are those of the generated tree (the printer
+	// changes only expressions, e.g. dropping the parens around the condition of an if or for)
+	printed, err := parser.ParseFile(fset, "", buf.Bytes(), 0)
+	if err != nil {
+		return "", internalError(0, 0, err)
+	}
+	generatedNodes, printedNodes := mappableNodes(file), mappableNodes(printed)
+	if len(generatedNodes) != len(printedNodes) {
+		return "", internalError(0, 0, errors.New("the printed code doesn't match the syntax tree"))
+	}
+	inserts := map[int]string{} // printed line number: text to insert before the line
+	firstMapped := 0            // the printed line of the first mapped node (0 if none)
+	for i, n := range generatedNodes {
+		if line, ok := p.lines[n]; ok {
+			printedLine := fset.Position(printedNodes[i].Pos()).Line
+			inserts[printedLine] = "//line " + filepath.Base(p.FullPath) + ":" + strconv.Itoa(line) + "\n"
+			if firstMapped == 0 || printedLine < firstMapped {
+				firstMapped = printedLine
+			}
+		}
+	}
+	// a declaration with no source line (e.g. the generated main) which follows mapped code
+	// gets its own lines back, rather than the source line of the code before it
+	resets := map[int]bool{} // printed line number: the line is preceded by a reset directive
+	for i, d := range file.Decls {
+		printedLine := fset.Position(printed.Decls[i].Pos()).Line
+		if _, ok := p.lines[d]; !ok && firstMapped != 0 && printedLine > firstMapped {
+			resets[printedLine] = true
+		}
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	// the printer separates declarations by blank lines only where their positions are far apart
+	for _, d := range printed.Decls[1:] {
+		printedLine := fset.Position(d.Pos()).Line
+		if lines[printedLine-2] != "\n" {
+			inserts[printedLine] = "\n" + inserts[printedLine]
+		}
+	}
+	var code strings.Builder
+	for i, line := range lines {
+		code.WriteString(inserts[i+1])
+		if resets[i+1] {
+			// (the line after the directive is the directive's line + 1)
+			outputLine := strings.Count(code.String(), "\n") + 2
+			code.WriteString("//line " + outputFile + ":" + strconv.Itoa(outputLine) + ":1\n")
+		}
+		code.WriteString(line)
+	}
+	return code.String(), nil
+}
+
+// sorts the imports by path, as gofmt does (ast.SortImports needs the positions of the imports)
+func sortImports(file *ast.File) {
+	for _, d := range file.Decls {
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			sort.SliceStable(d.Specs, func(i, j int) bool {
+				return d.Specs[i].(*ast.ImportSpec).Path.Value < d.Specs[j].(*ast.ImportSpec).Path.Value
+			})
+		}
+	}
+}
+
+// returns the declarations, specs, and statements of the tree (the nodes which can be mapped to
+// source lines) in the order visited by ast.Inspect
+func mappableNodes(node ast.Node) []ast.Node {
+	nodes := []ast.Node{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n.(type) {
+		case ast.Decl, ast.Spec, ast.Stmt:
+			nodes = append(nodes, n)
+		}
+		return true
+	})
+	return nodes
+}
+
+// returns an error for generated code which isn't valid Go (a bug in the compiler, not in the source)
+func internalError(line int, column int, err error) error {
+	return Diagnostic{Start: Position{line, column}, Severity: SeverityError,
+		Message: "Internal compiler error (the generated Go code is malformed): " + err.Error()}
+}
+
+// parses the generated code of an expression or type
+func parseExpr(code string, line int, column int) (ast.Expr, error) {
+	e, err := parser.ParseExpr(code)
+	if err != nil {
+		return nil, internalError(line, column, err)
+	}
+	clearPositions(e)
+	return e, nil
+}
+
+// parses the generated code of a list of statements
+func parseStmts(code string, line int, column int) ([]ast.Stmt, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\nfunc _() {\n"+code+"\n}\n", 0)
+	if err != nil {
+		return nil, internalError(line, column, err)
+	}
+	body := f.Decls[0].(*ast.FuncDecl).Body
+	clearPositions(body)
+	return body.List, nil
+}
+
+// parses the Go code of a native definition (the fields of a struct type or the body of a function)
+// as the code between the opening line and the closing brace of a declaration, e.g. "func _() {".
+// The statements of the code are mapped to their source lines (the code starts on line). (Fields
+// aren't mapped: a //line directive between fields would change how gofmt aligns them.)
+func (p *Package) parseNative(opening string, code string, line int) (ast.Decl, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", "package p\n"+opening+"\n"+code+"\n}\n", 0)
+	const codeLine = 3 // the line of the parsed file on which the code starts
+	if err != nil {
+		if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+			pos := list[0].Pos
+			errLine, column := line+pos.Line-codeLine, pos.Column
+			// an error at the end of the code (e.g. a missing operand) is found at the closing brace
+			// which follows the code, so it's reported just after the code's last line instead
+			codeLines := strings.Split(code, "\n")
+			last := len(codeLines) - 1
+			for last > 0 && strings.TrimSpace(codeLines[last]) == "" {
+				last--
+			}
+			if pos.Line-codeLine > last {
+				errLine, column = line+last, len(strings.TrimRight(codeLines[last], " \t\r"))+1
+			}
+			return nil, msg(errLine, column, "P0114", "Native code isn't valid Go: "+list[0].Msg+".")
+		}
+		return nil, msg(line, 1, "P0114", "Native code isn't valid Go: "+err.Error()+".")
+	}
+	ast.Inspect(f.Decls[0], func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.BlockStmt:
+		case ast.Stmt:
+			p.mapLine(n, line+fset.Position(n.Pos()).Line-codeLine)
+		}
+		return true
+	})
+	clearPositions(f.Decls[0])
+	return f.Decls[0], nil
+}
+
+var posType = reflect.TypeOf(token.NoPos)
+
+// The position of every node of parsed code, which is on line 1 of the first file of the file set
+// in which the generated code is printed. (The printer lays out nodes without positions as it
+// would if each token were on its own line, e.g. printing an interface{} as an empty block.)
+const parsedPos = token.Pos(1)
+
+// parsed code is positioned in its own file set, so its positions are replaced before it joins the tree
+func clearPositions(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		v := reflect.ValueOf(n)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return true
+		}
+		v = v.Elem()
+		for i := 0; i < v.NumField(); i++ {
+			// (the validity of some positions is significant, e.g. CallExpr.Ellipsis)
+			if f := v.Field(i); f.Type() == posType && f.Int() != int64(token.NoPos) {
+				f.SetInt(int64(parsedPos))
+			}
+		}
+		return true
+	})
+}
+
+// returns the identifier, or for a name of the form "x.y", the selector expression
+func ident(name string) ast.Expr {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return &ast.SelectorExpr{X: ident(name[:i]), Sel: ast.NewIdent(name[i+1:])}
+	}
+	return ast.NewIdent(name)
+}
+
+func call(fn string, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{Fun: ident(fn), Args: args}
+}
+
+func callStmt(fn string, args ...ast.Expr) ast.Stmt {
+	return &ast.ExprStmt{X: call(fn, args...)}
+}
+
+func idents(names ...string) []ast.Expr {
+	exprs := make([]ast.Expr, len(names))
+	for i, name := range names {
+		exprs[i] = ast.NewIdent(name)
+	}
+	return exprs
+}
+
+func importSpec(name string, path string) ast.Spec {
+	return &ast.ImportSpec{Name: ast.NewIdent(name), Path: &ast.BasicLit{Kind: token.STRING, Value: `"` + path + `"`}}
+}
+
+// returns a declaration of the variable (with no value if val is nil)
+func varDecl(name string, t ast.Expr, val ast.Expr) *ast.GenDecl {
+	spec := &ast.ValueSpec{Names: []*ast.Ident{ast.NewIdent(name)}, Type: t}
+	if val != nil {
+		spec.Values = []ast.Expr{val}
+	}
+	return &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{spec}}
+}
+
+func block(stmts ...ast.Stmt) *ast.BlockStmt {
+	return &ast.BlockStmt{List: stmts}
+}
+
+// parses the generated code of the expression
+func parseExprOf(code string, e Expression) (ast.Expr, error) {
+	line, column := position(e)
+	return parseExpr(code, line, column)
+}
+
+// parses the generated code of an expression evaluated as a statement
+func exprStmt(code string, e Expression) (ast.Stmt, error) {
+	x, err := parseExprOf(code, e)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ExprStmt{X: x}, nil
+}
+
+func define(name string, val ast.Expr) ast.Stmt {
+	return &ast.AssignStmt{Lhs: idents(name), Tok: token.DEFINE, Rhs: []ast.Expr{val}}
+}
+
+// returns the import specs whose names the file uses
+func usedImports(file *ast.File, specs []ast.Spec) []ast.Spec {
+	used := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok {
+				used[x.Name] = true
+			}
+		}
+		return true
+	})
+	usedSpecs := []ast.Spec{}
+	for _, spec := range specs {
+		if used[spec.(*ast.ImportSpec).Name.Name] {
+			usedSpecs = append(usedSpecs, spec)
+		}
+	}
+	return usedSpecs
+}
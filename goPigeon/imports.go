@@ -0,0 +1,213 @@
+package goPigeon
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A program may span several files. An import definition names another file, by a path relative
+// to the importing file, and the definitions of that file to bring into scope, each optionally
+// under an alias:
+//
+//	import "shapes/circle.gopigeon"
+//	    Circle
+//	    area circleArea
+//
+// Each imported file is compiled once (however many files import it) into its own Go package,
+// whose prefix qualifies the names of its definitions in the importing packages, e.g. _p1.Area.
+
+// the files of one compilation: the compiled file and the files it imports, directly or indirectly
+type importGraph struct {
+	packages  map[string]*Package // the imported packages, by full path
+	order     []*Package          // the imported packages, in the order they were compiled
+	importing []*Package          // the packages being compiled, each imported by the one before
+	failed    map[string]bool     // full paths of the imported files which failed to compile
+	count     int                 // the number of imported files compiled (or being compiled)
+	outputDir string
+}
+
+// Imports returns the packages of the files imported by the package, directly or indirectly,
+// in the order they were compiled. Each package's code is built at the import path OutputDir + Prefix.
+func (p *Package) Imports() []*Package {
+	if p.graph == nil {
+		return nil
+	}
+	return p.graph.order
+}
+
+func (p *Package) importsInOrder() []ImportDefinition {
+	imports := []ImportDefinition{}
+	for _, imp := range p.ImportDefs {
+		imports = append(imports, imp)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		a, b := imports[i], imports[j]
+		return sourceOrder(a.LineNumber, a.Column, a.Path, b.LineNumber, b.Column, b.Path)
+	})
+	return imports
+}
+
+// compiles the files imported by the package and adds the imported definitions to the package
+// (names holds the package's top-level names, in upper case, which the imported names must not repeat)
+func importDefinitions(pkg *Package, names map[string]bool, outputDir string) error {
+	if len(pkg.ImportDefs) == 0 {
+		return nil
+	}
+	if pkg.graph == nil {
+		pkg.graph = &importGraph{
+			packages:  map[string]*Package{},
+			importing: []*Package{pkg},
+			failed:    map[string]bool{},
+			outputDir: outputDir,
+		}
+	}
+	var errs Diagnostics
+	for _, imp := range pkg.importsInOrder() {
+		imported, err := importFile(pkg, imp)
+		if err != nil {
+			errs = errs.add(err)
+			continue
+		}
+		for i, name := range imp.Names {
+			local := name
+			if imp.Aliases[i] != "" {
+				local = imp.Aliases[i]
+			}
+			un := strings.ToUpper(local)
+			pos := imp.Positions[i]
+			if names[un] {
+				errs = errs.add(msg(pos.Line, pos.Column, "P0202", "Duplicate top-level name: "+local))
+				continue
+			}
+			switch d := imported.getExportedDefinition(name).(type) {
+			case GlobalDefinition:
+				pkg.Globals[local] = d
+			case FunctionDefinition:
+				pkg.Funcs[local] = d
+			case StructDefinition:
+				pkg.Types[local] = imported.Structs[name]
+			case InterfaceDefinition:
+				pkg.Interfaces[local] = d
+				pkg.Types[local] = d
+			default:
+				errs = errs.add(msg(pos.Line, pos.Column, "P0201", "Imported file "+imp.Path+
+					" has no definition named "+name+"."+didYouMean(name, imported.exportedNames())))
+				continue
+			}
+			names[un] = true
+		}
+	}
+	return errs.err()
+}
+
+// returns the package of the imported file, compiling the file if it hasn't already been compiled
+func importFile(pkg *Package, imp ImportDefinition) (*Package, error) {
+	graph := pkg.graph
+	name, full := pkg.resolve(imp.Path)
+	for i, p := range graph.importing {
+		if p.FullPath == full {
+			cycle := []string{}
+			for _, p := range graph.importing[i:] {
+				cycle = append(cycle, p.Filename)
+			}
+			return nil, msg(imp.LineNumber, imp.Column, "P0206", "Import cycle: "+
+				strings.Join(cycle, " imports ")+" imports "+name+".")
+		}
+	}
+	if graph.failed[full] {
+		return nil, msg(imp.LineNumber, imp.Column, "P0207", "Imported file "+imp.Path+" has errors.")
+	}
+	imported, ok := graph.packages[full]
+	if !ok {
+		src, err := pkg.readFile(full)
+		if err != nil {
+			return nil, msg(imp.LineNumber, imp.Column, "P0207", "Cannot read imported file "+imp.Path+": "+err.Error())
+		}
+		imported = newPackage(full, false) // only the importing file is instrumented for the debugger
+		imported.Filename = name
+		graph.count++
+		imported.Prefix = "p" + strconv.Itoa(graph.count)
+		imported.fs = pkg.fs
+		imported.graph = graph
+		imported.imported = true
+		graph.importing = append(graph.importing, imported)
+		err = compileSource(imported, src, graph.outputDir)
+		graph.importing = graph.importing[:len(graph.importing)-1]
+		if err != nil {
+			graph.failed[full] = true
+			// the errors are reported in the imported file, and the import is reported as failing
+			errs := Diagnostics(fileDiagnostics(err, name, src))
+			errs = errs.add(msg(imp.LineNumber, imp.Column, "P0207", "Imported file "+imp.Path+" has errors."))
+			return nil, errs
+		}
+		graph.packages[full] = imported
+		graph.order = append(graph.order, imported)
+	}
+	pkg.ImportedPackages[full] = imported
+	return imported, nil
+}
+
+// returns the name (joined to the package's Filename) and full path of the file at the path
+// relative to the directory of the package's source file
+func (p *Package) resolve(name string) (string, string) {
+	if p.fs != nil {
+		return path.Join(path.Dir(p.Filename), name), path.Join(path.Dir(p.FullPath), name)
+	}
+	return filepath.Join(filepath.Dir(p.Filename), filepath.FromSlash(name)),
+		filepath.Join(filepath.Dir(p.FullPath), filepath.FromSlash(name))
+}
+
+func (p *Package) readFile(full string) ([]byte, error) {
+	if p.fs != nil {
+		return fs.ReadFile(p.fs, full)
+	}
+	return ioutil.ReadFile(full)
+}
+
+// returns the Go name of the package's definition as referred to in the code of the package from
+// (qualified by the package's prefix if from is another package)
+func (p *Package) qualify(from *Package, name string) string {
+	if p == from {
+		return name
+	}
+	return "_" + p.Prefix + "." + name
+}
+
+// returns the interface's name qualified by the prefix of its package
+// (distinguishing interfaces of different files which have the same name)
+func (iface InterfaceDefinition) qualifiedName() string {
+	return iface.Pkg.Prefix + "." + iface.Name
+}
+
+// returns the definition of the name in the package's own file (nil if there is none)
+func (p *Package) getExportedDefinition(name string) Definition {
+	if g, ok := p.Globals[name]; ok && g.Pkg == p {
+		return g
+	}
+	if st, ok := p.StructDefs[name]; ok {
+		return st
+	}
+	if f, ok := p.Funcs[name]; ok && f.Pkg == p {
+		return f
+	}
+	if inter, ok := p.Interfaces[name]; ok && inter.Pkg == p {
+		return inter
+	}
+	return nil
+}
+
+// returns the names which the package's file defines
+func (p *Package) exportedNames() []string {
+	names := []string{}
+	for _, n := range append(p.topLevelNames(), p.typeNames()...) {
+		if p.getExportedDefinition(n) != nil {
+			names = append(names, n)
+		}
+	}
+	return names
+}
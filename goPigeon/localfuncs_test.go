@@ -0,0 +1,32 @@
+package goPigeon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocalFuncRedefinition(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"local variable", "func main\n    locals f I\n    localfunc f : I\n        return 1\n    (println f)\n",
+			"3:5 P0202 localfunc f is already defined as a parameter or local variable."},
+		{"parameter", "func g f I\n    localfunc f : I\n        return 1\n    (println (f))\n\nfunc main\n    (g 1)\n",
+			"2:5 P0202 localfunc f is already defined as a parameter or local variable."},
+		{"localfunc", "func main\n    localfunc f : I\n        return 1\n    localfunc f : I\n        return 2\n    (println (f))\n",
+			"4:5 P0202 localfunc f is already defined as a localfunc."},
+		{"enclosing localfunc", "func main\n    localfunc f : I\n        localfunc f : I\n            return 2\n        return 1\n    (println (f))\n",
+			"3:9 P0202 localfunc f is already defined as a localfunc."},
+		// a local function's type is written as a Fn type, but its name isn't a local function
+		{"local of function type", "func main\n    locals f Fn<: I>\n    localfunc f : I\n        return 1\n    (println (f))\n",
+			"3:5 P0202 localfunc f is already defined as a parameter or local variable."},
+	}
+	for _, test := range tests {
+		got := compileDiagnostics(t, test.src)
+		if strings.Join(got, "\n") != test.want {
+			t.Errorf("%s: got diagnostics:\n%s\nwant:\n%s", test.name, strings.Join(got, "\n"), test.want)
+		}
+	}
+}
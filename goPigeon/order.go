@@ -0,0 +1,93 @@
+package goPigeon
+
+import "sort"
+
+// The definitions of a package are kept in maps, which iterate in a different order every time.
+// The compiler instead visits them in source order, so that compiling the same source
+// always generates the same code (and reports the same error first).
+
+// reports whether the definition at the first position comes before the definition at the second
+// (definitions of different files may share a position, so ties are broken by name)
+func sourceOrder(line1 int, column1 int, name1 string, line2 int, column2 int, name2 string) bool {
+	if line1 != line2 {
+		return line1 < line2
+	}
+	if column1 != column2 {
+		return column1 < column2
+	}
+	return name1 < name2
+}
+
+func (p *Package) funcsInOrder() []FunctionDefinition {
+	funcs := []FunctionDefinition{}
+	for _, fn := range p.Funcs {
+		funcs = append(funcs, fn)
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		a, b := funcs[i], funcs[j]
+		return sourceOrder(a.LineNumber, a.Column, a.Name, b.LineNumber, b.Column, b.Name)
+	})
+	return funcs
+}
+
+func (p *Package) globalsInOrder() []GlobalDefinition {
+	globals := []GlobalDefinition{}
+	for _, g := range p.Globals {
+		globals = append(globals, g)
+	}
+	sort.Slice(globals, func(i, j int) bool {
+		a, b := globals[i], globals[j]
+		return sourceOrder(a.LineNumber, a.Column, a.Name, b.LineNumber, b.Column, b.Name)
+	})
+	return globals
+}
+
+func (p *Package) structDefsInOrder() []StructDefinition {
+	structs := []StructDefinition{}
+	for _, st := range p.StructDefs {
+		structs = append(structs, st)
+	}
+	sort.Slice(structs, func(i, j int) bool {
+		a, b := structs[i], structs[j]
+		return sourceOrder(a.LineNumber, a.Column, a.Name, b.LineNumber, b.Column, b.Name)
+	})
+	return structs
+}
+
+func (p *Package) structsInOrder() []Struct {
+	structs := []Struct{}
+	for _, st := range p.Structs {
+		structs = append(structs, st)
+	}
+	sort.Slice(structs, func(i, j int) bool {
+		a, b := structs[i], structs[j]
+		return sourceOrder(a.LineNumber, a.Column, a.Name, b.LineNumber, b.Column, b.Name)
+	})
+	return structs
+}
+
+func (p *Package) methodsInOrder() []MethodDefinition {
+	methods := []MethodDefinition{}
+	for _, methByStruct := range p.Methods {
+		for _, m := range methByStruct {
+			methods = append(methods, m)
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		a, b := methods[i], methods[j]
+		return sourceOrder(a.LineNumber, a.Column, a.Name, b.LineNumber, b.Column, b.Name)
+	})
+	return methods
+}
+
+func (p *Package) interfacesInOrder() []InterfaceDefinition {
+	interfaces := []InterfaceDefinition{}
+	for _, iface := range p.Interfaces {
+		interfaces = append(interfaces, iface)
+	}
+	sort.Slice(interfaces, func(i, j int) bool {
+		a, b := interfaces[i], interfaces[j]
+		return sourceOrder(a.LineNumber, a.Column, a.Name, b.LineNumber, b.Column, b.Name)
+	})
+	return interfaces
+}
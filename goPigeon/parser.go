@@ -1,7 +1,7 @@
 package goPigeon
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -18,15 +18,19 @@ func isNumeral(r rune) bool {
 // assumes the string ends with a newline (because that makes it a bit easier to lex)
 func lex(text string) ([]Token, error) {
 	var tokens []Token
+	var errs Diagnostics // on an error, the rest of the line is skipped
 
 	line := 1
 	column := 1
 	runes := []rune(text) // to account for unicode properly, we need to iterate through runes, not bytes
 
+Outer:
 	for i := 0; i < len(runes); {
 		r := runes[i]
 		if r >= 128 {
-			return nil, msg(line, column, "File improperly contains a non-ASCII character.")
+			errs = errs.add(msg(line, column, "P0001", "File improperly contains a non-ASCII character."))
+			i = endOfLine(runes, i)
+			continue Outer
 		}
 		if r == '\n' {
 			tokens = append(tokens, Token{Newline, "\n", line, column})
@@ -35,7 +39,8 @@ func lex(text string) ([]Token, error) {
 			i++
 		} else if r == '\r' {
 			if runes[i+1] != '\n' {
-				return nil, msg(line, column, "Improper newline: expecting LF (linefeed) after CR (carriage return).")
+				errs = errs.add(msg(line, column, "P0003", "Improper newline: expecting LF (linefeed) after CR (carriage return)."))
+				i-- // treat the lone CR as a newline
 			}
 			tokens = append(tokens, Token{Newline, "\n", line, column})
 			line++
@@ -43,15 +48,17 @@ func lex(text string) ([]Token, error) {
 			i += 2
 		} else if r == '/' { // start of a comment
 			if runes[i+1] != '/' {
-				return nil, msg(line, column, "Expected second / (slash).")
+				errs = errs.add(msg(line, column, "P0004", "Expected second / (slash)."))
+				i = endOfLine(runes, i)
+				continue Outer
 			}
 			for runes[i] != '\n' && runes[i] != '\r' {
 				i++
 			}
-			i++
-			if runes[i] == '\n' { // LF after CR
+			if runes[i] == '\r' && runes[i+1] == '\n' { // LF after CR
 				i++
 			}
+			i++
 			if len(tokens) > 1 && tokens[len(tokens)-1].Type != Newline {
 				tokens = append(tokens, Token{Newline, "\n", line, column})
 			}
@@ -109,7 +116,9 @@ func lex(text string) ([]Token, error) {
 			}
 			tokens = append(tokens, Token{tokenType, string(runes[firstIdx:i]), line, column})
 		} else if r == '\t' {
-			return nil, msg(line, column, "File improperly contains a tab character.")
+			errs = errs.add(msg(line, column, "P0002", "File improperly contains a tab character."))
+			i = endOfLine(runes, i)
+			continue Outer
 		} else if r == '"' { // start of a string
 			prev := r
 			endIdx := i + 1
@@ -117,7 +126,9 @@ func lex(text string) ([]Token, error) {
 				current := runes[endIdx]
 				// loop will never run past end of runes because \n appended to end of file
 				if current == '\n' || current == '\r' {
-					return nil, msg(line, column, "String literal not closed.")
+					errs = errs.add(msg(line, column, "P0005", "String literal not closed."))
+					i = endOfLine(runes, i)
+					continue Outer
 				}
 				if current == '"' && prev != '\\' { // end of the string
 					endIdx++
@@ -141,31 +152,42 @@ func lex(text string) ([]Token, error) {
 					break
 				} else if current == '.' {
 					if decimalPointIdx != -1 {
-						return nil, msg(line, column, "Number literal has more than one decimal point.")
+						errs = errs.add(msg(line, column, "P0006", "Number literal has more than one decimal point."))
+						i = endOfLine(runes, i)
+						continue Outer
 					}
 					decimalPointIdx = endIdx
 				} else if !isNumeral(current) {
-					return nil, msg(line, column, "Number literal not properly formed.")
+					errs = errs.add(msg(line, column, "P0006", "Number literal not properly formed."))
+					i = endOfLine(runes, i)
+					continue Outer
 				}
 				endIdx++
 			}
 
 			if decimalPointIdx == endIdx {
-				return nil, msg(line, column, "Number literal should not end with decimal point.")
+				errs = errs.add(msg(line, column, "P0006", "Number literal should not end with decimal point."))
+				i = endOfLine(runes, i)
+				continue Outer
 			}
 
 			tokens = append(tokens, Token{NumberLiteral, string(runes[i:endIdx]), line, column})
 			column += (endIdx - i)
 			i = endIdx
 		} else if r == '\'' { // start of a multi-line string
-			if runes[i+1] != '\'' && runes[i+2] != '\'' {
-				return nil, msg(line, column, "Single quotes must come in threes to start multi-line string.")
+			if runes[i+1] != '\'' || runes[i+2] != '\'' {
+				errs = errs.add(msg(line, column, "P0005", "Single quotes must come in threes to start multi-line string."))
+				i = endOfLine(runes, i)
+				continue Outer
 			}
+			startLine, startColumn := line, column
 			column += 3
 			endIdx := i + 3
 			for {
 				if endIdx >= len(runes) {
-					return nil, msg(line, column, "Multi-line string is never closed.")
+					errs = errs.add(msg(line, column, "P0005", "Multi-line string is never closed."))
+					tokens = append(tokens, Token{Newline, "\n", line, column})
+					break Outer
 				}
 				if runes[endIdx] == '\'' && runes[endIdx+1] == '\'' && runes[endIdx+2] == '\'' {
 					endIdx += 3
@@ -180,7 +202,7 @@ func lex(text string) ([]Token, error) {
 				}
 				endIdx++
 			}
-			tokens = append(tokens, Token{MultilineStringLiteral, string(runes[i:endIdx]), line, column})
+			tokens = append(tokens, Token{MultilineStringLiteral, string(runes[i:endIdx]), startLine, startColumn})
 			i = endIdx
 		} else if isAlpha(r) { // start of a word (_ is not a valid identifier character in Pigeon)
 			endIdx := i + 1
@@ -191,7 +213,9 @@ func lex(text string) ([]Token, error) {
 				if strings.Contains(" \r\n)<>.[", string(current)) {
 					break
 				} else if !(isAlpha(current) || isNumeral(current)) {
-					return nil, msg(line, column, "Word improperly formed.")
+					errs = errs.add(msg(line, column, "P0007", "Word improperly formed."))
+					i = endOfLine(runes, i)
+					continue Outer
 				}
 				endIdx++
 			}
@@ -230,7 +254,9 @@ func lex(text string) ([]Token, error) {
 			column += (endIdx - i)
 			i = endIdx
 		} else {
-			return nil, msg(line, column, "Unexpected character "+string(r)+".")
+			errs = errs.add(msg(line, column, "P0008", "Unexpected character "+string(r)+"."))
+			i = endOfLine(runes, i)
+			continue Outer
 		}
 	}
 
@@ -271,10 +297,10 @@ func lex(text string) ([]Token, error) {
 
 	// remove all sequences of [newline -> indentation -> comma], replace with space
 	if tokens[0].Type == Comma || tokens[1].Type == Comma {
-		return nil, msg(line, column, "Unexpected comma at start of file.")
+		return nil, errs.add(msg(tokens[0].LineNumber, tokens[0].Column, "P0009", "Unexpected comma at start of file."))
 	}
 	if tokens[len(tokens)-2].Type == Comma || tokens[len(tokens)-1].Type == Comma {
-		return nil, msg(line, column, "Unexpected comma at end of file.")
+		return nil, errs.add(msg(line, column, "P0009", "Unexpected comma at end of file."))
 	}
 	filteredTokens = []Token{}
 	for i := 0; i < len(tokens)-2; {
@@ -287,7 +313,9 @@ func lex(text string) ([]Token, error) {
 			continue
 		}
 		if tokens[i].Type == Comma {
-			return nil, msg(line, column, "Unexpected comma.")
+			errs = errs.add(msg(tokens[i].LineNumber, tokens[i].Column, "P0009", "Unexpected comma."))
+			i++
+			continue
 		}
 		filteredTokens = append(filteredTokens, tokens[i])
 		i++
@@ -295,17 +323,40 @@ func lex(text string) ([]Token, error) {
 	if len(tokens) > 2 {
 		filteredTokens = append(filteredTokens, tokens[len(tokens)-2:]...)
 	}
-	return filteredTokens, nil
+	return filteredTokens, errs.err()
+}
+
+// returns the index of the newline which ends the line containing runes[i]
+func endOfLine(runes []rune, i int) int {
+	for i < len(runes) && runes[i] != '\n' && runes[i] != '\r' {
+		i++
+	}
+	return i
 }
 
 // parse the top-level definitions
 func parse(tokens []Token, pkg *Package) ([]Definition, error) {
 	var definitions []Definition
+	var errs Diagnostics // after an error, parsing resumes at the next top-level definition
 	for i := 0; i < len(tokens); {
 		t := tokens[i]
 		line := t.LineNumber
 		switch t.Type {
 		case ReservedWord:
+			if t.Content == "const" {
+				// (an enumeration defines several constants)
+				consts, numTokens, err := parseConst(tokens[i:], line, pkg)
+				if err != nil {
+					errs = errs.add(err)
+					i = nextDefinition(tokens, i)
+					continue
+				}
+				for _, c := range consts {
+					definitions = append(definitions, c)
+				}
+				i += numTokens
+				continue
+			}
 			var definition Definition
 			var numTokens int
 			var err error
@@ -320,11 +371,24 @@ func parse(tokens []Token, pkg *Package) ([]Definition, error) {
 				definition, numTokens, err = parseFunction(tokens[i:], line, pkg)
 			case "global":
 				definition, numTokens, err = parseGlobal(tokens[i:], line, pkg)
+			case "import":
+				definition, numTokens, err = parseImport(tokens[i:], line, pkg)
+			case "nativeimport":
+				definition, numTokens, err = parseNativeImport(tokens[i:], line, pkg)
+			case "nativefunc":
+				definition, numTokens, err = parseNativeFunction(tokens[i:], line, pkg)
+			case "nativestruct":
+				definition, numTokens, err = parseNativeStruct(tokens[i:], line, pkg)
 			default:
-				return nil, msg(t.LineNumber, t.Column, "Improper reserved word at top level of code.")
+				err = msg(t.LineNumber, t.Column, "P0104", "Improper reserved word at top level of code.")
 			}
 			if err != nil {
-				return nil, err
+				errs = errs.add(err)
+				if numTokens == 0 {
+					i = nextDefinition(tokens, i)
+					continue
+				}
+				// a function or method whose body failed to parse is kept for its signature
 			}
 			definitions = append(definitions, definition)
 			i += numTokens
@@ -336,25 +400,42 @@ func parse(tokens []Token, pkg *Package) ([]Definition, error) {
 			// (don't need to check if (i + 1) in bounds because we know token stream always
 			// ends with newline and so this indentation token can't be last)
 			if tokens[i+1].Type != Newline {
-				return nil, msg(t.LineNumber, t.Column, "Improper indentation at top level of code.")
+				errs = errs.add(msg(t.LineNumber, t.Column, "P0103", "Improper indentation at top level of code."))
 			}
+			i = nextDefinition(tokens, i)
 		default:
-			return nil, msg(t.LineNumber, t.Column, "Improper token at top level of code.")
+			errs = errs.add(msg(t.LineNumber, t.Column, "P0104", "Improper token at top level of code."))
+			i = nextDefinition(tokens, i)
+		}
+	}
+	return definitions, errs.err()
+}
+
+// returns the index of the first token of the next top-level definition after tokens[i]
+// (or len(tokens) if there are no more definitions)
+func nextDefinition(tokens []Token, i int) int {
+	for i++; i < len(tokens); i++ {
+		if tokens[i-1].Type == Newline && tokens[i].Type == ReservedWord {
+			for _, word := range definitionWords {
+				if tokens[i].Content == word {
+					return i
+				}
+			}
 		}
 	}
-	return definitions, nil
+	return i
 }
 
 func parseImport(tokens []Token, line int, pkg *Package) (ImportDefinition, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return ImportDefinition{}, 0, msg(line, column, "Expected space.")
+		return ImportDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	pathToken := tokens[idx]
 	if pathToken.Type != StringLiteral {
-		return ImportDefinition{}, 0, msg(line, column, "Expected string literal.")
+		return ImportDefinition{}, 0, msg(pathToken.LineNumber, pathToken.Column, "P0112", "Expected string literal.")
 	}
 	path := strings.Trim(pathToken.Content, "\"")
 	idx++
@@ -362,29 +443,40 @@ func parseImport(tokens []Token, line int, pkg *Package) (ImportDefinition, int,
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return ImportDefinition{}, 0, msg(line, column, "Expected newline.")
+		return ImportDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expected newline.")
 	}
 	idx++
 
 	importedNames := []string{}
 	importedAliases := []string{}
+	positions := []Position{}
 	for {
 		if tokens[idx].Type != Indentation {
 			break
 		}
 		idx++
 		t := tokens[idx]
-		if t.Type != IdentifierWord {
-			return ImportDefinition{}, 0, msg(line, column, "Expected name to import.")
+		if t.Type != IdentifierWord && t.Type != TypeName {
+			return ImportDefinition{}, 0, msg(t.LineNumber, t.Column, "P0105", "Expected name to import.")
 		}
 		idx++
 		importedNames = append(importedNames, t.Content)
+		positions = append(positions, Position{t.LineNumber, t.Column})
 		if tokens[idx].Type == Space {
 			idx++
 		}
-		t = tokens[idx]
-		if t.Type == IdentifierWord {
-			importedAliases = append(importedAliases, t.Content)
+		alias := tokens[idx]
+		if alias.Type == IdentifierWord || alias.Type == TypeName {
+			// a type is aliased by a type name, and anything else by a name which isn't a type name
+			if alias.Type == TypeName && t.Type != TypeName {
+				return ImportDefinition{}, 0, msg(alias.LineNumber, alias.Column, "P0105", "Alias of "+t.Content+
+					" should begin with a lowercase letter.")
+			}
+			if alias.Type != TypeName && t.Type == TypeName {
+				return ImportDefinition{}, 0, msg(alias.LineNumber, alias.Column, "P0105", "Alias of "+t.Content+
+					" should begin with an uppercase letter.")
+			}
+			importedAliases = append(importedAliases, alias.Content)
 			idx++
 		} else {
 			importedAliases = append(importedAliases, "")
@@ -393,26 +485,26 @@ func parseImport(tokens []Token, line int, pkg *Package) (ImportDefinition, int,
 			idx++
 		}
 		if tokens[idx].Type != Newline {
-			return ImportDefinition{}, 0, msg(line, column, "Expected newline.")
+			return ImportDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expected newline.")
 		}
 		idx++
 	}
 	if len(importedNames) == 0 {
-		return ImportDefinition{}, 0, msg(line, column, "Import statement has no imported names.")
+		return ImportDefinition{}, 0, msg(line, column, "P0110", "Import statement has no imported names.")
 	}
-	return ImportDefinition{line, column, path, importedNames, importedAliases, pkg}, idx, nil
+	return ImportDefinition{line, column, path, importedNames, importedAliases, positions, pkg}, idx, nil
 }
 
 func parseNativeImport(tokens []Token, line int, pkg *Package) (NativeImportDefinition, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return NativeImportDefinition{}, 0, msg(line, column, "Expected space.")
+		return NativeImportDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	pathToken := tokens[idx]
 	if pathToken.Type != StringLiteral {
-		return NativeImportDefinition{}, 0, msg(line, column, "Expected string literal.")
+		return NativeImportDefinition{}, 0, msg(pathToken.LineNumber, pathToken.Column, "P0112", "Expected string literal.")
 	}
 	path := strings.Trim(pathToken.Content, "\"")
 	idx++
@@ -422,45 +514,50 @@ func parseNativeImport(tokens []Token, line int, pkg *Package) (NativeImportDefi
 		idx += 2
 	}
 	if tokens[idx].Type != Newline {
-		return NativeImportDefinition{}, 0, msg(line, column, "Expected newline.")
+		return NativeImportDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expected newline.")
 	}
 	idx++
 	return NativeImportDefinition{line, column, path, alias, pkg}, idx, nil
 }
 
 func parseNativeStruct(tokens []Token, line int, pkg *Package) (StructDefinition, int, error) {
-	column := tokens[0].Column
 	st, idx, err := parseStruct(tokens, line, pkg)
 	if err != nil {
 		return StructDefinition{}, 0, err
 	}
 	if tokens[idx].Type != MultilineStringLiteral {
-		return StructDefinition{}, 0, msg(line, column, "Expected multiline string.")
+		return StructDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0112", "Expected multiline string.")
 	}
-	native := tokens[idx].Content
-	st.NativeCode = native[3 : len(native)-3]
+	st.NativeCode, st.NativeLine = nativeCode(tokens[idx])
 	idx++
 	if tokens[idx].Type != Newline {
-		return StructDefinition{}, 0, msg(line, column, "Expecting newline at end of nativestruct.")
+		return StructDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expecting newline at end of nativestruct.")
 	}
+	idx++
 	return st, idx, nil
 }
 
+// returns the Go code of a multiline string (the code between the triple quotes) and the line on which it starts
+func nativeCode(t Token) (string, int) {
+	return t.Content[3 : len(t.Content)-3], t.LineNumber
+}
+
 func parseStruct(tokens []Token, line int, pkg *Package) (StructDefinition, int, error) {
+	structLine := line
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return StructDefinition{}, 0, msg(line, column, "Expected space.")
+		return StructDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	name := tokens[idx]
 	if name.Type != TypeName {
-		return StructDefinition{}, 0, msg(line, column, "Expected name for struct.")
+		return StructDefinition{}, 0, msg(name.LineNumber, name.Column, "P0105", "Expected name for struct.")
 	}
 	idx++
 	for _, v := range builtinTypes {
 		if name.Content == v {
-			return StructDefinition{}, 0, msg(line, column, "Invalid struct name: cannot redefine builtin type "+
+			return StructDefinition{}, 0, msg(name.LineNumber, name.Column, "P0202", "Invalid struct name: cannot redefine builtin type "+
 				name.Content+".")
 		}
 	}
@@ -468,7 +565,7 @@ func parseStruct(tokens []Token, line int, pkg *Package) (StructDefinition, int,
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return StructDefinition{}, 0, msg(line, tokens[idx].Column, "Expected newline.")
+		return StructDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expected newline.")
 	}
 	idx++
 
@@ -481,11 +578,11 @@ func parseStruct(tokens []Token, line int, pkg *Package) (StructDefinition, int,
 		idx++
 		memberName := tokens[idx]
 		if memberName.Type != IdentifierWord {
-			return StructDefinition{}, 0, msg(line, column, "Expected struct member name.")
+			return StructDefinition{}, 0, msg(memberName.LineNumber, memberName.Column, "P0105", "Expected struct member name.")
 		}
 		idx++
 		if tokens[idx].Type != Space {
-			return StructDefinition{}, 0, msg(line, column, "Expected space.")
+			return StructDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 		}
 		idx++
 		memberType, numTypeTokens, err := parseType(tokens[idx:], line)
@@ -498,21 +595,25 @@ func parseStruct(tokens []Token, line int, pkg *Package) (StructDefinition, int,
 			idx++
 		}
 		if tokens[idx].Type != Newline {
-			return StructDefinition{}, 0, msg(line, column, "Expected newline.")
+			return StructDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expected newline.")
 		}
 		idx++
 	}
-	return StructDefinition{line, column, name.Content, members, "", pkg}, idx, nil
+	return StructDefinition{structLine, column, name.Content, members, "", 0, pkg}, idx, nil
 }
 
 func parseMethod(tokens []Token, line int, pkg *Package) (MethodDefinition, int, error) {
 	column := tokens[0].Column
 	funcDef, numTokens, err := parseFunction(tokens, line, pkg)
-	if err != nil {
+	if err != nil && numTokens == 0 {
 		return MethodDefinition{}, 0, err
 	}
 	if len(funcDef.Parameters) == 0 {
-		return MethodDefinition{}, 0, msg(line, column, "Method must have a receiver parameter.")
+		errs := Diagnostics{}.add(msg(line, column, "P0111", "Method must have a receiver parameter."))
+		if err != nil {
+			errs = errs.add(err)
+		}
+		return MethodDefinition{}, 0, errs.err()
 	}
 	return MethodDefinition{
 		funcDef.LineNumber,
@@ -523,13 +624,13 @@ func parseMethod(tokens []Token, line int, pkg *Package) (MethodDefinition, int,
 		funcDef.ReturnTypes,
 		funcDef.Body,
 		pkg,
-	}, numTokens, nil
+		funcDef.BodyInvalid,
+	}, numTokens, err
 }
 
 // used by parseFunction
 // consumes all tokens through end of line
 func parseParameters(tokens []Token, line int) ([]Variable, []ParsedDataType, int, error) {
-	column := tokens[0].Column
 	params := []Variable{}
 	idx := 0
 	expectingSpace := false
@@ -543,10 +644,10 @@ Loop:
 		case IdentifierWord:
 			idx++
 			if tokens[idx].Type != Space {
-				return nil, nil, 0, msg(line, column, "Expecting space.")
+				return nil, nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 			}
 			idx++
-			dataType, n, err := parseType(tokens[idx:], line)
+			dataType, n, err := parseParamType(tokens[idx:], line)
 			if err != nil {
 				return nil, nil, 0, err
 			}
@@ -555,7 +656,7 @@ Loop:
 			params = append(params, Variable{line, t.Column, t.Content, dataType})
 		case Colon:
 			if expectingSpace {
-				return nil, nil, 0, msg(line, t.Column, "Expecting space.")
+				return nil, nil, 0, msg(t.LineNumber, t.Column, "P0101", "Expecting space.")
 			}
 			// don't inc idx
 			break Loop
@@ -563,7 +664,13 @@ Loop:
 			// don't inc idx
 			break Loop
 		default:
-			return nil, nil, 0, msg(t.LineNumber, t.Column, "Unexpected token.")
+			return nil, nil, 0, msg(t.LineNumber, t.Column, "P0104", "Unexpected token.")
+		}
+	}
+
+	for i, p := range params {
+		if p.Type.Variadic && i < len(params)-1 {
+			return nil, nil, 0, variadicMsg(p.Type)
 		}
 	}
 
@@ -572,7 +679,7 @@ Loop:
 	if tokens[idx].Type == Colon {
 		idx++
 		if tokens[idx].Type != Space {
-			return nil, nil, 0, msg(line, tokens[idx].Column, "Expecting space.")
+			return nil, nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 		}
 		idx++
 		t, n, err := parseType(tokens[idx:], line)
@@ -586,7 +693,7 @@ Loop:
 				break
 			}
 			if tokens[idx].Type != Space {
-				return nil, nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "Expecting space.")
+				return nil, nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 			}
 			idx++
 			t, n, err := parseType(tokens[idx:], line)
@@ -602,7 +709,7 @@ Loop:
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return nil, nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "Expecting newline.")
+		return nil, nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expecting newline.")
 	}
 	idx++
 	return params, returnTypes, idx, nil
@@ -617,7 +724,7 @@ func parseSignature(tokens []Token, line int) (Signature, int, error) {
 	idx := 0
 	methodName := tokens[idx]
 	if methodName.Type != IdentifierWord {
-		return Signature{}, 0, msg(line, column, "Expecting method name.")
+		return Signature{}, 0, msg(methodName.LineNumber, methodName.Column, "P0105", "Expecting method name.")
 	}
 	idx++
 	if tokens[idx].Type == Space && tokens[idx+1].Type != Newline {
@@ -629,8 +736,8 @@ func parseSignature(tokens []Token, line int) (Signature, int, error) {
 			case Space:
 				expectingSpace = false
 				idx++
-			case TypeName:
-				dataType, n, err := parseType(tokens[idx:], line)
+			case TypeName, Dot:
+				dataType, n, err := parseParamType(tokens[idx:], line)
 				if err != nil {
 					return Signature{}, 0, err
 				}
@@ -639,21 +746,24 @@ func parseSignature(tokens []Token, line int) (Signature, int, error) {
 				expectingSpace = true
 			case Colon:
 				if expectingSpace {
-					return Signature{}, 0, msg(line, column, "Expecting space.")
+					return Signature{}, 0, msg(t.LineNumber, t.Column, "P0101", "Expecting space.")
 				}
 				// don't inc idx
 				break Loop
 			case Newline:
 				break Loop
 			default:
-				return Signature{}, 0, msg(line, column, "Unexpected token.")
+				return Signature{}, 0, msg(t.LineNumber, t.Column, "P0104", "Unexpected token.")
 			}
 		}
+		if err := checkVariadic(paramTypes); err != nil {
+			return Signature{}, 0, err
+		}
 		// optional colon and return types
 		if tokens[idx].Type == Colon {
 			idx++
 			if tokens[idx].Type != Space {
-				return Signature{}, 0, msg(line, column, "Expecting space.")
+				return Signature{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 			}
 			idx++
 			t, n, err := parseType(tokens[idx:], line)
@@ -667,7 +777,7 @@ func parseSignature(tokens []Token, line int) (Signature, int, error) {
 					break
 				}
 				if tokens[idx].Type != Space {
-					return Signature{}, 0, msg(line, column, "Expecting space.")
+					return Signature{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 				}
 				idx++
 				t, n, err := parseType(tokens[idx:], line)
@@ -684,7 +794,7 @@ func parseSignature(tokens []Token, line int) (Signature, int, error) {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return Signature{}, 0, msg(line, column, "Expecting newline.")
+		return Signature{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expecting newline.")
 	}
 	idx++
 	return Signature{line, column, methodName.Content, paramTypes, returnTypes}, idx, nil
@@ -694,19 +804,19 @@ func parseInterface(tokens []Token, line int, pkg *Package) (InterfaceDefinition
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return InterfaceDefinition{}, 0, msg(line, column, "Expected space.")
+		return InterfaceDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	name := tokens[idx]
 	if name.Type != TypeName {
-		return InterfaceDefinition{}, 0, msg(line, column, "Expected name for interface.")
+		return InterfaceDefinition{}, 0, msg(name.LineNumber, name.Column, "P0105", "Expected name for interface.")
 	}
 	idx++
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return InterfaceDefinition{}, 0, msg(line, column, "Expected newline.")
+		return InterfaceDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expected newline.")
 	}
 	idx++
 
@@ -724,24 +834,25 @@ func parseInterface(tokens []Token, line int, pkg *Package) (InterfaceDefinition
 		idx += numTokens
 	}
 	if len(methods) == 0 {
-		return InterfaceDefinition{}, 0, msg(line, column, "Interface definition has no method signatures.")
+		return InterfaceDefinition{}, 0, msg(line, column, "P0110", "Interface definition has no method signatures.")
 	}
 	return InterfaceDefinition{line, column, name.Content, methods, pkg}, idx, nil
 }
 
 // consumes any number of types separated by spaces (returns upon any other kind of token)
 // expects type first before any space
-func parseTypeList(tokens []Token, line int) ([]ParsedDataType, int, error) {
+// (if params, the types are the parameter types of a function, the last of which may be variadic: ...T)
+func parseTypeList(tokens []Token, line int, params bool) ([]ParsedDataType, int, error) {
 	idx := 0
 	types := make([]ParsedDataType, 0)
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	for {
-		if tokens[idx].Type != TypeName {
+		if tokens[idx].Type != TypeName && !(params && isEllipsis(tokens[idx:])) {
 			break
 		}
-		dt, n, err := parseType(tokens[idx:], tokens[idx].LineNumber)
+		dt, n, err := parseParamType(tokens[idx:], tokens[idx].LineNumber)
 		if err != nil {
 			return []ParsedDataType{}, 0, err
 		}
@@ -752,15 +863,56 @@ func parseTypeList(tokens []Token, line int) ([]ParsedDataType, int, error) {
 		}
 		idx++
 	}
+	if params {
+		if err := checkVariadic(types); err != nil {
+			return []ParsedDataType{}, 0, err
+		}
+	}
 	return types, idx, nil
 }
 
+// reports whether the tokens start with an ellipsis (...), which is lexed as three dots
+func isEllipsis(tokens []Token) bool {
+	return len(tokens) >= 3 && tokens[0].Type == Dot && tokens[1].Type == Dot && tokens[2].Type == Dot
+}
+
+// parses the type of a parameter. The last parameter of a variadic function is written ...T,
+// and its type is S<T>.
+func parseParamType(tokens []Token, line int) (ParsedDataType, int, error) {
+	if !isEllipsis(tokens) {
+		return parseType(tokens, line)
+	}
+	dt, n, err := parseType(tokens[3:], line)
+	if err != nil {
+		return ParsedDataType{}, 0, err
+	}
+	return ParsedDataType{line, tokens[0].Column, "S", []ParsedDataType{dt}, nil, true}, n + 3, nil
+}
+
+// returns an error if any parameter type but the last is variadic
+func checkVariadic(params []ParsedDataType) error {
+	for i, p := range params {
+		if p.Variadic && i < len(params)-1 {
+			return variadicMsg(p)
+		}
+	}
+	return nil
+}
+
+func variadicMsg(p ParsedDataType) error {
+	return msg(p.LineNumber, p.Column, "P0106", "Only the last parameter of a function can be variadic (...T).")
+}
+
 func parseType(tokens []Token, line int) (ParsedDataType, int, error) {
 	column := tokens[0].Column
 	idx := 0
 	baseType := tokens[idx].Content
 	if tokens[idx].Type != TypeName {
-		return ParsedDataType{}, 0, msg(line, column, "Expecting type name.")
+		suggestion := ""
+		if tokens[idx].Type == IdentifierWord {
+			suggestion = didYouMean(baseType, builtinTypes)
+		}
+		return ParsedDataType{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0106", "Expecting type name."+suggestion)
 	}
 	idx++
 	paramTypes := []ParsedDataType{}
@@ -769,7 +921,7 @@ func parseType(tokens []Token, line int) (ParsedDataType, int, error) {
 		idx++
 		var n int
 		var err error
-		paramTypes, n, err = parseTypeList(tokens[idx:], tokens[idx].LineNumber)
+		paramTypes, n, err = parseTypeList(tokens[idx:], tokens[idx].LineNumber, baseType == "Fn")
 		if err != nil {
 			return ParsedDataType{}, 0, err
 		}
@@ -777,33 +929,32 @@ func parseType(tokens []Token, line int) (ParsedDataType, int, error) {
 		if baseType == "A" {
 			if tokens[idx].Type == NumberLiteral {
 				// special case for arrays (we expect a number literal, not just a number expression)
-				paramTypes = append(paramTypes, ParsedDataType{line, tokens[idx].Column, tokens[idx].Content, nil, nil})
+				paramTypes = append(paramTypes, ParsedDataType{line, tokens[idx].Column, tokens[idx].Content, nil, nil, false})
 				idx++
 			} else {
-				return ParsedDataType{}, 0, msg(line, column, "Expecting number for array size.")
+				return ParsedDataType{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0106", "Expecting number for array size.")
 			}
 		} else if tokens[idx].Type == Colon {
 			idx++
-			returnTypes, n, err = parseTypeList(tokens[idx:], tokens[idx].LineNumber)
+			returnTypes, n, err = parseTypeList(tokens[idx:], tokens[idx].LineNumber, false)
 			if err != nil {
 				return ParsedDataType{}, 0, err
 			}
 			idx += n
 		}
 		if tokens[idx].Type != CloseAngle {
-			return ParsedDataType{}, 0, msg(line, column, "Expecting closing angle bracket.")
+			return ParsedDataType{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0106", "Expecting closing angle bracket.")
 		}
 		idx++
 	}
-	return ParsedDataType{line, tokens[idx].Column, baseType, paramTypes, returnTypes}, idx, nil
+	return ParsedDataType{line, column, baseType, paramTypes, returnTypes, false}, idx, nil
 }
 
 // expects to end with newline or >, but does not consume the newline or >
 func parseReturnTypes(tokens []Token, line int) ([]ParsedDataType, int, error) {
-	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return nil, 0, msg(line, column, "Expecting space.")
+		return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 	}
 	returnTypes := []ParsedDataType{}
 	dataType, n, err := parseType(tokens[idx:], line)
@@ -821,7 +972,7 @@ func parseReturnTypes(tokens []Token, line int) ([]ParsedDataType, int, error) {
 			break
 		}
 		if tokens[idx].Type != Space {
-			return nil, 0, msg(line, column, "Expecting space.")
+			return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 		}
 		idx++
 		dataType, n, err := parseType(tokens[idx:], line)
@@ -838,16 +989,16 @@ func parseGlobal(tokens []Token, line int, pkg *Package) (GlobalDefinition, int,
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return GlobalDefinition{}, 0, msg(line, column, "Expected space.")
+		return GlobalDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	target := tokens[idx]
 	if target.Type != IdentifierWord {
-		return GlobalDefinition{}, 0, msg(line, column, "Improper name for a global.")
+		return GlobalDefinition{}, 0, msg(target.LineNumber, target.Column, "P0105", "Improper name for a global.")
 	}
 	idx++
 	if tokens[idx].Type != Space {
-		return GlobalDefinition{}, 0, msg(line, column, "Expected space.")
+		return GlobalDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	globalType, numTypeTokens, err := parseType(tokens[idx:], line)
@@ -856,7 +1007,7 @@ func parseGlobal(tokens []Token, line int, pkg *Package) (GlobalDefinition, int,
 	}
 	idx += numTypeTokens
 	if tokens[idx].Type != Space {
-		return GlobalDefinition{}, 0, msg(line, column, "Expected space.")
+		return GlobalDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	value, numValueTokens, err := parseExpression(tokens[idx:], line)
@@ -865,16 +1016,101 @@ func parseGlobal(tokens []Token, line int, pkg *Package) (GlobalDefinition, int,
 	}
 	idx += numValueTokens
 	if tokens[idx].Type != Newline {
-		return GlobalDefinition{}, 0, msg(line, column, "Global not terminated with newline.")
+		return GlobalDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Global not terminated with newline.")
+	}
+	idx++
+	return GlobalDefinition{line, column, target.Content, value, globalType, pkg, false, nil, false}, idx, nil
+}
+
+// parses a constant, with or without a type:
+//
+//	const max I 100
+//	const greeting "hi"
+//
+// or an enumeration, whose constants (one name per line) are the successive integers from 0:
+//
+//	const
+//	    empty
+//	    cross
+//	    nought
+func parseConst(tokens []Token, line int, pkg *Package) ([]GlobalDefinition, int, error) {
+	column := tokens[0].Column
+	idx := 1
+	if tokens[idx].Type == Space && tokens[idx+1].Type == Newline {
+		idx++
+	}
+	if tokens[idx].Type == Newline {
+		idx++
+		var consts []GlobalDefinition
+		for idx+1 < len(tokens) && tokens[idx].Type == Indentation && tokens[idx+1].Type != Newline {
+			if len(tokens[idx].Content) != indentationSpaces {
+				return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0103", "Improper indentation in const enumeration.")
+			}
+			idx++
+			name := tokens[idx]
+			if name.Type != IdentifierWord {
+				return nil, 0, msg(name.LineNumber, name.Column, "P0105", "Improper name for a constant.")
+			}
+			idx++
+			if tokens[idx].Type == Space {
+				idx++
+			}
+			if tokens[idx].Type != Newline {
+				return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expected newline after name of constant.")
+			}
+			idx++
+			value := Token{NumberLiteral, strconv.Itoa(len(consts)), name.LineNumber, name.Column}
+			consts = append(consts, GlobalDefinition{name.LineNumber, name.Column, name.Content, value,
+				ParsedDataType{name.LineNumber, name.Column, "I", nil, nil, false}, pkg, true, nil, false})
+		}
+		if len(consts) == 0 {
+			return nil, 0, msg(line, column, "P0110", "Const enumeration should name at least one constant.")
+		}
+		return consts, idx, nil
+	}
+	if tokens[idx].Type != Space {
+		return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
+	}
+	idx++
+	target := tokens[idx]
+	if target.Type != IdentifierWord {
+		return nil, 0, msg(target.LineNumber, target.Column, "P0105", "Improper name for a constant.")
+	}
+	idx++
+	if tokens[idx].Type != Space {
+		return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
+	}
+	idx++
+	var constType ParsedDataType
+	if tokens[idx].Type == TypeName {
+		var nTokens int
+		var err error
+		constType, nTokens, err = parseType(tokens[idx:], line)
+		if err != nil {
+			return nil, 0, err
+		}
+		idx += nTokens
+		if tokens[idx].Type != Space {
+			return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
+		}
+		idx++
+	}
+	value, numValueTokens, err := parseExpression(tokens[idx:], line)
+	if err != nil {
+		return nil, 0, err
+	}
+	idx += numValueTokens
+	if tokens[idx].Type != Newline {
+		return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Const not terminated with newline.")
 	}
 	idx++
-	return GlobalDefinition{line, column, target.Content, value, globalType, pkg}, idx, nil
+	return []GlobalDefinition{{line, column, target.Content, value, constType, pkg, true, nil, false}}, idx, nil
 }
 
 func parseExpression(tokens []Token, line int) (Expression, int, error) {
 	column := tokens[0].Column
 	if len(tokens) < 1 {
-		return nil, 0, msg(line, column, "Missing expression.")
+		return nil, 0, msg(line, column, "P0104", "Missing expression.")
 	}
 	idx := 0
 	token := tokens[idx]
@@ -900,7 +1136,7 @@ func parseExpression(tokens []Token, line int) (Expression, int, error) {
 			return nil, 0, err
 		}
 	default:
-		return nil, 0, msg(line, column, "Improper expression: "+fmt.Sprintf("%#v", token))
+		return nil, 0, msg(token.LineNumber, token.Column, "P0104", "Improper expression: "+token.Content)
 	}
 	return expr, idx, nil
 }
@@ -910,7 +1146,7 @@ func parseMakeOp(tokens []Token) (Operation, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return Operation{}, 0, msg(line, column, "Expecting space.")
+		return Operation{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 	}
 	idx++
 	dt, numTokens, err := parseType(tokens[idx:], line)
@@ -919,7 +1155,7 @@ func parseMakeOp(tokens []Token) (Operation, int, error) {
 	}
 	idx += numTokens
 	if tokens[idx].Type != Space {
-		return Operation{}, 0, msg(line, column, "Expecting space.")
+		return Operation{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 	}
 	idx++
 	expr, numTokens, err := parseExpression(tokens[idx:], line)
@@ -931,7 +1167,7 @@ func parseMakeOp(tokens []Token) (Operation, int, error) {
 		idx++
 	}
 	if tokens[idx].Type != CloseParen {
-		return Operation{}, 0, msg(line, column, "Expecting close paren.")
+		return Operation{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0104", "Expecting close paren.")
 	}
 	idx++
 	return Operation{line, column, "make", dt, []Expression{expr}}, idx, nil
@@ -985,10 +1221,11 @@ func parseOpenParen(tokens []Token) (Expression, int, error) {
 		}
 		idx += n
 	default:
-		return nil, 0, msg(line, column, "Improper function call or operation.")
+		return nil, 0, msg(t.LineNumber, t.Column, "P0104", "Improper function call or operation.")
 	}
 
 	var arguments []Expression
+	var spread Token // the ellipsis before the last argument (...x), if any
 Loop:
 	for true {
 		t := tokens[idx]
@@ -999,7 +1236,14 @@ Loop:
 			idx++
 			break Loop
 		default:
-			return nil, 0, msg(line, column, "Expecting space or end paren.")
+			return nil, 0, msg(t.LineNumber, t.Column, "P0104", "Expecting space or end paren.")
+		}
+		if spread.Type == Dot {
+			return nil, 0, msg(spread.LineNumber, spread.Column, "P0104", "Only the last argument of a call can be spread (...x).")
+		}
+		if isEllipsis(tokens[idx:]) {
+			spread = tokens[idx]
+			idx += 3
 		}
 		expr, numTokens, err := parseExpression(tokens[idx:], line)
 		if err != nil {
@@ -1008,24 +1252,28 @@ Loop:
 		arguments = append(arguments, expr)
 		idx += numTokens
 	}
+	isCall := functionCall && !typeExpression || op.Content == "mc" && len(arguments) > 2
+	if spread.Type == Dot && !isCall {
+		return nil, 0, msg(spread.LineNumber, spread.Column, "P0104", "Only the arguments of a function or method call can be spread (...x).")
+	}
 
 	var expr Expression
 	if op.Content == "mc" {
 		if len(arguments) < 2 {
-			return nil, 0, msg(line, column, "Method call must have a method name and a receiver.")
+			return nil, 0, msg(line, column, "P0301", "Method call must have a method name and a receiver.")
 		}
 		if name, ok := arguments[0].(Token); ok {
-			expr = MethodCall{line, column, name.Content, arguments[1], arguments[2:]}
+			expr = MethodCall{line, column, name.Content, name.LineNumber, name.Column, arguments[1], arguments[2:], spread.Type == Dot}
 		} else {
-			return nil, 0, msg(line, column, "First argument to 'mc' must be the method name.")
+			return nil, 0, msg(line, column, "P0105", "First argument to 'mc' must be the method name.")
 		}
 	} else if typeExpression {
 		expr = TypeExpression{line, column, dt, arguments}
 	} else if functionCall {
 		if leadingCall == nil {
-			expr = FunctionCall{line, column, op, arguments}
+			expr = FunctionCall{line, column, op, arguments, spread.Type == Dot}
 		} else {
-			expr = FunctionCall{line, column, leadingCall, arguments}
+			expr = FunctionCall{line, column, leadingCall, arguments, spread.Type == Dot}
 		}
 	} else {
 		expr = Operation{line, column, op.Content, ParsedDataType{}, arguments}
@@ -1040,16 +1288,82 @@ func isReserved(t Token) bool {
 
 func parseFunction(tokens []Token, line int, pkg *Package) (FunctionDefinition, int, error) {
 	column := tokens[0].Column
+	name, params, returnTypes, idx, err := parseFunctionSignature(tokens, line)
+	if err != nil {
+		return FunctionDefinition{}, 0, err
+	}
+	// if the body fails to parse, the definition is returned with the errors
+	body, nTokens, err := parseBody(tokens[idx:], indentationSpaces)
+	idx += nTokens
+	return FunctionDefinition{
+		line, column,
+		name,
+		params, returnTypes,
+		body,
+		"", 0,
+		pkg,
+		err != nil,
+	}, idx, err
+}
+
+// a native function has a signature like any other function but a body of Go code in a multiline string
+func parseNativeFunction(tokens []Token, line int, pkg *Package) (FunctionDefinition, int, error) {
+	column := tokens[0].Column
+	name, params, returnTypes, idx, err := parseFunctionSignature(tokens, line)
+	if err != nil {
+		return FunctionDefinition{}, 0, err
+	}
+	if tokens[idx].Type != MultilineStringLiteral {
+		return FunctionDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0112", "Expected multiline string.")
+	}
+	native, nativeLine := nativeCode(tokens[idx])
+	idx++
+	if tokens[idx].Type != Newline {
+		return FunctionDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expecting newline at end of nativefunc.")
+	}
+	idx++
+	return FunctionDefinition{
+		line, column,
+		name,
+		params, returnTypes,
+		nil,
+		native, nativeLine,
+		pkg,
+		false,
+	}, idx, nil
+}
+
+// a local function has a signature like any other function, and its body is indented one level further than the localfunc
+func parseLocalFunc(tokens []Token, indentation int) (LocalFuncStatement, int, error) {
+	line := tokens[0].LineNumber
+	column := tokens[0].Column
+	name, params, returnTypes, idx, err := parseFunctionSignature(tokens, line)
+	if err != nil {
+		return LocalFuncStatement{}, 0, err
+	}
+	body, nTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
+	if err != nil {
+		return LocalFuncStatement{}, 0, err
+	}
+	idx += nTokens
+	if len(body) == 0 {
+		return LocalFuncStatement{}, 0, msg(line, column, "P0110", "Local function should contain at least one statement.")
+	}
+	return LocalFuncStatement{line, column, name, params, returnTypes, body}, idx, nil
+}
+
+// parses the name, parameters, and return types of a function up to the end of its first line
+func parseFunctionSignature(tokens []Token, line int) (string, []Variable, []ParsedDataType, int, error) {
 	idx := 1
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	name := tokens[idx]
 	if isReserved(name) {
-		return FunctionDefinition{}, 0, msg(line, column, "Function name cannot be a reserved word and cannot be uppercase.")
+		return "", nil, nil, 0, msg(name.LineNumber, name.Column, "P0105", "Function name cannot be a reserved word and cannot be uppercase.")
 	}
 	if name.Type != IdentifierWord {
-		return FunctionDefinition{}, 0, msg(line, column, "Function missing name.")
+		return "", nil, nil, 0, msg(name.LineNumber, name.Column, "P0105", "Function missing name.")
 	}
 	if name.Content == "main" {
 		name.Content = "_main"
@@ -1057,36 +1371,24 @@ func parseFunction(tokens []Token, line int, pkg *Package) (FunctionDefinition,
 	idx++
 	var params []Variable
 	var returnTypes []ParsedDataType
-	var err error
 	if tokens[idx].Type == Newline {
 		idx++
 	} else if tokens[idx].Type == Space && tokens[idx+1].Type == Newline {
 		idx += 2
 	} else {
 		if tokens[idx].Type != Space {
-			return FunctionDefinition{}, 0, msg(line, column, "Expecting space.")
+			return "", nil, nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 		}
 		idx++
 		var nTokens int
+		var err error
 		params, returnTypes, nTokens, err = parseParameters(tokens[idx:], line)
 		if err != nil {
-			return FunctionDefinition{}, 0, err
+			return "", nil, nil, 0, err
 		}
 		idx += nTokens
 	}
-	body, nTokens, err := parseBody(tokens[idx:], indentationSpaces)
-	if err != nil {
-		return FunctionDefinition{}, 0, err
-	}
-	idx += nTokens
-	return FunctionDefinition{
-		line, column,
-		name.Content,
-		params, returnTypes,
-		body,
-		"",
-		pkg,
-	}, idx, nil
+	return name.Content, params, returnTypes, idx, nil
 }
 
 func parseTypeswitch(tokens []Token, indentation int) (TypeswitchStatement, int, error) {
@@ -1094,7 +1396,7 @@ func parseTypeswitch(tokens []Token, indentation int) (TypeswitchStatement, int,
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return TypeswitchStatement{}, 0, msg(line, column, "Missing space.")
+		return TypeswitchStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	value, n, err := parseExpression(tokens[idx:], line)
@@ -1106,7 +1408,7 @@ func parseTypeswitch(tokens []Token, indentation int) (TypeswitchStatement, int,
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return TypeswitchStatement{}, 0, msg(line, column, "Typeswitch expected newline.")
+		return TypeswitchStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Typeswitch expected newline.")
 	}
 	idx++
 	var cases []TypeswitchCase
@@ -1149,16 +1451,16 @@ func parseTypeswitchCase(tokens []Token, indentation int) (TypeswitchCase, int,
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return TypeswitchCase{}, 0, msg(line, column, "Missing space.")
+		return TypeswitchCase{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return TypeswitchCase{}, 0, msg(line, column, "Expecting identifier.")
+		return TypeswitchCase{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting identifier.")
 	}
 	name := tokens[idx].Content
 	idx++
 	if tokens[idx].Type != Space {
-		return TypeswitchCase{}, 0, msg(line, column, "Missing space.")
+		return TypeswitchCase{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	dt, nTokens, err := parseType(tokens[idx:], line)
@@ -1170,7 +1472,7 @@ func parseTypeswitchCase(tokens []Token, indentation int) (TypeswitchCase, int,
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return TypeswitchCase{}, 0, msg(line, column, "typeswitch case type not followed by newline.")
+		return TypeswitchCase{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "typeswitch case type not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -1183,15 +1485,13 @@ func parseTypeswitchCase(tokens []Token, indentation int) (TypeswitchCase, int,
 }
 
 func parseDefaultCase(tokens []Token, indentation int) ([]Statement, string, int, error) {
-	line := tokens[0].LineNumber
-	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return nil, "", 0, msg(line, column, "Missing space.")
+		return nil, "", 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return nil, "", 0, msg(line, column, "Expecting identifier.")
+		return nil, "", 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting identifier.")
 	}
 	name := tokens[idx].Content
 	idx++
@@ -1199,7 +1499,7 @@ func parseDefaultCase(tokens []Token, indentation int) ([]Statement, string, int
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return nil, "", 0, msg(line, column, "Default case not followed by newline.")
+		return nil, "", 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Default case not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -1210,12 +1510,300 @@ func parseDefaultCase(tokens []Token, indentation int) ([]Statement, string, int
 	return body, name, idx, nil
 }
 
+// the cases of a switch are indented like the switch itself
+func parseSwitch(tokens []Token, indentation int) (SwitchStatement, int, error) {
+	line := tokens[0].LineNumber
+	column := tokens[0].Column
+	idx := 1
+	if tokens[idx].Type != Space {
+		return SwitchStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+	}
+	idx++
+	value, n, err := parseExpression(tokens[idx:], line)
+	if err != nil {
+		return SwitchStatement{}, 0, err
+	}
+	idx += n
+	if tokens[idx].Type == Space {
+		idx++
+	}
+	if tokens[idx].Type != Newline {
+		return SwitchStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Switch expected newline.")
+	}
+	idx++
+	var cases []SwitchCase
+	for idx+1 < len(tokens) {
+		if tokens[idx].Type == Indentation &&
+			len(tokens[idx].Content) == indentation &&
+			tokens[idx+1].Content == "case" {
+			idx++
+			c, nTokens, err := parseSwitchCase(tokens[idx:], indentation)
+			if err != nil {
+				return SwitchStatement{}, 0, err
+			}
+			cases = append(cases, c)
+			idx += nTokens
+		} else {
+			break
+		}
+	}
+	var defaultBody []Statement
+	if idx+1 < len(tokens) {
+		if tokens[idx].Type == Indentation &&
+			len(tokens[idx].Content) == indentation &&
+			tokens[idx+1].Content == "default" {
+			idx += 2
+			if tokens[idx].Type == Space {
+				idx++
+			}
+			if tokens[idx].Type != Newline {
+				return SwitchStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Default case not followed by newline.")
+			}
+			idx++
+			var nTokens int
+			defaultBody, nTokens, err = parseBody(tokens[idx:], indentation+indentationSpaces)
+			if err != nil {
+				return SwitchStatement{}, 0, err
+			}
+			idx += nTokens
+		}
+	}
+	return SwitchStatement{line, column, value, cases, defaultBody}, idx, nil
+}
+
+func parseSwitchCase(tokens []Token, indentation int) (SwitchCase, int, error) {
+	line := tokens[0].LineNumber
+	column := tokens[0].Column
+	idx := 1
+	var values []Expression
+	for {
+		if tokens[idx].Type == Space {
+			idx++
+		}
+		if tokens[idx].Type == Newline {
+			break
+		}
+		if tokens[idx-1].Type != Space {
+			return SwitchCase{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+		}
+		value, n, err := parseExpression(tokens[idx:], line)
+		if err != nil {
+			return SwitchCase{}, 0, err
+		}
+		values = append(values, value)
+		idx += n
+	}
+	if len(values) == 0 {
+		return SwitchCase{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0104", "Switch case has no values.")
+	}
+	idx++
+	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
+	if err != nil {
+		return SwitchCase{}, 0, err
+	}
+	idx += numTokens
+	return SwitchCase{line, column, values, body}, idx, nil
+}
+
+// the clauses of a select are indented like the select itself
+func parseSelect(tokens []Token, indentation int) (SelectStatement, int, error) {
+	line := tokens[0].LineNumber
+	column := tokens[0].Column
+	idx := 1
+	if tokens[idx].Type == Space {
+		idx++
+	}
+	if tokens[idx].Type != Newline {
+		return SelectStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Select expected newline.")
+	}
+	idx++
+	var clauses []SelectClause
+	for idx+1 < len(tokens) && tokens[idx].Type == Indentation && len(tokens[idx].Content) == indentation {
+		var clause SelectClause
+		var nTokens int
+		var err error
+		switch tokens[idx+1].Content {
+		case "rcving":
+			clause, nTokens, err = parseSelectRcv(tokens[idx+1:], indentation)
+		case "snding":
+			clause, nTokens, err = parseSelectSend(tokens[idx+1:], indentation)
+		}
+		if clause == nil && err == nil {
+			break
+		}
+		if err != nil {
+			return SelectStatement{}, 0, err
+		}
+		clauses = append(clauses, clause)
+		idx += 1 + nTokens
+	}
+	var defaultClause SelectDefaultClause
+	if idx+1 < len(tokens) &&
+		tokens[idx].Type == Indentation &&
+		len(tokens[idx].Content) == indentation &&
+		tokens[idx+1].Content == "default" {
+		idx++
+		defaultClause.LineNumber = tokens[idx].LineNumber
+		defaultClause.Column = tokens[idx].Column
+		idx++
+		if tokens[idx].Type == Space {
+			idx++
+		}
+		if tokens[idx].Type != Newline {
+			return SelectStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Default case not followed by newline.")
+		}
+		idx++
+		body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
+		if err != nil {
+			return SelectStatement{}, 0, err
+		}
+		defaultClause.Body = body
+		idx += numTokens
+	}
+	return SelectStatement{line, column, clauses, defaultClause}, idx, nil
+}
+
+// parses e.g. "rcving v I ch", which assigns to new variable v (of type I) a value received from channel ch
+func parseSelectRcv(tokens []Token, indentation int) (SelectRcvClause, int, error) {
+	line := tokens[0].LineNumber
+	column := tokens[0].Column
+	idx := 1
+	if tokens[idx].Type != Space {
+		return SelectRcvClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+	}
+	idx++
+	name := tokens[idx]
+	if name.Type != IdentifierWord {
+		return SelectRcvClause{}, 0, msg(name.LineNumber, name.Column, "P0105", "Expecting identifier.")
+	}
+	idx++
+	if tokens[idx].Type != Space {
+		return SelectRcvClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+	}
+	idx++
+	dt, nTokens, err := parseType(tokens[idx:], line)
+	if err != nil {
+		return SelectRcvClause{}, 0, err
+	}
+	idx += nTokens
+	if tokens[idx].Type != Space {
+		return SelectRcvClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+	}
+	idx++
+	channel, nTokens, err := parseExpression(tokens[idx:], line)
+	if err != nil {
+		return SelectRcvClause{}, 0, err
+	}
+	idx += nTokens
+	if tokens[idx].Type == Space {
+		idx++
+	}
+	if tokens[idx].Type != Newline {
+		return SelectRcvClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "rcving case not followed by newline.")
+	}
+	idx++
+	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
+	if err != nil {
+		return SelectRcvClause{}, 0, err
+	}
+	idx += numTokens
+	v := Variable{name.LineNumber, name.Column, name.Content, dt}
+	return SelectRcvClause{line, column, v, channel, body}, idx, nil
+}
+
+// parses e.g. "snding ch 7", which sends 7 to channel ch
+func parseSelectSend(tokens []Token, indentation int) (SelectSendClause, int, error) {
+	line := tokens[0].LineNumber
+	column := tokens[0].Column
+	idx := 1
+	if tokens[idx].Type != Space {
+		return SelectSendClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+	}
+	idx++
+	channel, nTokens, err := parseExpression(tokens[idx:], line)
+	if err != nil {
+		return SelectSendClause{}, 0, err
+	}
+	idx += nTokens
+	if tokens[idx].Type != Space {
+		return SelectSendClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+	}
+	idx++
+	value, nTokens, err := parseExpression(tokens[idx:], line)
+	if err != nil {
+		return SelectSendClause{}, 0, err
+	}
+	idx += nTokens
+	if tokens[idx].Type == Space {
+		idx++
+	}
+	if tokens[idx].Type != Newline {
+		return SelectSendClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "snding case not followed by newline.")
+	}
+	idx++
+	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
+	if err != nil {
+		return SelectSendClause{}, 0, err
+	}
+	idx += numTokens
+	return SelectSendClause{line, column, channel, value, body}, idx, nil
+}
+
+func parseGo(tokens []Token) (GoStatement, int, error) {
+	call, idx, err := parseCallStatement(tokens)
+	if err != nil {
+		return GoStatement{}, 0, err
+	}
+	switch call.(type) {
+	case FunctionCall, MethodCall:
+	default:
+		return GoStatement{}, 0, exprMsg(call, "P0310", "go statement must call a function or method.")
+	}
+	return GoStatement{tokens[0].LineNumber, tokens[0].Column, call}, idx, nil
+}
+
+func parseDefer(tokens []Token) (DeferStatement, int, error) {
+	call, idx, err := parseCallStatement(tokens)
+	if err != nil {
+		return DeferStatement{}, 0, err
+	}
+	switch call.(type) {
+	case FunctionCall, MethodCall, Operation:
+	default:
+		return DeferStatement{}, 0, exprMsg(call, "P0310", "defer statement must call a function or method, or be an operation.")
+	}
+	return DeferStatement{tokens[0].LineNumber, tokens[0].Column, call}, idx, nil
+}
+
+// parses a statement of a reserved word followed by one expression, e.g. "go (foo 3)"
+func parseCallStatement(tokens []Token) (Expression, int, error) {
+	line := tokens[0].LineNumber
+	idx := 1
+	if tokens[idx].Type != Space {
+		return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+	}
+	idx++
+	call, nTokens, err := parseExpression(tokens[idx:], line)
+	if err != nil {
+		return nil, 0, err
+	}
+	idx += nTokens
+	if tokens[idx].Type == Space {
+		idx++
+	}
+	if tokens[idx].Type != Newline {
+		return nil, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", tokens[0].Content+" statement not followed by newline.")
+	}
+	idx++
+	return call, idx, nil
+}
+
 func parseIf(tokens []Token, indentation int) (IfStatement, int, error) {
 	line := tokens[0].LineNumber
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return IfStatement{}, 0, msg(line, column, "Missing space.")
+		return IfStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	condition, numConditionTokens, err := parseExpression(tokens[idx:], line)
@@ -1227,7 +1815,7 @@ func parseIf(tokens []Token, indentation int) (IfStatement, int, error) {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return IfStatement{}, 0, msg(line, column, "If statement condition not followed by newline.")
+		return IfStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "If statement condition not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -1272,19 +1860,19 @@ func parseElif(tokens []Token, indentation int) (ElseifClause, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return ElseifClause{}, 0, msg(line, column, "Missing space.")
+		return ElseifClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	condition, numConditionTokens, err := parseExpression(tokens[idx:], line)
 	if err != nil {
-		return ElseifClause{}, 0, msg(line, column, "Improper condition in if statement.")
+		return ElseifClause{}, 0, msg(line, column, "P0306", "Improper condition in if statement.")
 	}
 	idx += numConditionTokens
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return ElseifClause{}, 0, msg(line, column, "Elseif clause condition not followed by newline.")
+		return ElseifClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Elseif clause condition not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -1303,7 +1891,7 @@ func parseElse(tokens []Token, indentation int) (ElseClause, int, error) {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return ElseClause{}, 0, msg(line, column, "Else clause not followed by newline.")
+		return ElseClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Else clause not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -1319,16 +1907,16 @@ func parseForeach(tokens []Token, indentation int) (ForeachStatement, int, error
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return ForeachStatement{}, 0, msg(line, column, "Missing space.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return ForeachStatement{}, 0, msg(line, column, "Expecting identifier for the indexes in foreach.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting identifier for the indexes in foreach.")
 	}
 	indexName := tokens[idx].Content
 	idx++
 	if tokens[idx].Type != Space {
-		return ForeachStatement{}, 0, msg(line, column, "Missing space.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	indexType, nTokens, err := parseType(tokens[idx:], line)
@@ -1337,16 +1925,16 @@ func parseForeach(tokens []Token, indentation int) (ForeachStatement, int, error
 	}
 	idx += nTokens
 	if tokens[idx].Type != Space {
-		return ForeachStatement{}, 0, msg(line, column, "Missing space.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return ForeachStatement{}, 0, msg(line, column, "Expecting identifier for the values in foreach.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting identifier for the values in foreach.")
 	}
 	valName := tokens[idx].Content
 	idx++
 	if tokens[idx].Type != Space {
-		return ForeachStatement{}, 0, msg(line, column, "Missing space.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	valType, nTokens, err := parseType(tokens[idx:], line)
@@ -1355,7 +1943,7 @@ func parseForeach(tokens []Token, indentation int) (ForeachStatement, int, error
 	}
 	idx += nTokens
 	if tokens[idx].Type != Space {
-		return ForeachStatement{}, 0, msg(line, column, "Missing space.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	collection, nTokens, err := parseExpression(tokens[idx:], line)
@@ -1363,8 +1951,10 @@ func parseForeach(tokens []Token, indentation int) (ForeachStatement, int, error
 		return ForeachStatement{}, 0, err
 	}
 	idx += nTokens
+	label, nLabel := parseLabel(tokens[idx:])
+	idx += nLabel
 	if tokens[idx].Type != Newline {
-		return ForeachStatement{}, 0, msg(line, column,
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102",
 			"Foreach statement collection expression not followed by newline.")
 	}
 	idx++
@@ -1377,7 +1967,7 @@ func parseForeach(tokens []Token, indentation int) (ForeachStatement, int, error
 		line, column,
 		indexName, indexType,
 		valName, valType,
-		collection, body}, idx, nil
+		collection, body, label}, idx, nil
 }
 
 func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement, int, error) {
@@ -1385,16 +1975,16 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return ForincStatement{}, 0, msg(line, column, "Missing space.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return ForincStatement{}, 0, msg(line, column, "Expecting identifier for the index in forinc.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting identifier for the index in forinc.")
 	}
 	indexName := tokens[idx].Content
 	idx++
 	if tokens[idx].Type != Space {
-		return ForincStatement{}, 0, msg(line, column, "Missing space.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	indexType, nTokens, err := parseType(tokens[idx:], line)
@@ -1403,7 +1993,7 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 	}
 	idx += nTokens
 	if tokens[idx].Type != Space {
-		return ForincStatement{}, 0, msg(line, column, "Missing space.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	startExpr, nTokens, err := parseExpression(tokens[idx:], line)
@@ -1412,7 +2002,7 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 	}
 	idx += nTokens
 	if tokens[idx].Type != Space {
-		return ForincStatement{}, 0, msg(line, column, "Missing space.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	endExpr, nTokens, err := parseExpression(tokens[idx:], line)
@@ -1420,8 +2010,10 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 		return ForincStatement{}, 0, err
 	}
 	idx += nTokens
+	label, nLabel := parseLabel(tokens[idx:])
+	idx += nLabel
 	if tokens[idx].Type != Newline {
-		return ForincStatement{}, 0, msg(line, column,
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102",
 			"Foreach statement collection expression not followed by newline.")
 	}
 	idx++
@@ -1434,7 +2026,7 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 		line, column,
 		indexName, indexType,
 		startExpr, endExpr,
-		body, isDec}, idx, nil
+		body, isDec, label}, idx, nil
 }
 
 func parseWhile(tokens []Token, indentation int) (WhileStatement, int, error) {
@@ -1442,7 +2034,7 @@ func parseWhile(tokens []Token, indentation int) (WhileStatement, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return WhileStatement{}, 0, msg(line, column, "Missing space.")
+		return WhileStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	condition, nTokens, err := parseExpression(tokens[idx:], line)
@@ -1450,8 +2042,10 @@ func parseWhile(tokens []Token, indentation int) (WhileStatement, int, error) {
 		return WhileStatement{}, 0, err
 	}
 	idx += nTokens
+	label, nLabel := parseLabel(tokens[idx:])
+	idx += nLabel
 	if tokens[idx].Type != Newline {
-		return WhileStatement{}, 0, msg(line, column, "While statement condition not followed by newline.")
+		return WhileStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "While statement condition not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -1459,7 +2053,7 @@ func parseWhile(tokens []Token, indentation int) (WhileStatement, int, error) {
 		return WhileStatement{}, 0, err
 	}
 	idx += numTokens
-	return WhileStatement{line, column, condition, body}, idx, nil
+	return WhileStatement{line, column, condition, body, label}, idx, nil
 }
 
 func parseReturn(tokens []Token) (ReturnStatement, int, error) {
@@ -1473,7 +2067,7 @@ func parseReturn(tokens []Token) (ReturnStatement, int, error) {
 		return ReturnStatement{line, column, nil}, idx + 2, nil
 	}
 	if tokens[idx].Type != Space {
-		return ReturnStatement{}, 0, msg(line, column, "Missing space.")
+		return ReturnStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	value, nTokens, err := parseExpression(tokens[idx:], line)
@@ -1492,7 +2086,7 @@ func parseReturn(tokens []Token) (ReturnStatement, int, error) {
 			break
 		}
 		if tokens[idx].Type != Space {
-			return ReturnStatement{}, 0, msg(line, column, "Missing space.")
+			return ReturnStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 		}
 		idx++
 		value, nTokens, err := parseExpression(tokens[idx:], line)
@@ -1508,29 +2102,40 @@ func parseReturn(tokens []Token) (ReturnStatement, int, error) {
 func parseBreak(tokens []Token) (BreakStatement, int, error) {
 	line := tokens[0].LineNumber
 	column := tokens[0].Column
-	idx := 1
+	label, idx := parseLabel(tokens[1:])
+	idx++
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return BreakStatement{}, 0, msg(line, column, "Break statement not terminated with newline.")
+		return BreakStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Break statement not terminated with newline.")
 	}
 	idx++
-	return BreakStatement{line, column}, idx, nil
+	return BreakStatement{line, column, label}, idx, nil
 }
 
 func parseContinue(tokens []Token) (ContinueStatement, int, error) {
 	line := tokens[0].LineNumber
 	column := tokens[0].Column
-	idx := 1
+	label, idx := parseLabel(tokens[1:])
+	idx++
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return ContinueStatement{}, 0, msg(line, column, "Continue statement not terminated with newline.")
+		return ContinueStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Continue statement not terminated with newline.")
 	}
 	idx++
-	return ContinueStatement{line, column}, idx, nil
+	return ContinueStatement{line, column, label}, idx, nil
+}
+
+// parses the label (if any) at the end of the line of a loop, break, or continue,
+// returning the label ("" if none) and the number of tokens
+func parseLabel(tokens []Token) (string, int) {
+	if tokens[0].Type == Space && tokens[1].Type == IdentifierWord {
+		return tokens[1].Content, 2
+	}
+	return "", 0
 }
 
 // assume first token is reserved word "as"
@@ -1540,7 +2145,7 @@ func parseAssignment(tokens []Token) (AssignmentStatement, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return AssignmentStatement{}, 0, msg(line, column, "Missing space.")
+		return AssignmentStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	exprs := []Expression{}
@@ -1560,12 +2165,12 @@ func parseAssignment(tokens []Token) (AssignmentStatement, int, error) {
 			break
 		}
 		if tokens[idx].Type != Space {
-			return AssignmentStatement{}, 0, msg(line, column, "Missing space.")
+			return AssignmentStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 		}
 		idx++
 	}
 	if len(exprs) < 2 {
-		return AssignmentStatement{}, 0, msg(line, column, "Invalid assignment statement.")
+		return AssignmentStatement{}, 0, msg(line, column, "P0108", "Invalid assignment statement.")
 	}
 	return AssignmentStatement{
 		tokens[0].LineNumber,
@@ -1576,10 +2181,9 @@ func parseAssignment(tokens []Token) (AssignmentStatement, int, error) {
 
 func parseLocals(tokens []Token) (LocalsStatement, int, error) {
 	line := tokens[0].LineNumber
-	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return LocalsStatement{}, 0, msg(line, column, "Expecting space.")
+		return LocalsStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 	}
 	idx++
 	var locals []Variable
@@ -1588,7 +2192,7 @@ func parseLocals(tokens []Token) (LocalsStatement, int, error) {
 		if token.Type == IdentifierWord {
 			idx++
 			if tokens[idx].Type != Space {
-				return LocalsStatement{}, 0, msg(line, column, "Expecting space.")
+				return LocalsStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expecting space.")
 			}
 			idx++
 			dataType, n, err := parseType(tokens[idx:], line)
@@ -1607,7 +2211,7 @@ func parseLocals(tokens []Token) (LocalsStatement, int, error) {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return LocalsStatement{}, 0, msg(line, column, "Expecting newline in locals statement.")
+		return LocalsStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Expecting newline in locals statement.")
 	}
 	idx++
 	return LocalsStatement{tokens[0].LineNumber, tokens[0].Column, locals}, idx, nil
@@ -1618,6 +2222,7 @@ func parseLocals(tokens []Token) (LocalsStatement, int, error) {
 // May return zero statements if body is empty.
 func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 	var statements []Statement
+	var errs Diagnostics // after an error, parsing resumes at the next statement of this body
 	i := 0
 	for i < len(tokens) {
 		t := tokens[i]
@@ -1628,6 +2233,7 @@ func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 			if numSpaces < indentation { // gone past end of the body
 				break
 			} else if numSpaces == indentation {
+				start := i
 				i++
 				t = tokens[i]
 				var statement Statement
@@ -1637,7 +2243,9 @@ func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 				case ReservedWord:
 					switch t.Content {
 					case "func":
-						return nil, 0, msg(t.LineNumber, t.Column, "Functions cannot be nested.")
+						err = msg(t.LineNumber, t.Column, "P0107", "Functions cannot be nested. (Define a local function with localfunc.)")
+					case "localfunc":
+						statement, numTokens, err = parseLocalFunc(tokens[i:], indentation)
 					case "as":
 						statement, numTokens, err = parseAssignment(tokens[i:])
 					case "if":
@@ -1652,6 +2260,14 @@ func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 						statement, numTokens, err = parseReturn(tokens[i:])
 					case "typeswitch":
 						statement, numTokens, err = parseTypeswitch(tokens[i:], indentation)
+					case "switch":
+						statement, numTokens, err = parseSwitch(tokens[i:], indentation)
+					case "select":
+						statement, numTokens, err = parseSelect(tokens[i:], indentation)
+					case "go":
+						statement, numTokens, err = parseGo(tokens[i:])
+					case "defer":
+						statement, numTokens, err = parseDefer(tokens[i:])
 					case "forinc":
 						statement, numTokens, err = parseForinc(tokens[i:], indentation, false)
 					case "fordec":
@@ -1661,31 +2277,56 @@ func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 					case "continue":
 						statement, numTokens, err = parseContinue(tokens[i:])
 					default:
-						return nil, 0, msg(t.LineNumber, t.Column, "Improper reserved word '"+t.Content+"' in body.")
-					}
-					if err != nil {
-						return nil, 0, err
+						err = msg(t.LineNumber, t.Column, "P0104", "Improper reserved word '"+t.Content+"' in body.")
 					}
 				case OpenParen:
 					var expression Expression
 					expression, numTokens, err = parseOpenParen(tokens[i:])
 					if err != nil {
-						return nil, 0, err
+						break
 					}
 					statement = expression.(Statement)
 					if tokens[i+numTokens].Type != Newline {
-						return nil, 0, msg(t.LineNumber, t.Column, "Statement not terminated with newline.")
+						err = msg(t.LineNumber, t.Column, "P0102", "Statement not terminated with newline.")
+						break
 					}
 					numTokens++ // add in the newline
 				default:
-					return nil, 0, msg(t.LineNumber, t.Column, "Improper token. Expected start of statement.")
+					err = msg(t.LineNumber, t.Column, "P0104", "Improper token. Expected start of statement.")
+				}
+				if err != nil {
+					errs = errs.add(err)
+					i = skipStatement(tokens, start, indentation)
+					continue
 				}
 				statements = append(statements, statement)
 				i += numTokens
 			} else {
-				return nil, 0, msg(t.LineNumber, t.Column, "Improper indentation.")
+				errs = errs.add(msg(t.LineNumber, t.Column, "P0103", "Improper indentation."))
+				i = skipStatement(tokens, i, indentation)
+			}
+		}
+	}
+	return statements, i, errs.err()
+}
+
+// returns the index of the line after the statement whose line starts at tokens[i],
+// skipping the lines of the statement's bodies and of its elif, else, case, default, rcving, and snding clauses
+func skipStatement(tokens []Token, i int, indentation int) int {
+	for {
+		for i < len(tokens) && tokens[i].Type != Newline {
+			i++
+		}
+		i++
+		if i >= len(tokens) || tokens[i].Type != Indentation || len(tokens[i].Content) < indentation {
+			return i
+		}
+		if len(tokens[i].Content) == indentation {
+			switch tokens[i+1].Content {
+			case "elif", "else", "case", "default", "rcving", "snding":
+			default:
+				return i
 			}
 		}
 	}
-	return statements, i, nil
 }
@@ -7,6 +7,7 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
@@ -271,3 +272,30 @@ func SeekFileEnd(id int64, offset int64) (int64, string) {
 		return 0, "Error seeking file: no open file has id '" + strconv.FormatInt(id, 10) + "'"
 	}
 }
+
+// PanicMessage returns the message of a panic's value, as the recover operator returns it:
+// "" for no panic, the text of an error (without the "runtime error: " of Go's runtime panics),
+// or the value as print would show it.
+func PanicMessage(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case error:
+		return strings.TrimPrefix(v.Error(), "runtime error: ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Uncaught is deferred by the generated main. If a panic escapes the program's main function,
+// it prints the panic's message and the stack trace (which pigeon shows in terms of the source),
+// and exits with status 2.
+func Uncaught() {
+	v := recover()
+	if v == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "panic: "+PanicMessage(v)+"\n")
+	os.Stderr.Write(debug.Stack())
+	os.Exit(2)
+}
@@ -0,0 +1,62 @@
+package goPigeon
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"print", "print", 0},
+		{"prinln", "println", 1},
+		{"concatt", "concat", 1},
+		{"str", "Str", 1},
+		{"kitten", "sitting", 3},
+		{"ab", "ba", 1}, // a transposition
+		{"pirntln", "println", 1},
+		{"ca", "abc", 3}, // (no substring is edited twice)
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates [][]string
+		want       string
+	}{
+		{"prinln", [][]string{{"print", "println"}}, " Did you mean println?"},
+		{"concatt", [][]string{{"concat", "count"}}, " Did you mean concat?"},
+		{"pirntln", [][]string{{"println"}}, " Did you mean println?"},
+		{"lenght", [][]string{{"length"}}, " Did you mean length?"},
+		// the candidates of all the lists are considered
+		{"totl", [][]string{{"x"}, {"total"}}, " Did you mean total?"},
+		// a name which is a candidate is not a misspelling of another
+		{"print", [][]string{{"print"}}, ""},
+		{"", [][]string{{"a"}}, ""},
+		// a distance of at most a third of the name's length
+		{"abcdef", [][]string{{"abxyef"}}, " Did you mean abxyef?"},
+		{"abcdef", [][]string{{"axyzef"}}, ""},
+		{"x", [][]string{{"y", "xy"}}, ""},
+		{"ab", [][]string{{"ac"}}, ""},
+		// a candidate which differs only in case is suggested whatever the name's length
+		{"str", [][]string{{"Str"}}, " Did you mean Str?"},
+		{"x", [][]string{{"X"}}, " Did you mean X?"},
+		{"Fooo", [][]string{{"Foo", "fOOO"}}, " Did you mean fOOO?"},
+		// ties go to the candidate which sorts first
+		{"cat", [][]string{{"hat", "bat"}}, " Did you mean bat?"},
+		{"cat", [][]string{{"hat"}, {"bat"}}, " Did you mean bat?"},
+	}
+	for _, test := range tests {
+		if got := didYouMean(test.name, test.candidates...); got != test.want {
+			t.Errorf("didYouMean(%q, %v) = %q, want %q", test.name, test.candidates, got, test.want)
+		}
+	}
+}
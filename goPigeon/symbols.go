@@ -0,0 +1,167 @@
+package goPigeon
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A Symbol is a use of a name in the source code, recorded during compilation for editor tooling.
+// Info describes the name (shown on hover), and DefFile, DefLine, and DefColumn locate its definition.
+type Symbol struct {
+	LineNumber int
+	Column     int
+	Name       string
+	Info       string
+	DefFile    string // the full path of the imported file of the definition ("" if it is in the source)
+	DefLine    int    // 0 if the definition is unknown
+	DefColumn  int
+}
+
+func (p *Package) addSymbol(line int, column int, name string, info string, defLine int, defColumn int) {
+	p.Symbols = append(p.Symbols, Symbol{line, column, name, info, "", defLine, defColumn})
+}
+
+// records a use of a name defined in the package def (which may be imported)
+func (p *Package) addSymbolOf(def *Package, line int, column int, name string, info string, defLine int, defColumn int) {
+	if def == p {
+		p.addSymbol(line, column, name, info, defLine, defColumn)
+		return
+	}
+	defFile := ""
+	if def != nil {
+		defFile = def.FullPath
+	} else {
+		defLine, defColumn = 0, 0
+	}
+	p.Symbols = append(p.Symbols, Symbol{line, column, name, info, defFile, defLine, defColumn})
+}
+
+// TypeString returns the data type in GoPigeon syntax, e.g. "M<Str L<I>>".
+func TypeString(dt DataType) string {
+	switch t := dt.(type) {
+	case BuiltinType:
+		if len(t.Params) == 0 {
+			return t.Name
+		}
+		return t.Name + "<" + typeListString(t.Params) + ">"
+	case ArrayType:
+		return "A<" + TypeString(t.Type) + " " + strconv.Itoa(t.Size) + ">"
+	case FunctionType:
+		params := typeListString(t.Params)
+		if t.Variadic {
+			// the last parameter, S<T>, is written ...T
+			last := t.Params[len(t.Params)-1].(BuiltinType)
+			params = strings.TrimSuffix(params, TypeString(last)) + "..." + TypeString(last.Params[0])
+		}
+		s := "Fn<" + params
+		if len(t.ReturnTypes) > 0 {
+			s += " : " + typeListString(t.ReturnTypes)
+		}
+		return strings.TrimSpace(s) + ">"
+	case Struct:
+		return t.Name
+	case InterfaceDefinition:
+		return t.Name
+	}
+	return "?"
+}
+
+func typeListString(types []DataType) string {
+	strs := make([]string, len(types))
+	for i, t := range types {
+		strs[i] = TypeString(t)
+	}
+	return strings.Join(strs, " ")
+}
+
+// returns the parsed data type in GoPigeon syntax
+func parsedTypeString(parsed ParsedDataType) string {
+	if parsed.Variadic {
+		return "..." + parsedTypeString(parsed.Params[0])
+	}
+	if len(parsed.Params) == 0 && len(parsed.ReturnTypes) == 0 {
+		return parsed.Type
+	}
+	strs := []string{}
+	for _, p := range parsed.Params {
+		strs = append(strs, parsedTypeString(p))
+	}
+	if len(parsed.ReturnTypes) > 0 {
+		strs = append(strs, ":")
+		for _, p := range parsed.ReturnTypes {
+			strs = append(strs, parsedTypeString(p))
+		}
+	}
+	return parsed.Type + "<" + strings.Join(strs, " ") + ">"
+}
+
+// returns the signature in GoPigeon syntax, e.g. "func foo a I b Str : Bool"
+func signatureString(keyword string, name string, params []Variable, returnTypes []ParsedDataType) string {
+	s := keyword + " " + name
+	for _, p := range params {
+		s += " " + p.Name + " " + parsedTypeString(p.Type)
+	}
+	if len(returnTypes) > 0 {
+		s += " :"
+		for _, rt := range returnTypes {
+			s += " " + parsedTypeString(rt)
+		}
+	}
+	return s
+}
+
+func (fn FunctionDefinition) info() string {
+	name := fn.Name
+	if name == "_main" {
+		name = "main"
+	}
+	return signatureString("func", name, fn.Parameters, fn.ReturnTypes)
+}
+
+func (m MethodDefinition) info() string {
+	return signatureString("method", m.Name, append([]Variable{m.Receiver}, m.Parameters...), m.ReturnTypes)
+}
+
+func (sig Signature) info() string {
+	s := sig.Name
+	for _, p := range sig.ParamTypes {
+		s += " " + parsedTypeString(p)
+	}
+	if len(sig.ReturnTypes) > 0 {
+		s += " :"
+		for _, rt := range sig.ReturnTypes {
+			s += " " + parsedTypeString(rt)
+		}
+	}
+	return s
+}
+
+func (st StructDefinition) info() string {
+	s := "struct " + st.Name
+	for _, m := range st.Members {
+		s += "\n    " + m.Name + " " + parsedTypeString(m.Type)
+	}
+	return s
+}
+
+func (iface InterfaceDefinition) info() string {
+	s := "interface " + iface.Name
+	for _, sig := range iface.Methods {
+		s += "\n    " + sig.info()
+	}
+	return s
+}
+
+// returns the definition of the named method of the struct
+func findMethod(pkg *Package, structName string, methodName string) (MethodDefinition, bool) {
+	for _, meth := range pkg.Methods[methodName] {
+		t := meth.Receiver.Type
+		if t.Type == "P" && len(t.Params) == 1 {
+			t = t.Params[0]
+		}
+		if t.Type == structName {
+			return meth, true
+		}
+	}
+	return MethodDefinition{}, false
+}
@@ -1,9 +1,10 @@
 package goPigeon
 
 import (
-	"errors"
 	"fmt"
-	"strconv"
+	"go/ast"
+	"go/constant"
+	"io/fs"
 )
 
 // we use arbitrary number values to designate each type of token. Rather than using straight ints, we
@@ -36,9 +37,14 @@ const (
 
 const indentationSpaces = 4
 
+// reserved words which start top-level definitions
+var definitionWords = []string{"func", "global", "const", "struct", "method", "interface", "import",
+	"nativeimport", "nativefunc", "nativestruct"}
+
 var reservedWords = []string{
 	"func",
 	"global",
+	"const",
 	"struct",
 	"interface",
 	"import",
@@ -48,7 +54,12 @@ var reservedWords = []string{
 	"method",
 	"foreach",
 	"go",
+	"defer",
 	"typeswitch",
+	"switch",
+	"select",
+	"rcving",
+	"snding",
 	"case",
 	"default",
 	"break",
@@ -63,6 +74,7 @@ var reservedWords = []string{
 	"return",
 	"as",
 	"locals",
+	"localfunc",
 	"_p",
 	"_main",
 	"_break",
@@ -105,6 +117,11 @@ var operators = []string{
 	"make",
 	"len",
 	"istype",
+	"send",
+	"rcv",
+	"close",
+	"panic",
+	"recover",
 	"band", // bitwise and
 	"bor",  // bitwise or
 	"bxor", // bitwise xor
@@ -134,6 +151,25 @@ var operators = []string{
 	"seekFileEnd",
 }
 
+// The operators whose operations have an effect (rather than merely computing a value), so that an
+// operation of one of them can be a statement on its own. An operator is true if its operation can
+// also be deferred (recover, which stops a panic only when called by a deferred function, can't).
+var statementOperators = map[string]bool{
+	"set":     true,
+	"sr":      false,
+	"push":    true,
+	"print":   true,
+	"println": true,
+	"prompt":  true,
+	"send":    true,
+	"rcv":     false,
+	"close":   true,
+	"panic":   true,
+	"recover": false,
+	// (the other file operators return values which should be checked)
+	"closeFile": true,
+}
+
 var builtinTypes = []string{
 	"I",
 	"F",
@@ -184,6 +220,7 @@ type ParsedDataType struct {
 	Type        string
 	Params      []ParsedDataType
 	ReturnTypes []ParsedDataType // non-nil only for functions with return types
+	Variadic    bool             // a final parameter written ...T (whose type is S<T>)
 }
 
 type BuiltinType struct {
@@ -199,6 +236,7 @@ type ArrayType struct {
 type FunctionType struct {
 	Params      []DataType
 	ReturnTypes []DataType
+	Variadic    bool // the last parameter is a slice, S<T>, which takes any number of T arguments
 }
 
 type SelectClause interface {
@@ -232,9 +270,11 @@ func (t FunctionCall) Statement()        {}
 func (t MethodCall) Statement()          {}
 func (t Operation) Statement()           {}
 func (t TypeswitchStatement) Statement() {}
+func (t SwitchStatement) Statement()     {}
 func (t BreakStatement) Statement()      {}
 func (t ContinueStatement) Statement()   {}
 func (t GoStatement) Statement()         {}
+func (t DeferStatement) Statement()      {}
 func (t SelectStatement) Statement()     {}
 
 func (t InterfaceDefinition) DataType() {}
@@ -274,6 +314,9 @@ func (t BreakStatement) Line() int {
 func (t ContinueStatement) Line() int {
 	return t.LineNumber
 }
+func (t DeferStatement) Line() int {
+	return t.LineNumber
+}
 func (t GoStatement) Line() int {
 	return t.LineNumber
 }
@@ -292,6 +335,9 @@ func (t FunctionCall) Line() int {
 func (t TypeswitchStatement) Line() int {
 	return t.LineNumber
 }
+func (t SwitchStatement) Line() int {
+	return t.LineNumber
+}
 func (t ParsedDataType) Line() int {
 	return t.LineNumber
 }
@@ -305,6 +351,25 @@ func (t Operation) Line() int {
 	return t.LineNumber
 }
 
+// returns the line and column at which the expression starts
+func position(e Expression) (int, int) {
+	switch e := e.(type) {
+	case Token:
+		return e.LineNumber, e.Column
+	case FunctionCall:
+		return e.LineNumber, e.Column
+	case Operation:
+		return e.LineNumber, e.Column
+	case TypeExpression:
+		return e.LineNumber, e.Column
+	case MethodCall:
+		return e.LineNumber, e.Column
+	case ParsedDataType:
+		return e.LineNumber, e.Column
+	}
+	return e.Line(), 0
+}
+
 func (t FunctionDefinition) Line() int {
 	return t.LineNumber
 }
@@ -345,7 +410,9 @@ type FunctionDefinition struct {
 	ReturnTypes []ParsedDataType
 	Body        []Statement
 	NativeCode  string // a native function has a string of native code and an empty body
+	NativeLine  int    // the line on which the native code starts
 	Pkg         *Package
+	BodyInvalid bool // the body failed to parse, so only the signature is checked
 }
 
 type GlobalDefinition struct {
@@ -353,8 +420,13 @@ type GlobalDefinition struct {
 	Column     int
 	Name       string
 	Value      Expression
-	Type       ParsedDataType
+	Type       ParsedDataType // for a constant defined without a type, the zero value until resolveConstTypes
 	Pkg        *Package
+	Const      bool // defined by const (the value is a constant expression, and it can't be changed)
+	// for a constant, its value as computed by resolveConstTypes (nil if it isn't known),
+	// and whether the value has errors
+	Constant constant.Value
+	Invalid  bool
 }
 
 type ImportDefinition struct {
@@ -362,15 +434,16 @@ type ImportDefinition struct {
 	Column     int
 	Path       string
 	Names      []string
-	Aliases    []string
+	Aliases    []string   // the alias of each name ("" if the name isn't aliased)
+	Positions  []Position // the position of each name
 	Pkg        *Package
 }
 
 type NativeImportDefinition struct {
 	LineNumber int
 	Column     int
-	Path       string
-	Alias      string
+	Path       string // the Go import path, e.g. "math/rand"
+	Alias      string // the name of the package in native code ("" for the last element of the path)
 	Pkg        *Package
 }
 
@@ -379,7 +452,8 @@ type StructDefinition struct {
 	Column     int
 	Name       string
 	Members    []Variable
-	NativeCode string
+	NativeCode string // Go fields of a native struct, which GoPigeon code can't access
+	NativeLine int    // the line on which the native code starts
 	Pkg        *Package
 }
 
@@ -392,6 +466,7 @@ type Struct struct {
 	Implements  map[string]bool // names of the interfaces this struct implements
 	Methods     map[string]FunctionType
 	NativeCode  string
+	NativeLine  int
 	Pkg         *Package
 }
 
@@ -404,6 +479,7 @@ type MethodDefinition struct {
 	ReturnTypes []ParsedDataType
 	Body        []Statement
 	Pkg         *Package
+	BodyInvalid bool // the body failed to parse, so only the signature is checked
 }
 
 type InterfaceDefinition struct {
@@ -434,14 +510,18 @@ type FunctionCall struct {
 	Column     int
 	Function   Expression // either an identifier or another function/operator call
 	Arguments  []Expression
+	Spread     bool // the last argument is a slice passed as the variadic arguments (...x)
 }
 
 type MethodCall struct {
-	LineNumber int
-	Column     int
-	MethodName string // either an identifier or another function/operator call
-	Receiver   Expression
-	Arguments  []Expression
+	LineNumber   int
+	Column       int
+	MethodName   string // either an identifier or another function/operator call
+	MethodLine   int    // position of the method name
+	MethodColumn int
+	Receiver     Expression
+	Arguments    []Expression
+	Spread       bool // the last argument is a slice passed as the variadic arguments (...x)
 }
 
 type Operation struct {
@@ -484,6 +564,21 @@ type TypeswitchCase struct {
 	Body       []Statement
 }
 
+type SwitchStatement struct {
+	LineNumber int
+	Column     int
+	Value      Expression
+	Cases      []SwitchCase
+	Default    []Statement
+}
+
+type SwitchCase struct {
+	LineNumber int
+	Column     int
+	Values     []Expression // the case runs if the switch value equals any of these
+	Body       []Statement
+}
+
 type IfStatement struct {
 	LineNumber int
 	Column     int
@@ -541,6 +636,12 @@ type GoStatement struct {
 	Call       Expression // FunctionCall or MethodCall
 }
 
+type DeferStatement struct {
+	LineNumber int
+	Column     int
+	Call       Expression // FunctionCall, MethodCall, or Operation
+}
+
 type LocalsStatement struct {
 	LineNumber int
 	Column     int
@@ -552,6 +653,7 @@ type WhileStatement struct {
 	Column     int
 	Condition  Expression
 	Body       []Statement
+	Label      string // the name by which a break or continue can target the loop ("" if none)
 }
 
 type ForeachStatement struct {
@@ -563,6 +665,7 @@ type ForeachStatement struct {
 	ValType    ParsedDataType
 	Collection Expression
 	Body       []Statement
+	Label      string // the name by which a break or continue can target the loop ("" if none)
 }
 
 type ForincStatement struct {
@@ -574,6 +677,7 @@ type ForincStatement struct {
 	EndVal     Expression
 	Body       []Statement
 	Dec        bool
+	Label      string // the name by which a break or continue can target the loop ("" if none)
 }
 
 type ReturnStatement struct {
@@ -585,11 +689,13 @@ type ReturnStatement struct {
 type BreakStatement struct {
 	LineNumber int
 	Column     int
+	Label      string // the label of the targeted loop ("" for the innermost loop)
 }
 
 type ContinueStatement struct {
 	LineNumber int
 	Column     int
+	Label      string // the label of the targeted loop ("" for the innermost loop)
 }
 
 type AssignmentStatement struct {
@@ -610,36 +716,18 @@ type Package struct {
 	Methods          map[string]map[string]MethodDefinition // method name:, struct name:, def
 	Interfaces       map[string]InterfaceDefinition
 	FullPath         string
-	Prefix           string
+	Filename         string // the source file's name as given (for an imported file, joined to the importing file's name)
+	Prefix           string // the package's import path in the output module (and qualifier of its definitions)
 	ImportDefs       map[string]ImportDefinition
 	ImportedPackages map[string]*Package
 	Code             string
-	NativeImports    map[string]string
-}
-
-func (p *Package) getExportedDefinition(name string) Definition {
-	g, ok := p.Globals[name]
-	if ok {
-		return g
-	}
-	st, ok := p.StructDefs[name]
-	if ok {
-		return st
-	}
-	f, ok := p.Funcs[name]
-	if ok {
-		return f
-	}
-	inter, ok := p.Interfaces[name]
-	if ok {
-		return inter
-	}
-	return nil
-}
-
-func msg(line int, column int, s string) error {
-	return errors.New("Line " + strconv.Itoa(line) + ", column " +
-		strconv.Itoa(column) + ": " + s)
+	NativeImports    map[string]NativeImportDefinition // by the name of the imported Go package in the code
+	Debug            bool                              // if true, the code is instrumented for the debugger
+	Symbols          []Symbol                          // uses of names in the source, recorded during compilation
+	lines            map[ast.Node]int                  // the source line of each generated declaration and statement (see mapLine)
+	fs               fs.FS                             // the file tree of FullPath and its imports (nil for the operating system's)
+	graph            *importGraph                      // the files of the compilation (nil if the package imports nothing)
+	imported         bool                              // true if the package is compiled from an imported file (into a package other than main)
 }
 
 func debug(args ...interface{}) {
@@ -0,0 +1,241 @@
+/*
+Package golden checks the code generated for the example programs, and the programs' output,
+against golden files:
+
+	go test ./golden             # report each difference from the golden files
+	go test ./golden -update     # rewrite the golden files (after checking that the differences are intended)
+
+The examples are goPigeon/examples/*.gopigeon and pigeon/examples/*.pigeon, plus the GoPigeon programs
+of several files, each in its own directory: goPigeon/examples/DIR/DIR.gopigeon (whose imported files
+are beside it). For an example NAME, golden/testdata/NAME.golden holds the generated Go code (or, if the
+example doesn't compile, its diagnostics), followed by the code generated for each imported file.
+Each example is compiled twice, and the two compilations must generate the same code, which must be
+formatted as gofmt would format it. Each example is a subtest, e.g. go test ./golden -run 'TestGolden/loops.pigeon'.
+
+If the go command is installed, each example which compiles is also run with 'pigeon run' in an empty
+directory, with input read from the file NAME.input beside the example (no input if there is no such file),
+and its output, error output, and exit status are checked against golden/testdata/NAME.out.
+*/
+package golden
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BrianWill/pigeon/goPigeon"
+	"github.com/BrianWill/pigeon/pigeon"
+)
+
+const testdataDir = "golden/testdata"
+
+// import path prefix of the generated packages (as for 'pigeon run')
+const outputModule = "pigeon_output/"
+
+// the longest an example may run (including the Go build)
+const timeout = 2 * time.Minute
+
+// the date and time with which the log package prefixes messages
+var logTimestamp = regexp.MustCompile(`(?m)^[0-9]{4}/[0-9]{2}/[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2} `)
+
+var update = flag.Bool("update", false, "rewrite the golden files instead of checking them")
+
+// the tests run in the repository root, to which the examples and golden files are relative
+// (as are the file names in the golden diagnostics)
+func TestMain(m *testing.M) {
+	flag.Parse()
+	err := os.Chdir("..")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	os.Exit(m.Run())
+}
+
+func TestGolden(t *testing.T) {
+	var examples []string
+	for _, pattern := range []string{"goPigeon/examples/*.gopigeon", "pigeon/examples/*.pigeon"} {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		examples = append(examples, files...)
+	}
+	dirs, err := filepath.Glob("goPigeon/examples/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		program := filepath.Join(dir, filepath.Base(dir)+".gopigeon")
+		if _, err := os.Stat(program); err == nil {
+			examples = append(examples, program)
+		}
+	}
+	if len(examples) == 0 {
+		t.Fatal("No examples found.")
+	}
+	pigeonCmd := ""
+	if _, err := exec.LookPath("go"); err == nil {
+		pigeonCmd = filepath.Join(t.TempDir(), "pigeon")
+		output, err := exec.Command("go", "build", "-o", pigeonCmd, ".").CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to build the pigeon command:\n%s", output)
+		}
+	} else {
+		t.Log("The go command is not installed, so the examples aren't run.")
+	}
+	for _, example := range examples {
+		example := example
+		t.Run(filepath.Base(example), func(t *testing.T) {
+			name := filepath.Base(example)
+			files, ok := compile(example)
+			code := strings.Join(files, "")
+			if again, _ := compile(example); strings.Join(again, "") != code {
+				t.Error("compiling twice generates different code")
+			}
+			for _, file := range files {
+				if formatted, err := format.Source([]byte(file)); ok && (err != nil || string(formatted) != file) {
+					t.Error("generated code isn't formatted as gofmt would format it")
+				}
+			}
+			if err := check(name+".golden", code); err != nil {
+				t.Errorf("generated code: %v", err)
+			}
+			if !ok || pigeonCmd == "" {
+				return
+			}
+			output, err := run(pigeonCmd, example)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := check(name+".out", output); err != nil {
+				t.Errorf("output: %v", err)
+			}
+		})
+	}
+}
+
+// returns the code generated for the example (the code of the main package, then the code of
+// each imported file's package, each preceded by a line naming the file) and true,
+// or the diagnostics and false if the example doesn't compile
+func compile(example string) ([]string, bool) {
+	var code []string
+	var diags []string
+	switch filepath.Ext(example) {
+	case ".gopigeon":
+		pkg, ds := goPigeon.Compile(example, outputModule, false)
+		if pkg != nil {
+			code = []string{pkg.Code}
+			for _, imported := range pkg.Imports() {
+				code = append(code, "// ---- "+filepath.ToSlash(imported.Filename)+"\n"+imported.Code)
+			}
+		}
+		for _, d := range ds {
+			diags = append(diags, diagnostic(d.File, d.Start.Line, d.Start.Column, d.Code, d.Message))
+		}
+	case ".pigeon":
+		pkg, ds := pigeon.Compile(example, outputModule, false)
+		if pkg != nil {
+			code = []string{pkg.Code}
+		}
+		for _, d := range ds {
+			diags = append(diags, diagnostic(d.File, d.Start.Line, d.Start.Column, d.Code, d.Message))
+		}
+	}
+	if code == nil {
+		return []string{strings.Join(diags, "")}, false
+	}
+	return code, true
+}
+
+func diagnostic(file string, line int, column int, code string, message string) string {
+	return filepath.ToSlash(file) + ":" + strconv.Itoa(line) + ":" + strconv.Itoa(column) + ": " +
+		code + " " + message + "\n"
+}
+
+// runs the example in an empty directory, returning its output, error output (without log timestamps), and exit status
+func run(pigeonCmd string, example string) (string, error) {
+	input, err := ioutil.ReadFile(strings.TrimSuffix(example, filepath.Ext(example)) + ".input")
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	path, err := filepath.Abs(example)
+	if err != nil {
+		return "", err
+	}
+	dir, err := ioutil.TempDir("", "golden-run-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, pigeonCmd, "run", path)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("timed out after %v", timeout)
+	}
+	status := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		status = exitErr.ExitCode()
+	} else if err != nil {
+		return "", err
+	}
+	return "output:\n" + stdout.String() +
+		"error output:\n" + logTimestamp.ReplaceAllString(stderr.String(), "") +
+		"exit status " + strconv.Itoa(status) + "\n", nil
+}
+
+// checks that the golden file holds the text (or, with -update, writes the text to the file)
+func check(golden string, text string) error {
+	path := filepath.Join(testdataDir, golden)
+	if *update {
+		err := os.MkdirAll(testdataDir, os.ModePerm)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, []byte(text), 0644)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if string(data) != text {
+		return fmt.Errorf("differs from %s (if the change is intended, run go test ./golden -update):\n%s",
+			path, firstDifference(string(data), text))
+	}
+	return nil
+}
+
+// describes the first line at which the text differs from the golden text
+func firstDifference(golden string, text string) string {
+	goldenLines, lines := strings.Split(golden, "\n"), strings.Split(text, "\n")
+	for i := 0; ; i++ {
+		if i >= len(goldenLines) || i >= len(lines) || goldenLines[i] != lines[i] {
+			want, got := "(end)", "(end)"
+			if i < len(goldenLines) {
+				want = goldenLines[i]
+			}
+			if i < len(lines) {
+				got = lines[i]
+			}
+			return fmt.Sprintf("line %d is\n\t%s\nwant\n\t%s", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+A Language Server Protocol server (https://microsoft.github.io/language-server-protocol/)
+for editing Pigeon and GoPigeon source files. Messages are JSON-RPC objects framed like
+DAP messages (see readMessage).
+
+Each time a document is opened or changed, it is compiled (without generating a program),
+and its diagnostics are published. For GoPigeon, hovering
+over a name shows its type or signature, and go-to-definition jumps to where the name is
+defined (functions, methods, structs, interfaces, globals, locals, and struct members), including
+definitions in imported files.
+
+LSP positions are zero-based, whereas the compilers number lines and columns from 1.
+*/
+
+type lspMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"` // absent for notifications
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocumentPositionParams struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+// an open source file
+type lspDocument struct {
+	Path     string
+	Lines    []string
+	Dialect  *dialect  // nil if the file has no known extension
+	Analysis *analysis // nil if Dialect is nil
+}
+
+// An lspServer reads messages from in and writes responses and notifications to out.
+type lspServer struct {
+	in        *bufio.Reader
+	out       io.Writer
+	documents map[string]*lspDocument // by URI
+	shutdown  bool                    // true once the client has requested shutdown
+}
+
+func lspCommand(args []string) int {
+	if len(args) != 0 {
+		fmt.Println("pigeon lsp takes no arguments: it serves the Language Server Protocol over stdin and stdout.")
+		return 2
+	}
+	status, err := serveLSP(os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return status
+}
+
+// serveLSP serves until the client sends 'exit' or in is exhausted.
+// Returns the exit status: 0 if the client requested shutdown before exit, otherwise 1.
+func serveLSP(in io.Reader, out io.Writer) (int, error) {
+	s := &lspServer{in: bufio.NewReader(in), out: out, documents: map[string]*lspDocument{}}
+	for {
+		data, err := readMessage(s.in)
+		if err == io.EOF {
+			return 1, nil
+		}
+		if err != nil {
+			return 1, err
+		}
+		var m lspMessage
+		err = json.Unmarshal(data, &m)
+		if err != nil {
+			return 1, err
+		}
+		if m.Method == "exit" {
+			if s.shutdown {
+				return 0, nil
+			}
+			return 1, nil
+		}
+		s.handle(m)
+	}
+}
+
+func (s *lspServer) write(msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		panic(err) // all messages are marshalable
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (s *lspServer) respond(id json.RawMessage, result interface{}) {
+	s.write(map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+func (s *lspServer) respondError(id json.RawMessage, code int, message string) {
+	s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   map[string]interface{}{"code": code, "message": message},
+	})
+}
+
+func (s *lspServer) notify(method string, params interface{}) {
+	s.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// JSON-RPC error codes
+const (
+	lspInvalidParams  = -32602
+	lspMethodNotFound = -32601
+)
+
+func (s *lspServer) handle(m lspMessage) {
+	isRequest := len(m.ID) > 0
+	switch m.Method {
+	case "initialize":
+		s.respond(m.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1, // the client sends the full text on each change
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]interface{}{"name": "pigeon"},
+		})
+	case "shutdown":
+		s.shutdown = true
+		s.respond(m.ID, nil)
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(m.Params, &params) == nil {
+			s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if json.Unmarshal(m.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			// with full sync, the last change is the whole document
+			s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(m.Params, &params) == nil {
+			delete(s.documents, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+				"uri":         params.TextDocument.URI,
+				"diagnostics": []interface{}{},
+			})
+		}
+	case "textDocument/hover":
+		doc, sym, ok := s.symbolAt(m)
+		if !ok {
+			s.respond(m.ID, nil)
+			break
+		}
+		s.respond(m.ID, map[string]interface{}{
+			"contents": map[string]interface{}{
+				"kind":  "markdown",
+				"value": "```" + doc.Dialect.Name + "\n" + sym.Info + "\n```",
+			},
+			"range": doc.wordRange(sym.Line, sym.Column),
+		})
+	case "textDocument/definition":
+		var params lspTextDocumentPositionParams
+		if json.Unmarshal(m.Params, &params) != nil {
+			s.respondError(m.ID, lspInvalidParams, "Invalid parameters.")
+			break
+		}
+		doc, sym, ok := s.symbolAt(m)
+		if !ok || sym.DefLine == 0 {
+			s.respond(m.ID, nil)
+			break
+		}
+		uri, lines := params.TextDocument.URI, doc.Lines
+		if sym.DefFile != "" {
+			uri, lines = pathURI(sym.DefFile), s.fileLines(sym.DefFile)
+		}
+		s.respond(m.ID, map[string]interface{}{
+			"uri":   uri,
+			"range": nameRange(lines, sym.DefLine, sym.DefColumn, sym.Name),
+		})
+	case "initialized", "textDocument/didSave", "$/cancelRequest", "$/setTrace":
+		// nothing to do
+	default:
+		if isRequest {
+			s.respondError(m.ID, lspMethodNotFound, "Unsupported method: "+m.Method)
+		}
+	}
+}
+
+// replaces the text of the document, then analyzes it and publishes its diagnostics
+func (s *lspServer) update(uri string, text string) {
+	doc := &lspDocument{
+		Path:  uriPath(uri),
+		Lines: strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n"),
+	}
+	doc.Dialect = dialectExtensions[filepath.Ext(doc.Path)]
+	s.documents[uri] = doc
+	diagnostics := []interface{}{}
+	if doc.Dialect != nil && doc.Dialect.Analyze != nil {
+		doc.Analysis = doc.Dialect.Analyze(doc.Path, []byte(text))
+		for _, d := range doc.Analysis.Diagnostics {
+			if d.File != "" && d.File != doc.Path {
+				continue // an error of an imported file (whose import is itself reported as failing)
+			}
+			r := doc.wordRange(1, 1) // a diagnostic without a position is shown at the start of the file
+			if d.Start.Line >= 1 {
+				r = lspRange{lspPosition{d.Start.Line - 1, d.Start.Column - 1}, lspPosition{d.End.Line - 1, d.End.Column - 1}}
+			}
+			severity := 1 // error
+			if d.Severity == "warning" {
+				severity = 2
+			}
+			diagnostics = append(diagnostics, map[string]interface{}{
+				"range":    r,
+				"severity": severity,
+				"code":     d.Code,
+				"source":   "pigeon",
+				"message":  d.Message,
+			})
+		}
+	}
+	s.notify("textDocument/publishDiagnostics", map[string]interface{}{"uri": uri, "diagnostics": diagnostics})
+}
+
+// returns the symbol at the position of a hover or definition request
+func (s *lspServer) symbolAt(m lspMessage) (*lspDocument, symbol, bool) {
+	var params lspTextDocumentPositionParams
+	if json.Unmarshal(m.Params, &params) != nil {
+		return nil, symbol{}, false
+	}
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok || doc.Analysis == nil {
+		return nil, symbol{}, false
+	}
+	sym, ok := doc.Analysis.symbolAt(params.Position.Line+1, params.Position.Character+1)
+	return doc, sym, ok
+}
+
+// returns the range of the word starting at the (one-based) position. If no word starts there,
+// the range spans one character.
+func (doc *lspDocument) wordRange(line int, column int) lspRange {
+	start := lspPosition{line - 1, column - 1}
+	end := lspPosition{line - 1, column}
+	if line <= len(doc.Lines) {
+		text := doc.Lines[line-1]
+		i := column - 1
+		for i < len(text) && isWordChar(text[i]) {
+			i++
+		}
+		if i > column-1 {
+			end.Character = i
+		}
+	}
+	return lspRange{start, end}
+}
+
+// returns the range of the name in the definition starting at the (one-based) position, which
+// may be the position of a keyword preceding the name, e.g. 'func'. If the name is not on the line,
+// the range is the word at the position.
+func nameRange(lines []string, line int, column int, name string) lspRange {
+	doc := &lspDocument{Lines: lines}
+	if line < 1 || line > len(lines) || column < 1 {
+		return doc.wordRange(line, column)
+	}
+	text := lines[line-1]
+	for i := column - 1; i+len(name) <= len(text); i++ {
+		end := i + len(name)
+		if text[i:end] == name && (i == 0 || !isWordChar(text[i-1])) && (end == len(text) || !isWordChar(text[end])) {
+			return lspRange{lspPosition{line - 1, i}, lspPosition{line - 1, end}}
+		}
+	}
+	return doc.wordRange(line, column)
+}
+
+// returns the lines of the file at the path: the text of its open document, if any, otherwise the
+// text on disk (nil if the file can't be read)
+func (s *lspServer) fileLines(path string) []string {
+	for _, doc := range s.documents {
+		if doc.Path == path {
+			return doc.Lines
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// converts an absolute path to a file URI
+func pathURI(path string) string {
+	slashed := filepath.ToSlash(path)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed // a Windows path, e.g. C:/dir
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String()
+}
+
+// converts a file URI to a path (other URIs are returned unchanged)
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
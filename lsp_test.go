@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A session through the requests an editor sends: the document of a program of two files is
+// opened, a name is hovered over, and the definitions of a local name and an imported name are found.
+func TestLSPSession(t *testing.T) {
+	path, err := filepath.Abs("goPigeon/examples/shapes/shapes.gopigeon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	geometryPath := filepath.Join(filepath.Dir(path), "geometry.gopigeon")
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := pathURI(path)
+	lines := strings.Split(string(text), "\n")
+	// the zero-based position of the first occurrence of the name in the line
+	position := func(line int, name string) map[string]interface{} {
+		return map[string]interface{}{"line": line, "character": strings.Index(lines[line], name)}
+	}
+	document := map[string]interface{}{"uri": uri}
+
+	var in bytes.Buffer
+	id := 0
+	send := func(method string, params interface{}) {
+		m := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+		if method != "initialized" && method != "exit" && !strings.HasPrefix(method, "textDocument/did") {
+			id++
+			m["id"] = id
+		}
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+	send("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}})
+	send("initialized", map[string]interface{}{})
+	send("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "gopigeon", "version": 1, "text": string(text)},
+	})
+	send("textDocument/hover", map[string]interface{}{"textDocument": document, "position": position(20, "total shapes")})
+	send("textDocument/definition", map[string]interface{}{"textDocument": document, "position": position(20, "total shapes")})
+	send("textDocument/definition", map[string]interface{}{"textDocument": document, "position": position(19, "describe")})
+	send("textDocument/definition", map[string]interface{}{"textDocument": document, "position": position(19, "println")})
+	send("shutdown", nil)
+	send("exit", nil)
+
+	var out bytes.Buffer
+	status, err := serveLSP(&in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 0 {
+		t.Errorf("exit status %d, want 0", status)
+	}
+	r := bufio.NewReader(&out)
+	next := func(want string) map[string]interface{} {
+		data, err := readMessage(r)
+		if err != nil {
+			t.Fatalf("reading the %s: %v", want, err)
+		}
+		var msg map[string]interface{}
+		err = json.Unmarshal(data, &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	// the result of the response with the id, as JSON
+	result := func(id int) string {
+		msg := next(fmt.Sprintf("response %d", id))
+		if msg["id"] != float64(id) {
+			t.Fatalf("got %v, want the response %d", msg, id)
+		}
+		if msg["error"] != nil {
+			t.Fatalf("response %d is an error: %v", id, msg["error"])
+		}
+		data, _ := json.Marshal(msg["result"])
+		return string(data)
+	}
+	type lspLocation struct {
+		URI   string   `json:"uri"`
+		Range lspRange `json:"range"`
+	}
+	// the location of the definition in the response with the id
+	definition := func(id int) lspLocation {
+		var loc lspLocation
+		err := json.Unmarshal([]byte(result(id)), &loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loc
+	}
+
+	capabilities := next("initialize response")["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	if capabilities["hoverProvider"] != true || capabilities["definitionProvider"] != true {
+		t.Errorf("capabilities %v, want hover and definition", capabilities)
+	}
+	diagnostics := next("diagnostics")
+	if diagnostics["method"] != "textDocument/publishDiagnostics" {
+		t.Fatalf("got %v, want the diagnostics of the opened document", diagnostics)
+	}
+	params := diagnostics["params"].(map[string]interface{})
+	if params["uri"] != uri || !reflect.DeepEqual(params["diagnostics"], []interface{}{}) {
+		t.Errorf("diagnostics %v, want none for %s", params, uri)
+	}
+
+	var hover struct {
+		Contents struct {
+			Value string `json:"value"`
+		} `json:"contents"`
+		Range lspRange `json:"range"`
+	}
+	err = json.Unmarshal([]byte(result(2)), &hover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(hover.Contents.Value, "func total shapes S<Shape> : F") {
+		t.Errorf("hover text %q, want the signature of total", hover.Contents.Value)
+	}
+	if start := strings.Index(lines[20], "total shapes"); hover.Range != (lspRange{lspPosition{20, start}, lspPosition{20, start + 5}}) {
+		t.Errorf("hover range %v, want the name total", hover.Range)
+	}
+
+	// total is defined by 'func total' on line 10
+	if got, want := definition(3), (lspLocation{uri, lspRange{lspPosition{9, 5}, lspPosition{9, 10}}}); got != want {
+		t.Errorf("definition of total: got %v, want %v", got, want)
+	}
+	// describe is defined by 'func describe' on line 28 of the imported file
+	if got, want := definition(4), (lspLocation{pathURI(geometryPath), lspRange{lspPosition{27, 5}, lspPosition{27, 13}}}); got != want {
+		t.Errorf("definition of describe: got %v, want %v", got, want)
+	}
+	// println is a builtin
+	if got := result(5); got != "null" {
+		t.Errorf("definition of println: got %s, want null", got)
+	}
+	result(6) // shutdown
+}
@@ -1,70 +1,178 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
-
-	"github.com/BrianWill/pigeon/goPigeon"
 )
 
-func Run(filename string) (*exec.Cmd, error) {
-	cmd := exec.Command("go", "run", filename)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err := cmd.Run()
-	if err != nil {
-		return nil, err
-	}
-	return cmd, nil
-}
+const usage = `Usage:
 
-func main() {
+    pigeon [run] [-keep dir] [-dialect name] [-json] file                compile and run a program
+    pigeon run -interp [-dialect name] [-json] file                      run a Pigeon program in the interpreter
+    pigeon build [-o output] [-keep dir] [-dialect name] [-json] file    compile a program into an executable
+    pigeon check [-dialect name] [-json] file                            report compile errors without building
+    pigeon repl                                                          evaluate Pigeon input interactively
+    pigeon explain [code]                                                explain an error code (or list the codes)
+    pigeon debug [-dialect name] file                                    run a program in the debugger
+    pigeon dap                                                           serve the Debug Adapter Protocol on stdin/stdout
+    pigeon lsp                                                           serve the Language Server Protocol on stdin/stdout
+
+The dialect is chosen by the file extension: Pigeon (.pigeon) or GoPigeon (.gopigeon).
+The -dialect flag (pigeon or gopigeon) overrides the extension.
+With -json, compile errors are printed as a JSON array of diagnostics instead of as text.
+With -interp, the program runs without the Go toolchain (only Pigeon has an interpreter).
+`
 
-	if len(os.Args) < 2 {
-		fmt.Println("Must specify a file to run.")
-		return
-	}
-	basedir := os.Getenv("GOPATH") + "/src/pigeon_output/"
-	outputFile := basedir + "output.go"
-	if _, err := os.Stat(basedir); os.IsNotExist(err) {
-		os.Mkdir(basedir, os.ModePerm)
-	}
-	var code []byte
-	if strings.HasSuffix(os.Args[1], ".gopigeon") {
-		pkg, err := goPigeon.Compile(os.Args[1], "pigeon_output/")
+// parses flags which may come before or after the one expected file argument
+func parseArgs(flags *flag.FlagSet, args []string) (string, error) {
+	var files []string
+	for {
+		err := flags.Parse(args)
 		if err != nil {
-			fmt.Println(err)
-			return
+			return "", err
 		}
-		code = []byte(pkg.Code)
-	} else if strings.HasSuffix(os.Args[1], ".pigeon") {
-		pkg, err := goPigeon.Compile(os.Args[1], "pigeon_output/")
-		if err != nil {
-			fmt.Println(err)
-			return
+		if flags.NArg() == 0 {
+			break
+		}
+		files = append(files, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(files) != 1 {
+		return "", errors.New("Must specify one file.")
+	}
+	return files[0], nil
+}
+
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	keep := flags.String("keep", "", "build in this directory and leave the generated Go source there")
+	dialectName := flags.String("dialect", "", "compile the file as this dialect, regardless of its extension")
+	jsonOutput := flags.Bool("json", false, "if compilation fails, print the diagnostics as a JSON array")
+	interp := flags.Bool("interp", false, "run the program in the interpreter instead of compiling it with Go")
+	filename, err := parseArgs(flags, args)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	d, err := findDialect(filename, *dialectName)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	if *interp {
+		if d.Interpret == nil {
+			fmt.Println("The " + d.Name + " dialect has no interpreter.")
+			return 2
 		}
-		code = []byte(pkg.Code)
-	} else {
-		log.Fatal("File has improper extension.")
+		diags := d.Interpret(filename)
+		if diags != nil {
+			printDiagnostics(diags, *jsonOutput)
+			return 1
+		}
+		return 0
+	}
+	prog, diags := d.Compile(filename, outputModule, false)
+	if prog == nil {
+		printDiagnostics(diags, *jsonOutput)
+		return 1
 	}
-	err := ioutil.WriteFile(outputFile, code, os.ModePerm)
+	w, err := newWorkspace(*keep, d)
 	if err != nil {
 		fmt.Println(err)
-		return
+		return 1
 	}
-	err = exec.Command("go", "fmt", outputFile).Run()
+	defer w.Close()
+	_, err = w.writeProgram(prog)
 	if err != nil {
 		fmt.Println(err)
-		return
+		return 1
 	}
-	_, err = Run(outputFile)
+	status, err := w.run()
 	if err != nil {
 		fmt.Println(err)
-		return
 	}
+	return status
+}
+
+func buildCommand(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := flags.String("o", "", "name of the executable (default: the file name without its extension)")
+	keep := flags.String("keep", "", "build in this directory and leave the generated Go source there")
+	dialectName := flags.String("dialect", "", "compile the file as this dialect, regardless of its extension")
+	jsonOutput := flags.Bool("json", false, "if compilation fails, print the diagnostics as a JSON array")
+	filename, err := parseArgs(flags, args)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	d, err := findDialect(filename, *dialectName)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	prog, diags := d.Compile(filename, outputModule, false)
+	if prog == nil {
+		printDiagnostics(diags, *jsonOutput)
+		return 1
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	outputPath, err := filepath.Abs(*output)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	w, err := newWorkspace(*keep, d)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer w.Close()
+	_, err = w.writeProgram(prog)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	status, err := w.build(outputPath)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return status
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) < 1 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	var status int
+	switch args[0] {
+	case "run":
+		status = runCommand(args[1:])
+	case "build":
+		status = buildCommand(args[1:])
+	case "check":
+		status = checkCommand(args[1:])
+	case "repl":
+		status = replCommand(args[1:])
+	case "explain":
+		status = explainCommand(args[1:])
+	case "debug":
+		status = debugCommand(args[1:])
+	case "dap":
+		status = dapCommand(args[1:])
+	case "lsp":
+		status = lspCommand(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		status = runCommand(args)
+	}
+	os.Exit(status)
 }
@@ -3,8 +3,11 @@ package pigeon
 import (
 	"errors"
 	"fmt"
+	"go/ast"
+	"go/token"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -12,243 +15,364 @@ import (
 /* All identifiers get prefixed with _ to avoid collisions with Go reserved words and predefined identifiers */
 // returns map of valid breakpoints
 func compile(pkg *Package, outputDir string) error {
-	code := "package main\n"
+	file := &ast.File{Name: ast.NewIdent("main")}
+	file.Decls = append(file.Decls, &ast.GenDecl{Tok: token.IMPORT, Specs: []ast.Spec{
+		importSpec("_fmt", "fmt"),
+		importSpec("_log", "log"),
+		importSpec("_std", "github.com/BrianWill/pigeon/pigeon/stdlib"),
+	}})
 
-	code += `import _fmt "fmt"
-import _log "log"
-import _std "github.com/BrianWill/pigeon/pigeon/stdlib"
-`
-
-	c, err := compileGlobals(pkg)
+	// an error stops the compilation of a global or function but not of the others
+	var errs Diagnostics
+	decls, err := compileGlobals(pkg)
 	if err != nil {
-		return err
+		errs = errs.add(err)
 	}
-	code += c
-	for _, fn := range pkg.Funcs {
+	file.Decls = append(file.Decls, decls...)
+	for _, fn := range pkg.funcsInOrder() {
 		if fn.Pkg != pkg {
 			continue
 		}
-		c, err := compileFunc(fn)
+		decl, err := compileFunc(fn)
 		if err != nil {
-			return err
+			errs = errs.add(err)
+			continue
 		}
-		code += c
+		file.Decls = append(file.Decls, decl)
+	}
+	if len(errs) > 0 {
+		return errs.err()
 	}
 
-	code += `
-		
-		func main() {
-			_fmt.Println()
-			_main()
-		}
-		`
+	file.Decls = append(file.Decls, &ast.FuncDecl{
+		Name: ast.NewIdent("main"),
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: block(
+			callStmt("_fmt.Println"),
+			callStmt("_main"),
+		),
+	})
 
-	pkg.Code = code
-	return nil
+	pkg.Code, err = pkg.print(file)
+	return err
 }
 
-func compileExpression(e Expression, pkg *Package, locals map[string]string) (string, error) {
-	var code string
+func compileExpression(e Expression, pkg *Package, locals map[string]string) (ast.Expr, error) {
+	var expr ast.Expr
 	var err error
 	switch e := e.(type) {
 	case Operation:
-		code, err = compileOperation(e, pkg, locals)
+		expr, err = compileOperation(e, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	case FunctionCall:
-		code, err = compileFunctionCall(e, pkg, locals)
+		expr, err = compileFunctionCall(e, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	case Token:
 		switch e.Type {
 		case IdentifierWord:
 			name := e.Content
 			if _, ok := locals[name]; ok {
-				code = name
+				expr = ast.NewIdent(name)
 			} else if v, ok := pkg.Globals[name]; ok {
 				if v.Pkg == pkg {
-					code = "G_" + name
+					expr = ast.NewIdent("G_" + name)
 				}
-			} else if v, ok := pkg.Funcs[name]; ok {
-				if v.Pkg == pkg {
-					code = name
-				}
-				code = name
+			} else if _, ok := pkg.Funcs[name]; ok {
+				expr = ast.NewIdent(name)
 			} else {
-				return "", msg(e.LineNumber, e.Column, "Name is undefined: "+e.Content)
+				return nil, msg(e.LineNumber, e.Column, "P0201", "Name is undefined: "+e.Content+"."+
+					didYouMean(name, localNames(locals), pkg.topLevelNames()))
 			}
 		case NumberLiteral:
-			code = "float64(" + e.Content + ")"
+			kind := token.INT
+			if strings.Contains(e.Content, ".") {
+				kind = token.FLOAT
+			}
+			var lit ast.Expr = &ast.BasicLit{Kind: kind, Value: strings.TrimPrefix(e.Content, "-")}
+			if strings.HasPrefix(e.Content, "-") {
+				lit = &ast.UnaryExpr{Op: token.SUB, X: lit}
+			}
+			expr = call("float64", lit)
 		case StringLiteral:
-			code = e.Content
+			expr = &ast.BasicLit{Kind: token.STRING, Value: e.Content}
 		case BooleanLiteral:
-			code = "interface{}(" + e.Content + ")"
+			expr = toAny(ast.NewIdent(e.Content))
 		case NilLiteral:
-			code = "_std.Nil(0)"
+			expr = call("_std.Nil", &ast.BasicLit{Kind: token.INT, Value: "0"})
 		}
 	}
-	return code, nil
+	return expr, nil
 }
 
-func compileGlobals(pkg *Package) (string, error) {
-	code := ""
-	for _, g := range pkg.Globals {
+func compileGlobals(pkg *Package) ([]ast.Decl, error) {
+	decls := []ast.Decl{}
+	// an error stops the compilation of a global but not of the others
+	var errs Diagnostics
+	for _, g := range pkg.globalsInOrder() {
 		if g.Pkg != pkg {
 			continue
 		}
-		code += "var G_" + g.Name + " interface{} = "
-		c, err := compileExpression(g.Value, pkg, map[string]string{})
+		val, err := compileExpression(g.Value, pkg, map[string]string{})
 		if err != nil {
-			return "", err
+			errs = errs.add(err)
+			continue
 		}
-		code += c + "\n"
-		pkg.ValidBreakpoints[strconv.Itoa(g.LineNumber)] = true
+		decl := varDecl("G_"+g.Name, anyType(), val)
+		pkg.mapLine(decl, g.LineNumber)
+		decls = append(decls, decl)
 	}
-	return code, nil
+	return decls, errs.err()
 }
 
-// returns code snippet ending with '\n\n'
-func compileFunc(fn FunctionDefinition) (string, error) {
+func compileFunc(fn FunctionDefinition) (*ast.FuncDecl, error) {
 	locals := map[string]string{}
-	header := "func " + strings.Title(fn.Name) + "(_params ...interface{}) interface{} { \n"
-	header += "if len(_params) != " + strconv.Itoa(len(fn.Parameters)) + ` {
-	_log.Fatalln("Call to function ` + fn.Name + ` has the wrong number of arguments.")
-	}
-	`
+	decl := &ast.FuncDecl{
+		Name: ast.NewIdent(strings.Title(fn.Name)),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{
+				{Names: []*ast.Ident{ast.NewIdent("_params")}, Type: &ast.Ellipsis{Elt: anyType()}},
+			}},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: anyType()}}},
+		},
+		Body: block(&ast.IfStmt{
+			Cond: &ast.BinaryExpr{
+				X:  call("len", ast.NewIdent("_params")),
+				Op: token.NEQ,
+				Y:  &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(fn.Parameters))},
+			},
+			Body: block(callStmt("_log.Fatalln", stringLit("Call to function "+fn.Name+" has the wrong number of arguments."))),
+		}),
+	}
+	fn.Pkg.mapLine(decl, fn.LineNumber)
+	body := decl.Body
 	for i, param := range fn.Parameters {
-		header += "var " + param + " interface{} = _params[" + strconv.Itoa(i) + "]\n"
+		val := &ast.IndexExpr{X: ast.NewIdent("_params"), Index: &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(i)}}
+		body.List = append(body.List,
+			&ast.DeclStmt{Decl: varDecl(param, anyType(), val)},
+			callStmt("_std.NullOp", ast.NewIdent(param)),
+		)
 		locals[param] = param
 	}
+	returnNil := &ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("nil")}}
+	if fn.BodyInvalid {
+		body.List = append(body.List, returnNil)
+		return decl, nil
+	}
 	if len(fn.Body) < 1 {
-		return "", msg(fn.LineNumber, fn.Column, "Function should contain at least one statement.")
+		return nil, msg(fn.LineNumber, fn.Column, "P0110", "Function should contain at least one statement.")
 	}
 	bodyStatements := fn.Body
 	// account for locals statement
 	if localsStatement, ok := bodyStatements[0].(LocalsStatement); ok {
+		first := len(body.List)
 		for _, v := range localsStatement.Vars {
-			header += "var "
 			if _, ok := locals[v]; ok {
-				return "", msg(localsStatement.LineNumber, localsStatement.Column, "Local variable "+v+" is already defined as a parameter.")
+				return nil, msg(localsStatement.LineNumber, localsStatement.Column, "P0202", "Local variable "+v+" is already defined as a parameter.")
 			}
 			locals[v] = v
-			header += v + " interface{}\n"
-			header += "_std.NullOp(" + v + ")\n"
+			body.List = append(body.List,
+				&ast.DeclStmt{Decl: varDecl(v, anyType(), nil)},
+				callStmt("_std.NullOp", ast.NewIdent(v)),
+			)
+		}
+		if first < len(body.List) {
+			fn.Pkg.mapLine(body.List[first], localsStatement.LineNumber)
 		}
 		bodyStatements = bodyStatements[1:]
 	}
-	header += genDebugFn(locals, fn.Pkg.Globals, fn.Pkg)
-	body, err := compileBody(bodyStatements, fn.Pkg, locals, false)
+	if fn.Pkg.Debug {
+		name := fn.Name
+		if name == "_main" {
+			name = "main"
+		}
+		stmts, err := parseStmts(genDebugFn(locals, fn.Pkg.Globals, fn.Pkg)+genDebugEnter(name), fn.LineNumber, fn.Column)
+		if err != nil {
+			return nil, err
+		}
+		body.List = append(body.List, stmts...)
+	}
+	stmts, err := compileBody(bodyStatements, fn.Pkg, locals, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return header + body + "\nreturn nil\n}\n", nil
+	body.List = append(body.List, stmts...)
+	body.List = append(body.List, returnNil)
+	return decl, nil
 }
 
+// returns code declaring _locals, a func returning the current values of the local variables,
+// and _debug, a func which reports a stop at a line to the debugger
 func genDebugFn(locals map[string]string, globals map[string]GlobalDefinition, pkg *Package) string {
-	s := `debug := func(line int) {
-	var globals = map[string]interface{}{
+	s := `_locals := func() map[string]interface{} {
+	return map[string]interface{}{
 `
-	for k := range globals {
-		s += fmt.Sprintf("\"%s\": G_%s,\n", k, k)
+	localNames := []string{}
+	for k := range locals {
+		localNames = append(localNames, k)
+	}
+	sort.Strings(localNames)
+	for _, k := range localNames {
+		s += fmt.Sprintf("\"%s\": %s,\n", k, k)
 	}
 	s += `}
-	var locals = map[string]interface{}{
+}
+_debug := func(line int) {
+	var globals = map[string]interface{}{
 `
-	for k := range locals {
-		s += fmt.Sprintf("\"%s\": %s,\n", k, k)
+	globalNames := []string{}
+	for k := range globals {
+		globalNames = append(globalNames, k)
+	}
+	sort.Strings(globalNames)
+	for _, k := range globalNames {
+		s += fmt.Sprintf("\"%s\": G_%s,\n", k, k)
 	}
 	s += `}
-	_fmt.Println(globals, locals)
-	//_p.PollContinue(line, globals, locals)
+	_std.PollContinue(line, globals, _locals())
 }
+_std.NullOp(_debug)
 `
 	return s
 }
 
-func compileIfStatement(s IfStatement, pkg *Package, locals map[string]string, insideLoop bool) (string, error) {
+// returns code which registers a call of the named function with the debugger
+func genDebugEnter(name string) string {
+	return "_std.Enter(\"" + name + "\", _locals)\ndefer _std.Exit()\n"
+}
+
+func compileIfStatement(s IfStatement, pkg *Package, locals map[string]string, loops []*enclosingLoop) (ast.Stmt, error) {
 	c, err := compileExpression(s.Condition, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	code := `
-	{
-		_cond, _ok := (` + c + `).(bool)
-		if !_ok {
-			_log.Fatalln("If condition must be a boolean.")
-		}
-		if _cond {
-	`
-	c, err = compileBody(s.Body, pkg, locals, insideLoop)
+	body, err := compileBody(s.Body, pkg, locals, loops)
 	if err != nil {
-		return "", nil
+		return nil, err
 	}
-	code += c
+	outer := block(condition(c, "If condition must be a boolean.")...)
+	ifStmt := &ast.IfStmt{Cond: ast.NewIdent("_cond"), Body: block(body...)}
+	outer.List = append(outer.List, ifStmt)
 	for _, elif := range s.Elifs {
 		c, err := compileExpression(elif.Condition, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-
-		code += `
-		} else {
-			_cond, _ok := (` + c + `).(bool)
-			if !_ok {
-				_log.Fatalln("Elif condition must be a boolean.")
-			}
-			if _cond {
-		`
-		c, err = compileBody(elif.Body, pkg, locals, insideLoop)
+		body, err := compileBody(elif.Body, pkg, locals, loops)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += c
+		els := block(condition(c, "Elif condition must be a boolean.")...)
+		elifStmt := &ast.IfStmt{Cond: ast.NewIdent("_cond"), Body: block(body...)}
+		els.List = append(els.List, elifStmt)
+		ifStmt.Else = els
+		ifStmt = elifStmt
 	}
 	if len(s.Else.Body) > 0 {
-		code += `
-		} else {	
-		`
-		c, err := compileBody(s.Else.Body, pkg, locals, insideLoop)
+		body, err := compileBody(s.Else.Body, pkg, locals, loops)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += c
+		ifStmt.Else = block(body...)
+	}
+	return outer, nil
+}
+
+// A switch is compiled as an if-else chain (so a break or continue in a case applies to the
+// enclosing loop), in which each value of a case is compared to the switch value with _std.Eq.
+func compileSwitchStatement(s SwitchStatement, pkg *Package, locals map[string]string, loops []*enclosingLoop) (ast.Stmt, error) {
+	val, err := compileExpression(s.Value, pkg, locals)
+	if err != nil {
+		return nil, err
 	}
-	for i := 0; i < 2+len(s.Elifs); i++ {
-		code += `
+	stmt := block(define("_switch", val))
+	if len(s.Cases) == 0 {
+		stmt.List = append(stmt.List, callStmt("_std.NullOp", ast.NewIdent("_switch")))
+	}
+	var last *ast.IfStmt
+	for _, c := range s.Cases {
+		var cond ast.Expr
+		for _, v := range c.Values {
+			expr, err := compileExpression(v, pkg, locals)
+			if err != nil {
+				return nil, err
 			}
-		`
+			var eq ast.Expr = &ast.TypeAssertExpr{X: call("_std.Eq", ast.NewIdent("_switch"), expr), Type: ast.NewIdent("bool")}
+			if cond != nil {
+				eq = &ast.BinaryExpr{X: cond, Op: token.LOR, Y: eq}
+			}
+			cond = eq
+		}
+		body, err := compileBody(c.Body, pkg, locals, loops)
+		if err != nil {
+			return nil, err
+		}
+		next := &ast.IfStmt{Cond: cond, Body: block(body...)}
+		if last == nil {
+			stmt.List = append(stmt.List, next)
+		} else {
+			last.Else = next
+		}
+		last = next
+	}
+	if len(s.Default) > 0 {
+		body, err := compileBody(s.Default, pkg, locals, loops)
+		if err != nil {
+			return nil, err
+		}
+		if last == nil {
+			stmt.List = append(stmt.List, block(body...))
+		} else {
+			last.Else = block(body...)
+		}
 	}
-	return code, nil
+	return stmt, nil
 }
 
-func compileWhileStatement(s WhileStatement, pkg *Package, locals map[string]string) (string, error) {
+func compileWhileStatement(s WhileStatement, pkg *Package, locals map[string]string, loops []*enclosingLoop) (ast.Stmt, error) {
 	c, err := compileExpression(s.Condition, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	code := `
-	for {
-		_cond, _ok := (` + c + `).(bool)
-		if !_ok {
-			_log.Fatalln("While loop condition must be a boolean.")
-		}
-		if !_cond {
-			break
-		}
-	`
-	c, err = compileBody(s.Body, pkg, locals, true)
+	l, err := newLoop(s.Label, s.LineNumber, s.Column, loops)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	body, err := compileBody(s.Body, pkg, locals, append(loops, l))
+	if err != nil {
+		return nil, err
+	}
+	loop := block(condition(c, "While loop condition must be a boolean.")...)
+	loop.List = append(loop.List, &ast.IfStmt{
+		Cond: &ast.UnaryExpr{Op: token.NOT, X: ast.NewIdent("_cond")},
+		Body: block(&ast.BranchStmt{Tok: token.BREAK}),
+	})
+	loop.List = append(loop.List, body...)
+	return labelLoop(l, &ast.ForStmt{Body: loop}), nil
+}
+
+// returns statements which assign the value (asserted to be a float64) to the variable, panicking
+// with the message if the value isn't a float64
+func number(name string, val ast.Expr, message string) []ast.Stmt {
+	return []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(name), ast.NewIdent("_ok")},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.TypeAssertExpr{X: &ast.ParenExpr{X: toAny(val)}, Type: ast.NewIdent("float64")}},
+		},
+		&ast.IfStmt{
+			Cond: &ast.UnaryExpr{Op: token.NOT, X: ast.NewIdent("_ok")},
+			Body: block(callStmt("panic", stringLit(message))),
+		},
 	}
-	return code + c + "\n}\n", nil
 }
 
-func compileForincStatement(s ForincStatement, pkg *Package, locals map[string]string) (string, error) {
+func compileForincStatement(s ForincStatement, pkg *Package, locals map[string]string, loops []*enclosingLoop) (ast.Stmt, error) {
 	if _, ok := locals[s.IndexName]; ok {
-		return "", msg(s.LineNumber, s.Column, "forinc index name conflicts with an existing local variable.")
+		return nil, msg(s.LineNumber, s.Column, "P0202", "forinc index name conflicts with an existing local variable.")
 	}
 	newLocals := map[string]string{}
 	for k, v := range locals {
@@ -257,62 +381,57 @@ func compileForincStatement(s ForincStatement, pkg *Package, locals map[string]s
 	newLocals[s.IndexName] = s.IndexName
 	startExpr, err := compileExpression(s.StartVal, pkg, newLocals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	endExpr, err := compileExpression(s.EndVal, pkg, newLocals)
 	if err != nil {
-		return "", err
-	}
-	code := `{
-	_start, _ok := (interface{}(` + startExpr + `)).(float64)
-	if !_ok {
-		panic("Forinc/fordec start value is not a number.")
-	}
-	_end, _ok := (interface{}(` + endExpr + `)).(float64)
-	if !_ok {
-		panic("Forinc/fordec end value is not a number.")
+		return nil, err
 	}
-	`
+	stmts := number("_start", startExpr, "Forinc/fordec start value is not a number.")
+	stmts = append(stmts, number("_end", endExpr, "Forinc/fordec end value is not a number.")...)
+	cmp, inc := token.LSS, token.INC
 	if s.Dec {
-		code += "_start-- \n"
+		stmts = append(stmts, &ast.IncDecStmt{X: ast.NewIdent("_start"), Tok: token.DEC})
+		cmp, inc = token.GEQ, token.DEC
 	}
-
-	code += `for ` + s.IndexName + ` := _start; ` + s.IndexName
-	if s.Dec {
-		code += " >= "
-	} else {
-		code += " < "
+	loop := &ast.ForStmt{
+		Init: define(s.IndexName, ast.NewIdent("_start")),
+		Cond: &ast.BinaryExpr{X: ast.NewIdent(s.IndexName), Op: cmp, Y: ast.NewIdent("_end")},
+		Post: &ast.IncDecStmt{X: ast.NewIdent(s.IndexName), Tok: inc},
+		Body: block(),
 	}
-	code += " _end; " + s.IndexName
-	if s.Dec {
-		code += "--"
-	} else {
-		code += "++"
+	if pkg.Debug {
+		debug, err := parseStmts(genDebugFn(newLocals, pkg.Globals, pkg), s.LineNumber, s.Column)
+		if err != nil {
+			return nil, err
+		}
+		loop.Body.List = append(loop.Body.List, debug...)
 	}
-	code += " { \n"
-
-	//code += genDebugFn(newLocals, pkg.Globals, pkg)
-	body, err := compileBody(s.Body, pkg, newLocals, true)
+	l, err := newLoop(s.Label, s.LineNumber, s.Column, loops)
+	if err != nil {
+		return nil, err
+	}
+	body, err := compileBody(s.Body, pkg, newLocals, append(loops, l))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	code += body + "}\n}\n"
-	return code, nil
+	loop.Body.List = append(loop.Body.List, body...)
+	return block(append(stmts, labelLoop(l, loop))...), nil
 }
 
-func compileForeachStatement(s ForeachStatement, pkg *Package, locals map[string]string) (string, error) {
+func compileForeachStatement(s ForeachStatement, pkg *Package, locals map[string]string, loops []*enclosingLoop) (ast.Stmt, error) {
 	if _, ok := locals[s.IndexName]; ok {
-		return "", msg(s.LineNumber, s.Column, "foreach index name conflicts with an existing local variable.")
+		return nil, msg(s.LineNumber, s.Column, "P0202", "foreach index name conflicts with an existing local variable.")
 	}
 	if _, ok := locals[s.ValName]; ok {
-		return "", msg(s.LineNumber, s.Column, "foreach val name conflicts with an existing local variable.")
+		return nil, msg(s.LineNumber, s.Column, "P0202", "foreach val name conflicts with an existing local variable.")
 	}
 	if s.IndexName == s.ValName {
-		return "", msg(s.LineNumber, s.Column, "foreach index name conflicts with val name.")
+		return nil, msg(s.LineNumber, s.Column, "P0202", "foreach index name conflicts with val name.")
 	}
 	collExpr, err := compileExpression(s.Collection, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	newLocals := map[string]string{}
 	for k, v := range locals {
@@ -321,195 +440,318 @@ func compileForeachStatement(s ForeachStatement, pkg *Package, locals map[string
 	newLocals[s.IndexName] = s.IndexName
 	newLocals[s.ValName] = s.ValName
 
-	body, err := compileBody(s.Body, pkg, newLocals, true)
+	// the body is compiled once for each case (each case needs its own nodes, and its own label)
+	listLoop, err := newLoop(s.Label, s.LineNumber, s.Column, loops)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	code := `
-		switch _c := ` + collExpr + `.(type) {
-		case _std.ListType:
-			for _i, _v := range *_c.List {
-`
-	code += s.IndexName + " := interface{}(float64(_i))\n"
-	code += s.ValName + " := interface{}(_v)\n"
-	code += "_std.NullOp(" + s.IndexName + ")\n"
-	code += "_std.NullOp(" + s.ValName + ")\n"
-	code += body
-	code += `
+	mapLoop := &enclosingLoop{label: listLoop.label, goLabel: listLoop.goLabel + "_map"}
+	loopBody := func(idx ast.Expr, l *enclosingLoop) (*ast.BlockStmt, error) {
+		loop := block(
+			define(s.IndexName, toAny(idx)),
+			define(s.ValName, toAny(ast.NewIdent("_v"))),
+			callStmt("_std.NullOp", ast.NewIdent(s.IndexName)),
+			callStmt("_std.NullOp", ast.NewIdent(s.ValName)),
+		)
+		if pkg.Debug {
+			debug, err := parseStmts(genDebugFn(newLocals, pkg.Globals, pkg), s.LineNumber, s.Column)
+			if err != nil {
+				return nil, err
 			}
-		case _std.MapType:
-			for _k, _v := range _c {
-`
-	code += s.IndexName + " := interface{}(_k)\n"
-	code += s.ValName + " := interface{}(_v)\n"
-	code += "_std.NullOp(" + s.IndexName + ")\n"
-	code += "_std.NullOp(" + s.ValName + ")\n"
-	code += body
-	code += `
-			}
-		default:
-			_log.Fatalln("Foreach collection must be a list or map.")
+			loop.List = append(loop.List, debug...)
+		}
+		body, err := compileBody(s.Body, pkg, newLocals, append(loops, l))
+		if err != nil {
+			return nil, err
 		}
-	
-	`
+		loop.List = append(loop.List, body...)
+		return loop, nil
+	}
+	listBody, err := loopBody(call("float64", ast.NewIdent("_i")), listLoop)
+	if err != nil {
+		return nil, err
+	}
+	mapBody, err := loopBody(ast.NewIdent("_k"), mapLoop)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.TypeSwitchStmt{
+		Assign: define("_c", &ast.TypeAssertExpr{X: collExpr}),
+		Body: block(
+			&ast.CaseClause{List: []ast.Expr{ident("_std.ListType")}, Body: []ast.Stmt{labelLoop(listLoop, &ast.RangeStmt{
+				Key:   ast.NewIdent("_i"),
+				Value: ast.NewIdent("_v"),
+				Tok:   token.DEFINE,
+				X:     &ast.StarExpr{X: ident("_c.List")},
+				Body:  listBody,
+			})}},
+			&ast.CaseClause{List: []ast.Expr{ident("_std.MapType")}, Body: []ast.Stmt{labelLoop(mapLoop, &ast.RangeStmt{
+				Key:   ast.NewIdent("_k"),
+				Value: ast.NewIdent("_v"),
+				Tok:   token.DEFINE,
+				X:     ast.NewIdent("_c"),
+				Body:  mapBody,
+			})}},
+			&ast.CaseClause{Body: []ast.Stmt{callStmt("_log.Fatalln", stringLit("Foreach collection must be a list or map."))}},
+		),
+	}, nil
+}
+
+// A loop enclosing the statements being compiled, which a break or continue can target
+type enclosingLoop struct {
+	label    string // the label of the loop in the source ("" if none)
+	goLabel  string // the label of the generated loop
+	targeted bool   // a break or continue targets the loop by its label
+}
+
+// returns the enclosingLoop of a loop statement, whose label can't be that of a loop enclosing it
+func newLoop(label string, line int, column int, loops []*enclosingLoop) (*enclosingLoop, error) {
+	for _, l := range loops {
+		if label != "" && l.label == label {
+			return nil, msg(line, column, "P0202", "Loop label "+label+" is already the label of an enclosing loop.")
+		}
+	}
+	// (two loops of a function with the same label would have the same Go label without the line)
+	return &enclosingLoop{label: label, goLabel: "_" + label + "_" + strconv.Itoa(line)}, nil
+}
 
-	return code, nil
+// returns the generated loop, labeled if a break or continue targets it (Go doesn't allow unused labels)
+func labelLoop(loop *enclosingLoop, stmt ast.Stmt) ast.Stmt {
+	if !loop.targeted {
+		return stmt
+	}
+	return &ast.LabeledStmt{Label: ast.NewIdent(loop.goLabel), Stmt: stmt}
 }
 
-func compileBody(statements []Statement, pkg *Package, locals map[string]string, insideLoop bool) (string, error) {
-	var code string
+// returns a break or continue (tok) of the innermost loop, or of the enclosing loop with the label
+func compileBranch(tok token.Token, label string, line int, column int, loops []*enclosingLoop) (ast.Stmt, error) {
+	if len(loops) == 0 {
+		return nil, msg(line, column, "P0113", "cannot have "+tok.String()+" statement outside a loop.")
+	}
+	if label == "" {
+		return &ast.BranchStmt{Tok: tok}, nil
+	}
+	for i := len(loops) - 1; i >= 0; i-- {
+		if loops[i].label == label {
+			loops[i].targeted = true
+			return &ast.BranchStmt{Tok: tok, Label: ast.NewIdent(loops[i].goLabel)}, nil
+		}
+	}
+	return nil, msg(line, column, "P0113", "cannot "+tok.String()+" "+label+": no enclosing loop has the label "+label+".")
+}
+
+func compileBody(statements []Statement, pkg *Package, locals map[string]string, loops []*enclosingLoop) ([]ast.Stmt, error) {
+	stmts := []ast.Stmt{}
 	for _, s := range statements {
 		line := s.Line()
 		lineStr := strconv.Itoa(line)
 		pkg.ValidBreakpoints[lineStr] = true
-		code += fmt.Sprintf("if _std.Breakpoints[%d] {debug(%d)}\n", line, line)
-		var c string
+		first := len(stmts)
+		if pkg.Debug {
+			debug, err := parseStmts(fmt.Sprintf("if _std.Break(%d) {_debug(%d)}\n", line, line), line, 0)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, debug...)
+		}
+		var stmt ast.Stmt
 		var err error
 		switch s := s.(type) {
 		case IfStatement:
-			c, err = compileIfStatement(s, pkg, locals, insideLoop)
+			stmt, err = compileIfStatement(s, pkg, locals, loops)
+		case SwitchStatement:
+			stmt, err = compileSwitchStatement(s, pkg, locals, loops)
 		case WhileStatement:
-			c, err = compileWhileStatement(s, pkg, locals)
+			stmt, err = compileWhileStatement(s, pkg, locals, loops)
 		case ForeachStatement:
-			c, err = compileForeachStatement(s, pkg, locals)
+			stmt, err = compileForeachStatement(s, pkg, locals, loops)
 		case ForincStatement:
-			c, err = compileForincStatement(s, pkg, locals)
+			stmt, err = compileForincStatement(s, pkg, locals, loops)
 		case AssignmentStatement:
-			c, err = compileAssignmentStatement(s, pkg, locals)
+			stmt, err = compileAssignmentStatement(s, pkg, locals)
 		case ReturnStatement:
-			c, err = compileReturnStatement(s, pkg, locals)
+			stmt, err = compileReturnStatement(s, pkg, locals)
 		case BreakStatement:
-			if insideLoop {
-				c += "break \n"
-			} else {
-				err = msg(s.LineNumber, s.Column, "cannot have break statement outside a loop.")
-			}
+			stmt, err = compileBranch(token.BREAK, s.Label, s.LineNumber, s.Column, loops)
 		case ContinueStatement:
-			if insideLoop {
-				c += "continue \n"
-			} else {
-				err = msg(s.LineNumber, s.Column, "cannot have continue statement outside a loop.")
-			}
+			stmt, err = compileBranch(token.CONTINUE, s.Label, s.LineNumber, s.Column, loops)
 		case FunctionCall:
+			var c ast.Expr
 			c, err = compileFunctionCall(s, pkg, locals)
-			c += "\n"
+			stmt = &ast.ExprStmt{X: c}
 		case Operation:
 			if s.Operator != "set" && s.Operator != "print" && s.Operator != "println" &&
 				s.Operator != "prompt" && s.Operator != "push" {
-				return "", msg(s.LineNumber, s.Column, "Improper operation as statement. Only set, push, print, println, "+
+				return nil, msg(s.LineNumber, s.Column, "P0310", "Improper operation as statement. Only set, push, print, println, "+
 					"and prompt can be standalone statements.")
 			}
+			var c ast.Expr
 			c, err = compileOperation(s, pkg, locals)
-			c += "\n"
+			stmt = &ast.ExprStmt{X: c}
 		}
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += c
+		stmts = append(stmts, stmt)
+		pkg.mapLine(stmts[first], line)
 	}
-	return code, nil
+	return stmts, nil
 }
 
-func compileAssignmentStatement(s AssignmentStatement, pkg *Package, locals map[string]string) (string, error) {
-	valCode, err := compileExpression(s.Value, pkg, locals)
+func compileAssignmentStatement(s AssignmentStatement, pkg *Package, locals map[string]string) (ast.Stmt, error) {
+	val, err := compileExpression(s.Value, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return s.Target + " = " + valCode + "\n", nil
+	return &ast.AssignStmt{Lhs: []ast.Expr{ast.NewIdent(s.Target)}, Tok: token.ASSIGN, Rhs: []ast.Expr{val}}, nil
 }
 
-func compileReturnStatement(s ReturnStatement, pkg *Package, locals map[string]string) (string, error) {
-	c, err := compileExpression(s.Value, pkg, locals)
+func compileReturnStatement(s ReturnStatement, pkg *Package, locals map[string]string) (ast.Stmt, error) {
+	val, err := compileExpression(s.Value, pkg, locals)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return "return " + c + "\n", nil
+	return &ast.ReturnStmt{Results: []ast.Expr{val}}, nil
 }
 
-func compileFunctionCall(s FunctionCall, pkg *Package, locals map[string]string) (string, error) {
-	code := ""
+func compileFunctionCall(s FunctionCall, pkg *Package, locals map[string]string) (ast.Expr, error) {
+	c := &ast.CallExpr{}
 	switch s := s.Function.(type) {
 	case Operation:
-		c, err := compileOperation(s, pkg, locals)
+		fn, err := compileOperation(s, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += c
+		c.Fun = fn
 	case FunctionCall:
-		c, err := compileFunctionCall(s, pkg, locals)
+		fn, err := compileFunctionCall(s, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += c
+		c.Fun = fn
 	case Token: // will always be an identifier
 		if _, ok := locals[s.Content]; ok {
-			code += s.Content
+			c.Fun = ast.NewIdent(s.Content)
 		} else {
 			// previous check means we don't have to check for zero val
 			if _, ok := pkg.Funcs[s.Content]; !ok {
-				return "", msg(s.LineNumber, s.Column,
-					"calling non-existent function: "+s.Content)
+				return nil, msg(s.LineNumber, s.Column, "P0201", "calling non-existent function: "+s.Content+"."+
+					didYouMean(s.Content, localNames(locals), pkg.topLevelNames(), operators))
 			}
-			code += strings.Title(s.Content)
+			c.Fun = ast.NewIdent(strings.Title(s.Content))
 		}
 	}
-	code += "(" // start of arguments
 	for _, exp := range s.Arguments {
-		c, err := compileExpression(exp, pkg, locals)
+		arg, err := compileExpression(exp, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		code += c + ", " // Go is OK with comma after last arg, so don't need special case for last arg
+		c.Args = append(c.Args, arg)
 	}
-	if len(s.Arguments) > 0 {
-		code = code[:len(code)-2] // drop last comma and space
-	}
-	return code + ")", nil
+	return c, nil
 }
 
-func compileOperation(o Operation, pkg *Package, locals map[string]string) (string, error) {
-	operandCode := make([]string, len(o.Operands))
+func compileOperation(o Operation, pkg *Package, locals map[string]string) (ast.Expr, error) {
+	operands := make([]ast.Expr, len(o.Operands))
 	for i, expr := range o.Operands {
 		c, err := compileExpression(expr, pkg, locals)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		operandCode[i] = c
+		operands[i] = c
 	}
-	code := "(_std." + strings.Title(o.Operator) + "("
-	for _, expr := range o.Operands {
-		c, err := compileExpression(expr, pkg, locals)
-		if err != nil {
-			return "", err
-		}
-		code += c + ", "
+	return call("_std."+strings.Title(o.Operator), operands...), nil
+}
+
+// Compile compiles the Pigeon source file into a Go program (Package.Code).
+// If debug is true, the program is instrumented for the debugger (see stdlib/debug.go).
+// If compilation fails, the package is nil, and the diagnostics report every error found.
+func Compile(filename string, outputDir string, debug bool) (*Package, []Diagnostic) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fileDiagnostics(err, filename, nil)
 	}
-	return code + "))", nil
+	result, diags := CompileSource(filename, data, Options{OutputDir: outputDir, Debug: debug})
+	if result == nil {
+		return nil, diags
+	}
+	return result.Package, diags
 }
 
-func Compile(filename string, outputDir string) (*Package, error) {
-	path, err := filepath.Abs(filename)
+// Options configure a compilation by CompileSource. (A Pigeon program is a single file,
+// so unlike GoPigeon, there are no imports to read.)
+type Options struct {
+	// OutputDir is the import path prefix of the generated packages: the path of the module
+	// in which the program is built, with a trailing slash (e.g. "pigeon_output/").
+	OutputDir string
+	// Debug instruments the program for the debugger (see stdlib/debug.go).
+	Debug bool
+}
+
+// A Result is a compiled program.
+type Result struct {
+	Code    string   // the generated Go program
+	Package *Package // the compiled definitions (e.g. for editor tooling)
+}
+
+// CompileSource compiles the source of the named file into a Go program without reading the file.
+// If compilation fails, the result is nil, and the diagnostics report every error found.
+func CompileSource(name string, src []byte, opts Options) (*Result, []Diagnostic) {
+	path, err := filepath.Abs(name)
 	if err != nil {
-		return nil, err
+		return nil, fileDiagnostics(err, name, src)
 	}
-	data, err := ioutil.ReadFile(path)
+	pkg := newPackage(path, opts.Debug)
+	err = compileSource(pkg, src, opts.OutputDir)
 	if err != nil {
-		return nil, err
+		return nil, fileDiagnostics(err, name, src)
 	}
-	tokens, err := lex(string(data) + "\r\n")
+	return &Result{Code: pkg.Code, Package: pkg}, nil
+}
+
+// Analyze compiles the source of the named file (without reading the file) for editor tooling.
+// The package is returned even if compilation fails.
+func Analyze(filename string, src []byte) (*Package, []Diagnostic) {
+	path, err := filepath.Abs(filename)
 	if err != nil {
-		return nil, err
+		return nil, fileDiagnostics(err, filename, src)
 	}
+	pkg := newPackage(path, false)
+	return pkg, fileDiagnostics(compileSource(pkg, src, ""), filename, src)
+}
 
-	pkg := &Package{
+func newPackage(path string, debug bool) *Package {
+	return &Package{
+		FullPath:         path,
+		Debug:            debug,
 		Globals:          map[string]GlobalDefinition{},
 		ValidBreakpoints: map[string]bool{},
 		Funcs:            map[string]FunctionDefinition{},
 	}
+}
+
+func compileSource(pkg *Package, src []byte, outputDir string) error {
+	// lex, parse, and compile errors are all reported together
+	var errs Diagnostics
+	tokens, err := lex(string(src) + "\r\n")
+	if err != nil {
+		errs = errs.add(err)
+		if tokens == nil {
+			return errs.err()
+		}
+	}
 	definitions, err := parse(tokens, pkg)
 	if err != nil {
-		return nil, err
+		// a line with a lex error likely also has a parse error, but only the lex error is reported
+		lexErrorLines := map[int]bool{}
+		for _, e := range errs {
+			lexErrorLines[e.Start.Line] = true
+		}
+		for _, e := range (Diagnostics{}).add(err) {
+			if !lexErrorLines[e.Start.Line] {
+				errs = append(errs, e)
+			}
+		}
 	}
 
 	packageNames := map[string]bool{}
@@ -518,24 +760,30 @@ func Compile(filename string, outputDir string) (*Package, error) {
 		case GlobalDefinition:
 			un := strings.ToUpper(d.Name)
 			if packageNames[un] {
-				return nil, msg(d.LineNumber, d.Column, "Duplicate top-level name: "+d.Name)
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate top-level name: "+d.Name))
+				continue
 			}
 			pkg.Globals[d.Name] = d
 			packageNames[un] = true
 		case FunctionDefinition:
 			un := strings.ToUpper(d.Name)
 			if packageNames[un] {
-				return nil, msg(d.LineNumber, d.Column, "Duplicate top-level name: "+d.Name)
+				errs = errs.add(msg(d.LineNumber, d.Column, "P0202", "Duplicate top-level name: "+d.Name))
+				continue
 			}
 			pkg.Funcs[d.Name] = d
 			packageNames[un] = true
 		default:
-			return nil, errors.New("Unrecognized definition")
+			return errors.New("Unrecognized definition")
 		}
 	}
+	// (err is that of the parse: a main which fails to parse is not missing)
+	if _, ok := pkg.Funcs["_main"]; !ok && err == nil {
+		errs = errs.add(msg(1, 1, "P0208", "Missing function main: a program starts by calling its main function."))
+	}
 	err = compile(pkg, outputDir)
 	if err != nil {
-		return nil, err
+		errs = errs.add(err)
 	}
-	return pkg, nil
+	return errs.err()
 }
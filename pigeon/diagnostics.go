@@ -0,0 +1,149 @@
+package pigeon
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Severity is the seriousness of a Diagnostic: only errors prevent compilation.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// A Position is a one-based line and column of the source (Line is 0 if the position is unknown).
+type Position struct {
+	Line   int
+	Column int
+}
+
+// A Diagnostic is a problem found in a source file by the compiler.
+type Diagnostic struct {
+	File     string
+	Start    Position
+	End      Position // just past the offending code, on the same line as Start
+	Severity Severity
+	Code     string // stable identifier of the kind of problem, e.g. "P0302" (empty if not about the source)
+	Message  string
+}
+
+func (d Diagnostic) Error() string {
+	return "Line " + strconv.Itoa(d.Start.Line) + ", column " +
+		strconv.Itoa(d.Start.Column) + ": " + d.Message
+}
+
+func msg(line int, column int, code string, s string) error {
+	return Diagnostic{Start: Position{line, column}, Severity: SeverityError, Code: code, Message: s}
+}
+
+// Diagnostics is the problems found in one compilation.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	strs := make([]string, len(ds))
+	for i, d := range ds {
+		strs[i] = d.Error()
+	}
+	return strings.Join(strs, "\n")
+}
+
+// returns the list with the error added (or, if err is a Diagnostics, each of its diagnostics)
+func (ds Diagnostics) add(err error) Diagnostics {
+	switch e := err.(type) {
+	case Diagnostics:
+		return append(ds, e...)
+	case Diagnostic:
+		return append(ds, e)
+	}
+	return append(ds, Diagnostic{Severity: SeverityError, Message: err.Error()})
+}
+
+// returns the list sorted by position, or nil if the list is empty
+func (ds Diagnostics) err() error {
+	if len(ds) == 0 {
+		return nil
+	}
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].Start.Line != ds[j].Start.Line {
+			return ds[i].Start.Line < ds[j].Start.Line
+		}
+		return ds[i].Start.Column < ds[j].Start.Column
+	})
+	return ds
+}
+
+// returns the diagnostics of err (which may be nil), each attributed to the file and
+// given an end position which spans the offending word, literal, or parenthesized expression
+func fileDiagnostics(err error, filename string, src []byte) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+	ds := Diagnostics{}.add(err)
+	lines := strings.Split(string(src), "\n")
+	for i := range ds {
+		d := &ds[i]
+		d.File = filename
+		d.End = d.Start
+		if d.Start.Line < 1 || d.Start.Line > len(lines) {
+			continue
+		}
+		line := []rune(strings.TrimRight(lines[d.Start.Line-1], "\r"))
+		if d.Start.Column < 1 || d.Start.Column > len(line) {
+			continue
+		}
+		d.End.Column = d.Start.Column + extent(line[d.Start.Column-1:])
+	}
+	return ds
+}
+
+// returns the number of characters of the word, string literal, or parenthesized expression
+// at the start of the text (1 if the text starts with anything else)
+func extent(text []rune) int {
+	switch text[0] {
+	case '(':
+		depth := 0
+		for i, r := range text {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(text)
+	case '"':
+		for i := 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+		return len(text)
+	}
+	n := 0
+	for n < len(text) && isWordRune(text[n]) {
+		n++
+	}
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// returns an error located at the start of the expression
+func exprMsg(e Expression, code string, s string) error {
+	line, column := position(e)
+	return msg(line, column, code, s)
+}
@@ -0,0 +1,233 @@
+package pigeon
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The generated program is built as a go/ast syntax tree, which go/printer formats.
+//
+// Each declaration and statement is mapped to its line of the source (see mapLine), and the
+// printed code is given a //line directive before each of them (so that panics and Go compiler
+// errors report source lines instead of lines of the generated code). A declaration with no source
+// line gets a directive back to its own line of the generated file.
+
+// the name of the file to which the driver writes the generated code
+// (every package of the program is written to a file of this name in its own directory)
+const outputFile = "output.go"
+
+// records the source line of a generated declaration or statement
+func (p *Package) mapLine(node ast.Node, line int) {
+	if p.lines == nil {
+		p.lines = map[ast.Node]int{}
+	}
+	p.lines[node] = line
+}
+
+// formats the generated file as Go source (as gofmt would), with the //line directives
+func (p *Package) print(file *ast.File) (string, error) {
+	fset := token.NewFileSet()
+	fset.AddFile("", -1, 1) // holds parsedPos
+	sortImports(file)
+	var buf bytes.Buffer
+	err := (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&buf, fset, file)
+	if err != nil {
+		return "", internalError(0, 0, err)
+	}
+	// the nodes have no positions, so the printed lines of the mapped nodes are found by parsing
+	// the printed code, whose declarations and statements are those of the generated tree (the printer
+	// changes only expressions, e.g. dropping the parens around the condition of an if or for)
+	printed, err := parser.ParseFile(fset, "", buf.Bytes(), 0)
+	if err != nil {
+		return "", internalError(0, 0, err)
+	}
+	generatedNodes, printedNodes := mappableNodes(file), mappableNodes(printed)
+	if len(generatedNodes) != len(printedNodes) {
+		return "", internalError(0, 0, errors.New("the printed code doesn't match the syntax tree"))
+	}
+	inserts := map[int]string{} // printed line number: text to insert before the line
+	firstMapped := 0            // the printed line of the first mapped node (0 if none)
+	for i, n := range generatedNodes {
+		if line, ok := p.lines[n]; ok {
+			printedLine := fset.Position(printedNodes[i].Pos()).Line
+			inserts[printedLine] = "//line " + filepath.Base(p.FullPath) + ":" + strconv.Itoa(line) + "\n"
+			if firstMapped == 0 || printedLine < firstMapped {
+				firstMapped = printedLine
+			}
+		}
+	}
+	// a declaration with no source line (e.g. the generated main) which follows mapped code
+	// gets its own lines back, rather than the source line of the code before it
+	resets := map[int]bool{} // printed line number: the line is preceded by a reset directive
+	for i, d := range file.Decls {
+		printedLine := fset.Position(printed.Decls[i].Pos()).Line
+		if _, ok := p.lines[d]; !ok && firstMapped != 0 && printedLine > firstMapped {
+			resets[printedLine] = true
+		}
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	// the printer separates declarations by blank lines only where their positions are far apart
+	for _, d := range printed.Decls[1:] {
+		printedLine := fset.Position(d.Pos()).Line
+		if lines[printedLine-2] != "\n" {
+			inserts[printedLine] = "\n" + inserts[printedLine]
+		}
+	}
+	var code strings.Builder
+	for i, line := range lines {
+		code.WriteString(inserts[i+1])
+		if resets[i+1] {
+			// (the line after the directive is the directive's line + 1)
+			outputLine := strings.Count(code.String(), "\n") + 2
+			code.WriteString("//line " + outputFile + ":" + strconv.Itoa(outputLine) + ":1\n")
+		}
+		code.WriteString(line)
+	}
+	return code.String(), nil
+}
+
+// sorts the imports by path, as gofmt does (ast.SortImports needs the positions of the imports)
+func sortImports(file *ast.File) {
+	for _, d := range file.Decls {
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			sort.SliceStable(d.Specs, func(i, j int) bool {
+				return d.Specs[i].(*ast.ImportSpec).Path.Value < d.Specs[j].(*ast.ImportSpec).Path.Value
+			})
+		}
+	}
+}
+
+// returns the declarations, specs, and statements of the tree (the nodes which can be mapped to
+// source lines) in the order visited by ast.Inspect
+func mappableNodes(node ast.Node) []ast.Node {
+	nodes := []ast.Node{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n.(type) {
+		case ast.Decl, ast.Spec, ast.Stmt:
+			nodes = append(nodes, n)
+		}
+		return true
+	})
+	return nodes
+}
+
+// returns an error for generated code which isn't valid Go (a bug in the compiler, not in the source)
+func internalError(line int, column int, err error) error {
+	return Diagnostic{Start: Position{line, column}, Severity: SeverityError,
+		Message: "Internal compiler error (the generated Go code is malformed): " + err.Error()}
+}
+
+// parses the generated code of a list of statements
+func parseStmts(code string, line int, column int) ([]ast.Stmt, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\nfunc _() {\n"+code+"\n}\n", 0)
+	if err != nil {
+		return nil, internalError(line, column, err)
+	}
+	body := f.Decls[0].(*ast.FuncDecl).Body
+	clearPositions(body)
+	return body.List, nil
+}
+
+var posType = reflect.TypeOf(token.NoPos)
+
+// The position of every node of parsed code, which is on line 1 of the first file of the file set
+// in which the generated code is printed. (The printer lays out nodes without positions as it
+// would if each token were on its own line, e.g. printing an interface{} as an empty block.)
+const parsedPos = token.Pos(1)
+
+// parsed code is positioned in its own file set, so its positions are replaced before it joins the tree
+func clearPositions(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		v := reflect.ValueOf(n)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return true
+		}
+		v = v.Elem()
+		for i := 0; i < v.NumField(); i++ {
+			// (the validity of some positions is significant, e.g. CallExpr.Ellipsis)
+			if f := v.Field(i); f.Type() == posType && f.Int() != int64(token.NoPos) {
+				f.SetInt(int64(parsedPos))
+			}
+		}
+		return true
+	})
+}
+
+// returns the identifier, or for a name of the form "x.y", the selector expression
+func ident(name string) ast.Expr {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return &ast.SelectorExpr{X: ident(name[:i]), Sel: ast.NewIdent(name[i+1:])}
+	}
+	return ast.NewIdent(name)
+}
+
+func call(fn string, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{Fun: ident(fn), Args: args}
+}
+
+func callStmt(fn string, args ...ast.Expr) ast.Stmt {
+	return &ast.ExprStmt{X: call(fn, args...)}
+}
+
+func importSpec(name string, path string) ast.Spec {
+	return &ast.ImportSpec{Name: ast.NewIdent(name), Path: stringLit(path)}
+}
+
+func stringLit(s string) *ast.BasicLit {
+	return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(s)}
+}
+
+// returns interface{}
+func anyType() ast.Expr {
+	return &ast.InterfaceType{Methods: &ast.FieldList{Opening: parsedPos, Closing: parsedPos}}
+}
+
+// returns the conversion of the expression to interface{}
+func toAny(e ast.Expr) ast.Expr {
+	return &ast.CallExpr{Fun: anyType(), Args: []ast.Expr{e}}
+}
+
+// returns a declaration of the variable (with no value if val is nil)
+func varDecl(name string, t ast.Expr, val ast.Expr) *ast.GenDecl {
+	spec := &ast.ValueSpec{Names: []*ast.Ident{ast.NewIdent(name)}, Type: t}
+	if val != nil {
+		spec.Values = []ast.Expr{val}
+	}
+	return &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{spec}}
+}
+
+func define(name string, val ast.Expr) ast.Stmt {
+	return &ast.AssignStmt{Lhs: []ast.Expr{ast.NewIdent(name)}, Tok: token.DEFINE, Rhs: []ast.Expr{val}}
+}
+
+func block(stmts ...ast.Stmt) *ast.BlockStmt {
+	return &ast.BlockStmt{List: stmts}
+}
+
+// returns statements which assign the value (asserted to be a bool) to _cond, calling _log.Fatalln with
+// the message if the value isn't a bool
+func condition(val ast.Expr, message string) []ast.Stmt {
+	return []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent("_cond"), ast.NewIdent("_ok")},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.TypeAssertExpr{X: &ast.ParenExpr{X: val}, Type: ast.NewIdent("bool")}},
+		},
+		&ast.IfStmt{
+			Cond: &ast.UnaryExpr{Op: token.NOT, X: ast.NewIdent("_ok")},
+			Body: block(callStmt("_log.Fatalln", stringLit(message))),
+		},
+	}
+}
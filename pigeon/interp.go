@@ -0,0 +1,321 @@
+package pigeon
+
+import (
+	"strconv"
+
+	"github.com/BrianWill/pigeon/pigeon/stdlib"
+)
+
+// the operators implemented by the runtime, which the interpreter shares with compiled programs
+var operations = map[string]func(...interface{}) interface{}{
+	"add":      stdlib.Add,
+	"sub":      stdlib.Sub,
+	"mul":      stdlib.Mul,
+	"div":      stdlib.Div,
+	"inc":      stdlib.Inc,
+	"dec":      stdlib.Dec,
+	"mod":      stdlib.Mod,
+	"eq":       stdlib.Eq,
+	"neq":      stdlib.Neq,
+	"not":      stdlib.Not,
+	"lt":       stdlib.Lt,
+	"gt":       stdlib.Gt,
+	"lte":      stdlib.Lte,
+	"gte":      stdlib.Gte,
+	"get":      stdlib.Get,
+	"set":      stdlib.Set,
+	"list":     stdlib.List,
+	"map":      stdlib.Map,
+	"push":     stdlib.Push,
+	"or":       stdlib.Or,
+	"and":      stdlib.And,
+	"print":    stdlib.Print,
+	"println":  stdlib.Println,
+	"prompt":   stdlib.Prompt,
+	"concat":   stdlib.Concat,
+	"lconcat":  stdlib.Lconcat,
+	"len":      stdlib.Len,
+	"floor":    stdlib.Floor,
+	"randNum":  stdlib.RandNum,
+	"getchar":  stdlib.Getchar,
+	"charlist": stdlib.Charlist,
+}
+
+// how control leaves a statement
+type control int
+
+const (
+	next control = iota // on to the following statement
+	breakLoop
+	continueLoop
+	returnFunc
+)
+
+// reports whether a break or continue (whose value is its label) targets the loop with the label
+func targets(val interface{}, label string) bool {
+	l := val.(string)
+	return l == "" || l == label
+}
+
+type interpreter struct {
+	pkg     *Package
+	globals map[string]interface{}
+	// globals whose initializers are being evaluated
+	initializing map[string]bool
+}
+
+// Interpret runs the program of a successfully compiled package by walking its syntax tree,
+// so the program runs without the Go toolchain. The program behaves as the compiled Go program does:
+// the operators are those of the runtime package (stdlib), and runtime errors end the process.
+func Interpret(pkg *Package) {
+	interp := &interpreter{
+		pkg:          pkg,
+		globals:      map[string]interface{}{},
+		initializing: map[string]bool{},
+	}
+	// the Go program initializes its globals in dependency order;
+	// evaluating each global when first needed does the same
+	for _, g := range pkg.globalsInOrder() {
+		interp.global(g.Name)
+	}
+	stdlib.Println("")
+	interp.call(pkg.Funcs["_main"], nil)
+}
+
+// returns the value of the global, evaluating its initializer if it hasn't been yet
+func (interp *interpreter) global(name string) interface{} {
+	if v, ok := interp.globals[name]; ok {
+		return v
+	}
+	if interp.initializing[name] {
+		stdlib.Fatalln("Initialization of global " + name + " refers to itself.")
+	}
+	interp.initializing[name] = true
+	v := interp.eval(interp.pkg.Globals[name].Value, map[string]interface{}{})
+	delete(interp.initializing, name)
+	interp.globals[name] = v
+	return v
+}
+
+func (interp *interpreter) call(fn FunctionDefinition, args []interface{}) interface{} {
+	if len(args) != len(fn.Parameters) {
+		stdlib.Fatalln("Call to function " + fn.Name + " has the wrong number of arguments.")
+	}
+	locals := map[string]interface{}{}
+	for i, param := range fn.Parameters {
+		locals[param] = args[i]
+	}
+	body := fn.Body
+	if localsStatement, ok := body[0].(LocalsStatement); ok {
+		for _, v := range localsStatement.Vars {
+			locals[v] = nil
+		}
+		body = body[1:]
+	}
+	_, val := interp.execBody(body, locals)
+	return val
+}
+
+// executes the statements until one breaks, continues, or returns.
+// The value is that of the return statement, or the label of the break or continue ("" if none).
+func (interp *interpreter) execBody(statements []Statement, locals map[string]interface{}) (control, interface{}) {
+	for _, s := range statements {
+		ctl, val := interp.exec(s, locals)
+		if ctl != next {
+			return ctl, val
+		}
+	}
+	return next, nil
+}
+
+func (interp *interpreter) exec(s Statement, locals map[string]interface{}) (control, interface{}) {
+	switch s := s.(type) {
+	case IfStatement:
+		if interp.condition(s.Condition, locals, "If condition must be a boolean.") {
+			return interp.execBody(s.Body, locals)
+		}
+		for _, elif := range s.Elifs {
+			if interp.condition(elif.Condition, locals, "Elif condition must be a boolean.") {
+				return interp.execBody(elif.Body, locals)
+			}
+		}
+		return interp.execBody(s.Else.Body, locals)
+	case SwitchStatement:
+		val := interp.eval(s.Value, locals)
+		for _, c := range s.Cases {
+			for _, v := range c.Values {
+				if stdlib.Eq(val, interp.eval(v, locals)).(bool) {
+					return interp.execBody(c.Body, locals)
+				}
+			}
+		}
+		return interp.execBody(s.Default, locals)
+	case WhileStatement:
+		for interp.condition(s.Condition, locals, "While loop condition must be a boolean.") {
+			ctl, val := interp.execBody(s.Body, locals)
+			if ctl == returnFunc || (ctl != next && !targets(val, s.Label)) {
+				return ctl, val
+			}
+			if ctl == breakLoop {
+				break
+			}
+		}
+	case ForincStatement:
+		return interp.execForinc(s, locals)
+	case ForeachStatement:
+		return interp.execForeach(s, locals)
+	case AssignmentStatement:
+		val := interp.eval(s.Value, locals)
+		if _, ok := locals[s.Target]; ok {
+			locals[s.Target] = val
+		} else {
+			interp.globals[s.Target] = val
+		}
+	case ReturnStatement:
+		return returnFunc, interp.eval(s.Value, locals)
+	case BreakStatement:
+		return breakLoop, s.Label
+	case ContinueStatement:
+		return continueLoop, s.Label
+	case FunctionCall:
+		interp.eval(s, locals)
+	case Operation:
+		interp.eval(s, locals)
+	}
+	return next, nil
+}
+
+// evaluates the condition of an if, elif, or while, ending the program with the message if it isn't a boolean
+func (interp *interpreter) condition(e Expression, locals map[string]interface{}, message string) bool {
+	cond, ok := interp.eval(e, locals).(bool)
+	if !ok {
+		stdlib.Fatalln(message)
+	}
+	return cond
+}
+
+func (interp *interpreter) execForinc(s ForincStatement, locals map[string]interface{}) (control, interface{}) {
+	start, ok := interp.eval(s.StartVal, locals).(float64)
+	if !ok {
+		panic("Forinc/fordec start value is not a number.")
+	}
+	end, ok := interp.eval(s.EndVal, locals).(float64)
+	if !ok {
+		panic("Forinc/fordec end value is not a number.")
+	}
+	if s.Dec {
+		start--
+	}
+	defer delete(locals, s.IndexName)
+	for i := start; (s.Dec && i >= end) || (!s.Dec && i < end); {
+		locals[s.IndexName] = i
+		ctl, val := interp.execBody(s.Body, locals)
+		if ctl == returnFunc || (ctl != next && !targets(val, s.Label)) {
+			return ctl, val
+		}
+		if ctl == breakLoop {
+			break
+		}
+		// the body may assign the index
+		i, ok = locals[s.IndexName].(float64)
+		if !ok {
+			stdlib.Fatalln("Forinc/fordec index assigned a non-number.")
+		}
+		if s.Dec {
+			i--
+		} else {
+			i++
+		}
+	}
+	return next, nil
+}
+
+func (interp *interpreter) execForeach(s ForeachStatement, locals map[string]interface{}) (control, interface{}) {
+	defer delete(locals, s.IndexName)
+	defer delete(locals, s.ValName)
+	iteration := func(key interface{}, val interface{}) (control, interface{}) {
+		locals[s.IndexName] = key
+		locals[s.ValName] = val
+		return interp.execBody(s.Body, locals)
+	}
+	switch c := interp.eval(s.Collection, locals).(type) {
+	case stdlib.ListType:
+		for i, v := range *c.List {
+			ctl, val := iteration(float64(i), v)
+			if ctl == returnFunc || (ctl != next && !targets(val, s.Label)) {
+				return ctl, val
+			}
+			if ctl == breakLoop {
+				break
+			}
+		}
+	case stdlib.MapType:
+		for k, v := range c {
+			ctl, val := iteration(k, v)
+			if ctl == returnFunc || (ctl != next && !targets(val, s.Label)) {
+				return ctl, val
+			}
+			if ctl == breakLoop {
+				break
+			}
+		}
+	default:
+		stdlib.Fatalln("Foreach collection must be a list or map.")
+	}
+	return next, nil
+}
+
+func (interp *interpreter) eval(e Expression, locals map[string]interface{}) interface{} {
+	switch e := e.(type) {
+	case Operation:
+		op, ok := operations[e.Operator]
+		if !ok {
+			stdlib.Fatalln("Line " + strconv.Itoa(e.LineNumber) + ": the " + e.Operator + " operation is not implemented.")
+		}
+		operands := make([]interface{}, len(e.Operands))
+		for i, operand := range e.Operands {
+			operands[i] = interp.eval(operand, locals)
+		}
+		return op(operands...)
+	case FunctionCall:
+		name, ok := e.Function.(Token)
+		fn, isFunc := interp.pkg.Funcs[name.Content]
+		if _, isLocal := locals[name.Content]; !ok || isLocal || !isFunc {
+			line, _ := position(e.Function)
+			stdlib.Fatalln("Line " + strconv.Itoa(line) + ": only functions can be called.")
+		}
+		args := make([]interface{}, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			args[i] = interp.eval(arg, locals)
+		}
+		return interp.call(fn, args)
+	case Token:
+		switch e.Type {
+		case IdentifierWord:
+			if v, ok := locals[e.Content]; ok {
+				return v
+			}
+			if _, ok := interp.pkg.Globals[e.Content]; ok {
+				return interp.global(e.Content)
+			}
+			stdlib.Fatalln("Line " + strconv.Itoa(e.LineNumber) + ": functions cannot be used as values.")
+		case NumberLiteral:
+			f, err := strconv.ParseFloat(e.Content, 64)
+			if err != nil {
+				stdlib.Fatalln("Line " + strconv.Itoa(e.LineNumber) + ": invalid number " + e.Content + ".")
+			}
+			return f
+		case StringLiteral:
+			s, err := strconv.Unquote(e.Content)
+			if err != nil {
+				stdlib.Fatalln("Line " + strconv.Itoa(e.LineNumber) + ": invalid string " + e.Content + ".")
+			}
+			return s
+		case BooleanLiteral:
+			return e.Content == "true"
+		case NilLiteral:
+			return stdlib.Nil(0)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package pigeon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the longest a program may run (including, when compiled, the Go build)
+const interpTimeout = 2 * time.Minute
+
+// the date and time with which the log package prefixes messages
+var logTimestamp = regexp.MustCompile(`^[0-9]{4}/[0-9]{2}/[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2} `)
+
+// the observable behavior of a program run
+type runResult struct {
+	Stdout string
+	Status int
+	Error  string // first line of the error output
+}
+
+// Each program in examples behaves identically run with 'pigeon run' and with 'pigeon run -interp':
+// the runs have the same output, exit status, and first line of error output (ignoring the
+// timestamps of log messages, and the stack traces of panics, which differ between the two).
+//
+// A program's input is read from the file of the same name with the extension .input
+// (the program gets no input if there is no such file). The programs must be deterministic.
+func TestInterpreterMatchesCompiled(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command is not installed")
+	}
+	files, err := filepath.Glob(filepath.Join("examples", "*.pigeon"))
+	if err != nil || len(files) == 0 {
+		t.Fatal("No example programs found.")
+	}
+	pigeonCmd := filepath.Join(t.TempDir(), "pigeon")
+	output, err := exec.Command("go", "build", "-o", pigeonCmd, "..").CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to build the pigeon command:\n%s", output)
+	}
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			input, err := ioutil.ReadFile(strings.TrimSuffix(file, ".pigeon") + ".input")
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			compiled, err := runPigeon(input, pigeonCmd, "run", file)
+			if err != nil {
+				t.Fatalf("compiled: %v", err)
+			}
+			interpreted, err := runPigeon(input, pigeonCmd, "run", "-interp", file)
+			if err != nil {
+				t.Fatalf("interpreted: %v", err)
+			}
+			if compiled != interpreted {
+				t.Errorf("runs differ\ncompiled:    %+v\ninterpreted: %+v", compiled, interpreted)
+			}
+		})
+	}
+}
+
+// runs the command with the input, returning an error only if the command couldn't run to completion
+func runPigeon(input []byte, name string, args ...string) (runResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), interpTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return runResult{}, fmt.Errorf("timed out after %v", interpTimeout)
+	}
+	r := runResult{Stdout: stdout.String()}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		r.Status = exitErr.ExitCode()
+	} else if err != nil {
+		return runResult{}, err
+	}
+	r.Error = strings.SplitN(stderr.String(), "\n", 2)[0]
+	r.Error = logTimestamp.ReplaceAllString(r.Error, "")
+	return r, nil
+}
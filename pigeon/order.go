@@ -0,0 +1,35 @@
+package pigeon
+
+import "sort"
+
+// The definitions of a package are kept in maps, which iterate in a different order every time.
+// The compiler instead visits them in source order, so that compiling the same source
+// always generates the same code (and reports the same error first).
+
+func (p *Package) funcsInOrder() []FunctionDefinition {
+	funcs := []FunctionDefinition{}
+	for _, fn := range p.Funcs {
+		funcs = append(funcs, fn)
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		if funcs[i].LineNumber != funcs[j].LineNumber {
+			return funcs[i].LineNumber < funcs[j].LineNumber
+		}
+		return funcs[i].Name < funcs[j].Name
+	})
+	return funcs
+}
+
+func (p *Package) globalsInOrder() []GlobalDefinition {
+	globals := []GlobalDefinition{}
+	for _, g := range p.Globals {
+		globals = append(globals, g)
+	}
+	sort.Slice(globals, func(i, j int) bool {
+		if globals[i].LineNumber != globals[j].LineNumber {
+			return globals[i].LineNumber < globals[j].LineNumber
+		}
+		return globals[i].Name < globals[j].Name
+	})
+	return globals
+}
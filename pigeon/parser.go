@@ -1,7 +1,6 @@
 package pigeon
 
 import (
-	"fmt"
 	"strings"
 )
 
@@ -18,15 +17,19 @@ func isNumeral(r rune) bool {
 // assumes the string ends with a newline (because that makes it a bit easier to lex)
 func lex(text string) ([]Token, error) {
 	var tokens []Token
+	var errs Diagnostics // on an error, the rest of the line is skipped
 
 	line := 1
 	column := 1
 	runes := []rune(text) // to account for unicode properly, we need to iterate through runes, not bytes
 
+Outer:
 	for i := 0; i < len(runes); {
 		r := runes[i]
 		if r >= 128 {
-			return nil, msg(line, column, "File improperly contains a non-ASCII character.")
+			errs = errs.add(msg(line, column, "P0001", "File improperly contains a non-ASCII character."))
+			i = endOfLine(runes, i)
+			continue Outer
 		}
 		if r == '\n' {
 			tokens = append(tokens, Token{Newline, "\n", line, column})
@@ -35,7 +38,8 @@ func lex(text string) ([]Token, error) {
 			i++
 		} else if r == '\r' {
 			if runes[i+1] != '\n' {
-				return nil, msg(line, column, "Improper newline: expecting LF (linefeed) after CR (carriage return).")
+				errs = errs.add(msg(line, column, "P0003", "Improper newline: expecting LF (linefeed) after CR (carriage return)."))
+				i-- // treat the lone CR as a newline
 			}
 			tokens = append(tokens, Token{Newline, "\n", line, column})
 			line++
@@ -43,7 +47,9 @@ func lex(text string) ([]Token, error) {
 			i += 2
 		} else if r == '/' { // start of a comment
 			if runes[i+1] != '/' {
-				return nil, msg(line, column, "Expected second / (slash).")
+				errs = errs.add(msg(line, column, "P0004", "Expected second / (slash)."))
+				i = endOfLine(runes, i)
+				continue Outer
 			}
 			for runes[i] != '\n' && runes[i] != '\r' {
 				i++
@@ -97,7 +103,9 @@ func lex(text string) ([]Token, error) {
 			}
 			tokens = append(tokens, Token{tokenType, string(runes[firstIdx:i]), line, column})
 		} else if r == '\t' {
-			return nil, msg(line, column, "File improperly contains a tab character.")
+			errs = errs.add(msg(line, column, "P0002", "File improperly contains a tab character."))
+			i = endOfLine(runes, i)
+			continue Outer
 		} else if r == '"' { // start of a string
 			prev := r
 			endIdx := i + 1
@@ -105,7 +113,9 @@ func lex(text string) ([]Token, error) {
 				current := runes[endIdx]
 				// loop will never run past end of runes because \n appended to end of file
 				if current == '\n' || current == '\r' {
-					return nil, msg(line, column, "String literal not closed.")
+					errs = errs.add(msg(line, column, "P0005", "String literal not closed."))
+					i = endOfLine(runes, i)
+					continue Outer
 				}
 				if current == '"' && prev != '\\' { // end of the string
 					endIdx++
@@ -129,17 +139,23 @@ func lex(text string) ([]Token, error) {
 					break
 				} else if current == '.' {
 					if decimalPointIdx != -1 {
-						return nil, msg(line, column, "Number literal has more than one decimal point.")
+						errs = errs.add(msg(line, column, "P0006", "Number literal has more than one decimal point."))
+						i = endOfLine(runes, i)
+						continue Outer
 					}
 					decimalPointIdx = endIdx
 				} else if !isNumeral(current) {
-					return nil, msg(line, column, "Number literal not properly formed.")
+					errs = errs.add(msg(line, column, "P0006", "Number literal not properly formed."))
+					i = endOfLine(runes, i)
+					continue Outer
 				}
 				endIdx++
 			}
 
 			if decimalPointIdx == endIdx {
-				return nil, msg(line, column, "Number literal should not end with decimal point.")
+				errs = errs.add(msg(line, column, "P0006", "Number literal should not end with decimal point."))
+				i = endOfLine(runes, i)
+				continue Outer
 			}
 
 			tokens = append(tokens, Token{NumberLiteral, string(runes[i:endIdx]), line, column})
@@ -154,7 +170,9 @@ func lex(text string) ([]Token, error) {
 				if strings.Contains(" \r\n).", string(current)) {
 					break
 				} else if !(isAlpha(current) || isNumeral(current)) {
-					return nil, msg(line, column, "Word improperly formed.")
+					errs = errs.add(msg(line, column, "P0007", "Word improperly formed."))
+					i = endOfLine(runes, i)
+					continue Outer
 				}
 				endIdx++
 			}
@@ -193,7 +211,9 @@ func lex(text string) ([]Token, error) {
 			column += (endIdx - i)
 			i = endIdx
 		} else {
-			return nil, msg(line, column, "Unexpected character "+string(r)+".")
+			errs = errs.add(msg(line, column, "P0008", "Unexpected character "+string(r)+"."))
+			i = endOfLine(runes, i)
+			continue Outer
 		}
 	}
 
@@ -233,14 +253,19 @@ func lex(text string) ([]Token, error) {
 	tokens = filteredTokens
 
 	// remove all sequences of [newline -> indentation -> comma], replace with space
-	if tokens[0].Type == Comma || tokens[1].Type == Comma {
-		return nil, msg(line, column, "Unexpected comma at start of file.")
+	if tokens[0].Type == Comma || (len(tokens) > 1 && tokens[1].Type == Comma) {
+		return nil, errs.add(msg(tokens[0].LineNumber, tokens[0].Column, "P0009", "Unexpected comma at start of file."))
 	}
-	if tokens[len(tokens)-2].Type == Comma || tokens[len(tokens)-1].Type == Comma {
-		return nil, msg(line, column, "Unexpected comma at end of file.")
+	if tokens[len(tokens)-1].Type == Comma || (len(tokens) > 1 && tokens[len(tokens)-2].Type == Comma) {
+		return nil, errs.add(msg(line, column, "P0009", "Unexpected comma at end of file."))
 	}
 	filteredTokens = []Token{}
-	for i := 0; i < len(tokens)-2; {
+	// the last two tokens can't start the pattern
+	tail := len(tokens) - 2
+	if tail < 0 {
+		tail = 0
+	}
+	for i := 0; i < tail; {
 		// commas should only be encountered in this pattern
 		if tokens[i].Type == Newline && tokens[i+1].Type == Indentation && tokens[i+2].Type == Comma {
 			tokens[i].Type = Space
@@ -250,20 +275,29 @@ func lex(text string) ([]Token, error) {
 			continue
 		}
 		if tokens[i].Type == Comma {
-			return nil, msg(line, column, "Unexpected comma.")
+			errs = errs.add(msg(tokens[i].LineNumber, tokens[i].Column, "P0009", "Unexpected comma."))
+			i++
+			continue
 		}
 		filteredTokens = append(filteredTokens, tokens[i])
 		i++
 	}
-	if len(tokens) > 2 {
-		filteredTokens = append(filteredTokens, tokens[len(tokens)-2:]...)
+	filteredTokens = append(filteredTokens, tokens[tail:]...)
+	return filteredTokens, errs.err()
+}
+
+// returns the index of the newline which ends the line containing runes[i]
+func endOfLine(runes []rune, i int) int {
+	for i < len(runes) && runes[i] != '\n' && runes[i] != '\r' {
+		i++
 	}
-	return filteredTokens, nil
+	return i
 }
 
 // parse the top-level definitions
 func parse(tokens []Token, pkg *Package) ([]Definition, error) {
 	var definitions []Definition
+	var errs Diagnostics // after an error, parsing resumes at the next top-level definition
 	for i := 0; i < len(tokens); {
 		t := tokens[i]
 		line := t.LineNumber
@@ -278,10 +312,15 @@ func parse(tokens []Token, pkg *Package) ([]Definition, error) {
 			case "global":
 				definition, numTokens, err = parseGlobal(tokens[i:], line, pkg)
 			default:
-				return nil, msg(t.LineNumber, t.Column, "Improper reserved word at top level of code.")
+				err = msg(t.LineNumber, t.Column, "P0104", "Improper reserved word at top level of code.")
 			}
 			if err != nil {
-				return nil, err
+				errs = errs.add(err)
+				if numTokens == 0 {
+					i = nextDefinition(tokens, i)
+					continue
+				}
+				// a function or method whose body failed to parse is kept for its signature
 			}
 			definitions = append(definitions, definition)
 			i += numTokens
@@ -293,29 +332,46 @@ func parse(tokens []Token, pkg *Package) ([]Definition, error) {
 			// (don't need to check if (i + 1) in bounds because we know token stream always
 			// ends with newline and so this indentation token can't be last)
 			if tokens[i+1].Type != Newline {
-				return nil, msg(t.LineNumber, t.Column, "Improper indentation at top level of code.")
+				errs = errs.add(msg(t.LineNumber, t.Column, "P0103", "Improper indentation at top level of code."))
 			}
+			i = nextDefinition(tokens, i)
 		default:
-			return nil, msg(t.LineNumber, t.Column, "Improper token at top level of code.")
+			errs = errs.add(msg(t.LineNumber, t.Column, "P0104", "Improper token at top level of code."))
+			i = nextDefinition(tokens, i)
 		}
 	}
-	return definitions, nil
+	return definitions, errs.err()
+}
+
+// returns the index of the first token of the next top-level definition after tokens[i]
+// (or len(tokens) if there are no more definitions)
+func nextDefinition(tokens []Token, i int) int {
+	for i++; i < len(tokens); i++ {
+		if tokens[i-1].Type == Newline && tokens[i].Type == ReservedWord {
+			for _, word := range definitionWords {
+				if tokens[i].Content == word {
+					return i
+				}
+			}
+		}
+	}
+	return i
 }
 
 func parseGlobal(tokens []Token, line int, pkg *Package) (GlobalDefinition, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return GlobalDefinition{}, 0, msg(line, column, "Expected space.")
+		return GlobalDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	target := tokens[idx]
 	if target.Type != IdentifierWord {
-		return GlobalDefinition{}, 0, msg(line, column, "Improper name for a global.")
+		return GlobalDefinition{}, 0, msg(target.LineNumber, target.Column, "P0105", "Improper name for a global.")
 	}
 	idx++
 	if tokens[idx].Type != Space {
-		return GlobalDefinition{}, 0, msg(line, column, "Expected space.")
+		return GlobalDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Expected space.")
 	}
 	idx++
 	value, numValueTokens, err := parseExpression(tokens[idx:], line)
@@ -324,7 +380,7 @@ func parseGlobal(tokens []Token, line int, pkg *Package) (GlobalDefinition, int,
 	}
 	idx += numValueTokens
 	if tokens[idx].Type != Newline {
-		return GlobalDefinition{}, 0, msg(line, column, "Global not terminated with newline.")
+		return GlobalDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Global not terminated with newline.")
 	}
 	idx++
 	return GlobalDefinition{line, column, target.Content, value, pkg}, idx, nil
@@ -333,7 +389,7 @@ func parseGlobal(tokens []Token, line int, pkg *Package) (GlobalDefinition, int,
 func parseExpression(tokens []Token, line int) (Expression, int, error) {
 	column := tokens[0].Column
 	if len(tokens) < 1 {
-		return nil, 0, msg(line, column, "Missing expression.")
+		return nil, 0, msg(line, column, "P0104", "Missing expression.")
 	}
 	idx := 0
 	token := tokens[idx]
@@ -351,7 +407,7 @@ func parseExpression(tokens []Token, line int) (Expression, int, error) {
 			return nil, 0, err
 		}
 	default:
-		return nil, 0, msg(line, column, "Improper expression: "+fmt.Sprintf("%#v", token))
+		return nil, 0, msg(token.LineNumber, token.Column, "P0104", "Improper expression: "+token.Content)
 	}
 	return expr, idx, nil
 }
@@ -384,7 +440,7 @@ func parseOpenParen(tokens []Token) (Expression, int, error) {
 		}
 		idx += numTokens
 	default:
-		return nil, 0, msg(line, column, "Improper function call or operation.")
+		return nil, 0, msg(t.LineNumber, t.Column, "P0104", "Improper function call or operation.")
 	}
 
 	var arguments []Expression
@@ -427,7 +483,7 @@ func parseFunction(tokens []Token, line int, pkg *Package) (FunctionDefinition,
 	}
 	name := tokens[idx]
 	if name.Type != IdentifierWord {
-		return FunctionDefinition{}, 0, msg(line, column, "Function missing name.")
+		return FunctionDefinition{}, 0, msg(name.LineNumber, name.Column, "P0105", "Function missing name.")
 	}
 	if name.Content == "main" {
 		name.Content = "_main"
@@ -445,13 +501,11 @@ func parseFunction(tokens []Token, line int, pkg *Package) (FunctionDefinition,
 			idx++
 			break
 		} else {
-			return FunctionDefinition{}, 0, msg(line, column, "Expecting parameter name or end of line.")
+			return FunctionDefinition{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting parameter name or end of line.")
 		}
 	}
+	// if the body fails to parse, the definition is returned with the errors
 	body, nTokens, err := parseBody(tokens[idx:], indentationSpaces)
-	if err != nil {
-		return FunctionDefinition{}, 0, err
-	}
 	idx += nTokens
 	return FunctionDefinition{
 		line, column,
@@ -459,7 +513,8 @@ func parseFunction(tokens []Token, line int, pkg *Package) (FunctionDefinition,
 		params,
 		body,
 		pkg,
-	}, idx, nil
+		err != nil,
+	}, idx, err
 }
 
 func parseIf(tokens []Token, indentation int) (IfStatement, int, error) {
@@ -467,7 +522,7 @@ func parseIf(tokens []Token, indentation int) (IfStatement, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return IfStatement{}, 0, msg(line, column, "Missing space.")
+		return IfStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	condition, numConditionTokens, err := parseExpression(tokens[idx:], line)
@@ -479,7 +534,7 @@ func parseIf(tokens []Token, indentation int) (IfStatement, int, error) {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return IfStatement{}, 0, msg(line, column, "If statement condition not followed by newline.")
+		return IfStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "If statement condition not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -524,19 +579,19 @@ func parseElif(tokens []Token, indentation int) (ElseifClause, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return ElseifClause{}, 0, msg(line, column, "Missing space.")
+		return ElseifClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	condition, numConditionTokens, err := parseExpression(tokens[idx:], line)
 	if err != nil {
-		return ElseifClause{}, 0, msg(line, column, "Improper condition in if statement.")
+		return ElseifClause{}, 0, msg(line, column, "P0306", "Improper condition in if statement.")
 	}
 	idx += numConditionTokens
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return ElseifClause{}, 0, msg(line, column, "Elif clause condition not followed by newline.")
+		return ElseifClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Elif clause condition not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -555,7 +610,7 @@ func parseElse(tokens []Token, indentation int) (ElseClause, int, error) {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return ElseClause{}, 0, msg(line, column, "Else clause not followed by newline.")
+		return ElseClause{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Else clause not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -566,30 +621,125 @@ func parseElse(tokens []Token, indentation int) (ElseClause, int, error) {
 	return ElseClause{line, column, body}, idx, nil
 }
 
+// the cases of a switch are indented like the switch itself
+func parseSwitch(tokens []Token, indentation int) (SwitchStatement, int, error) {
+	line := tokens[0].LineNumber
+	column := tokens[0].Column
+	idx := 1
+	if tokens[idx].Type != Space {
+		return SwitchStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+	}
+	idx++
+	value, n, err := parseExpression(tokens[idx:], line)
+	if err != nil {
+		return SwitchStatement{}, 0, err
+	}
+	idx += n
+	if tokens[idx].Type == Space {
+		idx++
+	}
+	if tokens[idx].Type != Newline {
+		return SwitchStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Switch expected newline.")
+	}
+	idx++
+	var cases []SwitchCase
+	for idx+1 < len(tokens) {
+		if tokens[idx].Type == Indentation &&
+			len(tokens[idx].Content) == indentation &&
+			tokens[idx+1].Content == "case" {
+			idx++
+			c, nTokens, err := parseSwitchCase(tokens[idx:], indentation)
+			if err != nil {
+				return SwitchStatement{}, 0, err
+			}
+			cases = append(cases, c)
+			idx += nTokens
+		} else {
+			break
+		}
+	}
+	var defaultBody []Statement
+	if idx+1 < len(tokens) {
+		if tokens[idx].Type == Indentation &&
+			len(tokens[idx].Content) == indentation &&
+			tokens[idx+1].Content == "default" {
+			idx += 2
+			if tokens[idx].Type == Space {
+				idx++
+			}
+			if tokens[idx].Type != Newline {
+				return SwitchStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Default case not followed by newline.")
+			}
+			idx++
+			var nTokens int
+			defaultBody, nTokens, err = parseBody(tokens[idx:], indentation+indentationSpaces)
+			if err != nil {
+				return SwitchStatement{}, 0, err
+			}
+			idx += nTokens
+		}
+	}
+	return SwitchStatement{line, column, value, cases, defaultBody}, idx, nil
+}
+
+func parseSwitchCase(tokens []Token, indentation int) (SwitchCase, int, error) {
+	line := tokens[0].LineNumber
+	column := tokens[0].Column
+	idx := 1
+	var values []Expression
+	for {
+		if tokens[idx].Type == Space {
+			idx++
+		}
+		if tokens[idx].Type == Newline {
+			break
+		}
+		if tokens[idx-1].Type != Space {
+			return SwitchCase{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
+		}
+		value, n, err := parseExpression(tokens[idx:], line)
+		if err != nil {
+			return SwitchCase{}, 0, err
+		}
+		values = append(values, value)
+		idx += n
+	}
+	if len(values) == 0 {
+		return SwitchCase{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0104", "Switch case has no values.")
+	}
+	idx++
+	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
+	if err != nil {
+		return SwitchCase{}, 0, err
+	}
+	idx += numTokens
+	return SwitchCase{line, column, values, body}, idx, nil
+}
+
 func parseForeach(tokens []Token, indentation int) (ForeachStatement, int, error) {
 	line := tokens[0].LineNumber
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return ForeachStatement{}, 0, msg(line, column, "Missing space.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return ForeachStatement{}, 0, msg(line, column, "Expecting identifier for the indexes in foreach.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting identifier for the indexes in foreach.")
 	}
 	indexName := tokens[idx].Content
 	idx++
 	if tokens[idx].Type != Space {
-		return ForeachStatement{}, 0, msg(line, column, "Missing space.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return ForeachStatement{}, 0, msg(line, column, "Expecting identifier for the values in foreach.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting identifier for the values in foreach.")
 	}
 	valName := tokens[idx].Content
 	idx++
 	if tokens[idx].Type != Space {
-		return ForeachStatement{}, 0, msg(line, column, "Missing space.")
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	collection, nTokens, err := parseExpression(tokens[idx:], line)
@@ -597,8 +747,10 @@ func parseForeach(tokens []Token, indentation int) (ForeachStatement, int, error
 		return ForeachStatement{}, 0, err
 	}
 	idx += nTokens
+	label, nLabel := parseLabel(tokens[idx:])
+	idx += nLabel
 	if tokens[idx].Type != Newline {
-		return ForeachStatement{}, 0, msg(line, column,
+		return ForeachStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102",
 			"Foreach statement collection expression not followed by newline.")
 	}
 	idx++
@@ -610,7 +762,7 @@ func parseForeach(tokens []Token, indentation int) (ForeachStatement, int, error
 	return ForeachStatement{
 		line, column,
 		indexName, valName,
-		collection, body}, idx, nil
+		collection, body, label}, idx, nil
 }
 
 func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement, int, error) {
@@ -618,16 +770,16 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return ForincStatement{}, 0, msg(line, column, "Missing space.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return ForincStatement{}, 0, msg(line, column, "Expecting identifier for the index in forinc.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting identifier for the index in forinc.")
 	}
 	indexName := tokens[idx].Content
 	idx++
 	if tokens[idx].Type != Space {
-		return ForincStatement{}, 0, msg(line, column, "Missing space.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	startExpr, nTokens, err := parseExpression(tokens[idx:], line)
@@ -636,7 +788,7 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 	}
 	idx += nTokens
 	if tokens[idx].Type != Space {
-		return ForincStatement{}, 0, msg(line, column, "Missing space.")
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	endExpr, nTokens, err := parseExpression(tokens[idx:], line)
@@ -644,8 +796,10 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 		return ForincStatement{}, 0, err
 	}
 	idx += nTokens
+	label, nLabel := parseLabel(tokens[idx:])
+	idx += nLabel
 	if tokens[idx].Type != Newline {
-		return ForincStatement{}, 0, msg(line, column,
+		return ForincStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102",
 			"forinc/fordec end expression not followed by newline.")
 	}
 	idx++
@@ -658,7 +812,7 @@ func parseForinc(tokens []Token, indentation int, isDec bool) (ForincStatement,
 		line, column,
 		indexName,
 		startExpr, endExpr,
-		body, isDec}, idx, nil
+		body, isDec, label}, idx, nil
 }
 
 func parseWhile(tokens []Token, indentation int) (WhileStatement, int, error) {
@@ -666,7 +820,7 @@ func parseWhile(tokens []Token, indentation int) (WhileStatement, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return WhileStatement{}, 0, msg(line, column, "Missing space.")
+		return WhileStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	condition, nTokens, err := parseExpression(tokens[idx:], line)
@@ -674,8 +828,10 @@ func parseWhile(tokens []Token, indentation int) (WhileStatement, int, error) {
 		return WhileStatement{}, 0, err
 	}
 	idx += nTokens
+	label, nLabel := parseLabel(tokens[idx:])
+	idx += nLabel
 	if tokens[idx].Type != Newline {
-		return WhileStatement{}, 0, msg(line, column, "While statement condition not followed by newline.")
+		return WhileStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "While statement condition not followed by newline.")
 	}
 	idx++
 	body, numTokens, err := parseBody(tokens[idx:], indentation+indentationSpaces)
@@ -683,7 +839,7 @@ func parseWhile(tokens []Token, indentation int) (WhileStatement, int, error) {
 		return WhileStatement{}, 0, err
 	}
 	idx += numTokens
-	return WhileStatement{line, column, condition, body}, idx, nil
+	return WhileStatement{line, column, condition, body, label}, idx, nil
 }
 
 func parseReturn(tokens []Token) (ReturnStatement, int, error) {
@@ -698,7 +854,7 @@ func parseReturn(tokens []Token) (ReturnStatement, int, error) {
 		return ReturnStatement{line, column, Token{NilLiteral, "nil", line, tokens[idx].Column}}, 3, nil
 	}
 	if tokens[idx].Type != Space {
-		return ReturnStatement{}, 0, msg(line, column, "Missing space.")
+		return ReturnStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	value, nTokens, err := parseExpression(tokens[idx:], line)
@@ -707,7 +863,7 @@ func parseReturn(tokens []Token) (ReturnStatement, int, error) {
 	}
 	idx += nTokens
 	if tokens[idx].Type != Newline {
-		return ReturnStatement{}, 0, msg(line, column, "Missing newline.")
+		return ReturnStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Missing newline.")
 	}
 	idx++
 	return ReturnStatement{line, column, value}, idx, nil
@@ -716,29 +872,40 @@ func parseReturn(tokens []Token) (ReturnStatement, int, error) {
 func parseBreak(tokens []Token) (BreakStatement, int, error) {
 	line := tokens[0].LineNumber
 	column := tokens[0].Column
-	idx := 1
+	label, idx := parseLabel(tokens[1:])
+	idx++
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return BreakStatement{}, 0, msg(line, column, "Break statement not terminated with newline.")
+		return BreakStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Break statement not terminated with newline.")
 	}
 	idx++
-	return BreakStatement{line, column}, idx, nil
+	return BreakStatement{line, column, label}, idx, nil
 }
 
 func parseContinue(tokens []Token) (ContinueStatement, int, error) {
 	line := tokens[0].LineNumber
 	column := tokens[0].Column
-	idx := 1
+	label, idx := parseLabel(tokens[1:])
+	idx++
 	if tokens[idx].Type == Space {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return ContinueStatement{}, 0, msg(line, column, "Continue statement not terminated with newline.")
+		return ContinueStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Continue statement not terminated with newline.")
 	}
 	idx++
-	return ContinueStatement{line, column}, idx, nil
+	return ContinueStatement{line, column, label}, idx, nil
+}
+
+// parses the label (if any) at the end of the line of a loop, break, or continue,
+// returning the label ("" if none) and the number of tokens
+func parseLabel(tokens []Token) (string, int) {
+	if tokens[0].Type == Space && tokens[1].Type == IdentifierWord {
+		return tokens[1].Content, 2
+	}
+	return "", 0
 }
 
 func parseAssignment(tokens []Token) (AssignmentStatement, int, error) {
@@ -746,16 +913,16 @@ func parseAssignment(tokens []Token) (AssignmentStatement, int, error) {
 	column := tokens[0].Column
 	idx := 1
 	if tokens[idx].Type != Space {
-		return AssignmentStatement{}, 0, msg(line, column, "Missing space.")
+		return AssignmentStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	if tokens[idx].Type != IdentifierWord {
-		return AssignmentStatement{}, 0, msg(line, column, "Expecting target of assignment.")
+		return AssignmentStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting target of assignment.")
 	}
 	target := tokens[idx]
 	idx++
 	if tokens[idx].Type != Space {
-		return AssignmentStatement{}, 0, msg(line, column, "Missing space.")
+		return AssignmentStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0101", "Missing space.")
 	}
 	idx++
 	expr, nTokens, err := parseExpression(tokens[idx:], line)
@@ -767,15 +934,13 @@ func parseAssignment(tokens []Token) (AssignmentStatement, int, error) {
 		idx++
 	}
 	if tokens[idx].Type != Newline {
-		return AssignmentStatement{}, 0, msg(line, tokens[0].Column, "Missing newline at end of assignment.")
+		return AssignmentStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0102", "Missing newline at end of assignment.")
 	}
 	idx++
 	return AssignmentStatement{line, column, target.Content, expr}, idx, nil
 }
 
 func parseLocals(tokens []Token) (LocalsStatement, int, error) {
-	line := tokens[0].LineNumber
-	column := tokens[0].Column
 	idx := 1
 	var locals []string
 	for true {
@@ -789,7 +954,7 @@ func parseLocals(tokens []Token) (LocalsStatement, int, error) {
 			idx++
 			break
 		} else {
-			return LocalsStatement{}, 0, msg(line, column, "Expecting local variable name or end of line.")
+			return LocalsStatement{}, 0, msg(tokens[idx].LineNumber, tokens[idx].Column, "P0105", "Expecting local variable name or end of line.")
 		}
 	}
 	return LocalsStatement{tokens[0].LineNumber, tokens[0].Column, locals}, idx, nil
@@ -800,6 +965,7 @@ func parseLocals(tokens []Token) (LocalsStatement, int, error) {
 // May return zero statements if body is empty.
 func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 	var statements []Statement
+	var errs Diagnostics // after an error, parsing resumes at the next statement of this body
 	i := 0
 	for i < len(tokens) {
 		t := tokens[i]
@@ -810,6 +976,7 @@ func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 			if numSpaces < indentation { // gone past end of the body
 				break
 			} else if numSpaces == indentation {
+				start := i
 				i++
 				t = tokens[i]
 				var statement Statement
@@ -819,11 +986,13 @@ func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 				case ReservedWord:
 					switch t.Content {
 					case "func":
-						return nil, 0, msg(t.LineNumber, t.Column, "Functions cannot be nested.")
+						err = msg(t.LineNumber, t.Column, "P0107", "Functions cannot be nested.")
 					case "as":
 						statement, numTokens, err = parseAssignment(tokens[i:])
 					case "if":
 						statement, numTokens, err = parseIf(tokens[i:], indentation)
+					case "switch":
+						statement, numTokens, err = parseSwitch(tokens[i:], indentation)
 					case "while":
 						statement, numTokens, err = parseWhile(tokens[i:], indentation)
 					case "foreach":
@@ -841,32 +1010,57 @@ func parseBody(tokens []Token, indentation int) ([]Statement, int, error) {
 					case "continue":
 						statement, numTokens, err = parseContinue(tokens[i:])
 					default:
-						return nil, 0, msg(t.LineNumber, t.Column, "Improper reserved word '"+t.Content+"' in body.")
-					}
-					if err != nil {
-						return nil, 0, err
+						err = msg(t.LineNumber, t.Column, "P0104", "Improper reserved word '"+t.Content+"' in body.")
 					}
 				case OpenParen:
 					var expression Expression
 					expression, numTokens, err = parseOpenParen(tokens[i:])
 					if err != nil {
-						return nil, 0, err
+						break
 					}
 					statement = expression.(Statement)
 					if tokens[i+numTokens].Type != Newline {
-						return nil, 0, msg(t.LineNumber, tokens[i+numTokens].Column,
+						err = msg(t.LineNumber, tokens[i+numTokens].Column, "P0102",
 							"Expecting newline.")
+						break
 					}
 					numTokens++ // include the newline
 				default:
-					return nil, 0, msg(t.LineNumber, t.Column, "Improper token. Expected start of statement.")
+					err = msg(t.LineNumber, t.Column, "P0104", "Improper token. Expected start of statement.")
+				}
+				if err != nil {
+					errs = errs.add(err)
+					i = skipStatement(tokens, start, indentation)
+					continue
 				}
 				statements = append(statements, statement)
 				i += numTokens
 			} else {
-				return nil, 0, msg(t.LineNumber, t.Column, "Improper indentation.")
+				errs = errs.add(msg(t.LineNumber, t.Column, "P0103", "Improper indentation."))
+				i = skipStatement(tokens, i, indentation)
+			}
+		}
+	}
+	return statements, i, errs.err()
+}
+
+// returns the index of the line after the statement whose line starts at tokens[i],
+// skipping the lines of the statement's bodies and of its elif, else, case, and default clauses
+func skipStatement(tokens []Token, i int, indentation int) int {
+	for {
+		for i < len(tokens) && tokens[i].Type != Newline {
+			i++
+		}
+		i++
+		if i >= len(tokens) || tokens[i].Type != Indentation || len(tokens[i].Content) < indentation {
+			return i
+		}
+		if len(tokens[i].Content) == indentation {
+			switch tokens[i+1].Content {
+			case "elif", "else", "case", "default":
+			default:
+				return i
 			}
 		}
 	}
-	return statements, i, nil
 }
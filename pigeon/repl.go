@@ -0,0 +1,262 @@
+package pigeon
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/BrianWill/pigeon/pigeon/stdlib"
+)
+
+// A Session is the state of a REPL: the functions and globals defined so far
+// and the current values of the globals. Input is evaluated by the interpreter.
+type Session struct {
+	pkg    *Package
+	interp *interpreter
+}
+
+// A RuntimeError is a runtime error which ended an evaluation in a Session.
+type RuntimeError struct {
+	Message string
+}
+
+func (e RuntimeError) Error() string {
+	return "Runtime error: " + e.Message
+}
+
+// NewSession returns a session in which nothing is yet defined.
+func NewSession() *Session {
+	s := &Session{}
+	s.Reset()
+	return s
+}
+
+// Reset discards every definition.
+func (s *Session) Reset() {
+	s.pkg = newPackage("repl.pigeon", false)
+	s.interp = &interpreter{
+		pkg:          s.pkg,
+		globals:      map[string]interface{}{},
+		initializing: map[string]bool{},
+	}
+}
+
+// Eval evaluates the input, which is either an expression or any number of func and global definitions.
+// For an expression, returns its value formatted as print would show it
+// (or "" if the expression is an operation, such as println, which only has an effect, or a call returning no value).
+// A definition replaces any existing function or global of the same name; the value of a global is
+// evaluated when defined. The error is Diagnostics (with no File) if the input doesn't compile, or a RuntimeError.
+func (s *Session) Eval(input string) (string, error) {
+	val, err := s.eval(input)
+	if err != nil {
+		if _, ok := err.(RuntimeError); !ok {
+			return "", Diagnostics(fileDiagnostics(err, "", []byte(input)))
+		}
+	}
+	return val, err
+}
+
+func (s *Session) eval(input string) (string, error) {
+	tokens, err := lex(input + "\r\n")
+	if err != nil {
+		return "", err
+	}
+	first := 0
+	for first < len(tokens) && (tokens[first].Type == Newline || tokens[first].Type == Space) {
+		first++
+	}
+	if first == len(tokens) {
+		return "", nil
+	}
+	if t := tokens[first]; t.Type == ReservedWord && (t.Content == "func" || t.Content == "global") {
+		return "", s.define(tokens)
+	}
+	expr, n, err := parseExpression(tokens[first:], tokens[first].LineNumber)
+	if err != nil {
+		return "", err
+	}
+	rest := tokens[first+n:]
+	if rest[0].Type == Space {
+		rest = rest[1:]
+	}
+	if rest[0].Type != Newline || len(rest) > 1 {
+		return "", errors.New("Enter one expression, or func and global definitions.")
+	}
+	_, err = compileExpression(expr, s.pkg, map[string]string{})
+	if err != nil {
+		return "", err
+	}
+	var val interface{}
+	err = s.run(func() {
+		val = s.interp.eval(expr, map[string]interface{}{})
+	})
+	if err != nil {
+		return "", err
+	}
+	if op, ok := expr.(Operation); ok {
+		switch op.Operator {
+		case "set", "push", "print", "println":
+			return "", nil
+		}
+	}
+	if val == nil {
+		return "", nil
+	}
+	return fmt.Sprint(val), nil
+}
+
+// Load evaluates the definitions of a source file, as if they were entered together.
+// The error is Diagnostics if the file doesn't compile, or a RuntimeError.
+func (s *Session) Load(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Diagnostics(fileDiagnostics(err, filename, nil))
+	}
+	tokens, err := lex(string(data) + "\r\n")
+	if err == nil {
+		err = s.define(tokens)
+	}
+	if err != nil {
+		if _, ok := err.(RuntimeError); !ok {
+			return Diagnostics(fileDiagnostics(err, filename, data))
+		}
+	}
+	return err
+}
+
+// parses and checks the definitions, then adds them to the session (only if they're all OK)
+func (s *Session) define(tokens []Token) error {
+	definitions, err := parse(tokens, s.pkg)
+	if err != nil {
+		return err
+	}
+	// restored if the definitions fail to compile or a new global fails to evaluate
+	globals, funcs, values := s.pkg.Globals, s.pkg.Funcs, s.interp.globals
+	restore := func() {
+		s.pkg.Globals, s.pkg.Funcs, s.interp.globals = globals, funcs, values
+	}
+	s.pkg.Globals = map[string]GlobalDefinition{}
+	for name, g := range globals {
+		s.pkg.Globals[name] = g
+	}
+	s.pkg.Funcs = map[string]FunctionDefinition{}
+	for name, fn := range funcs {
+		s.pkg.Funcs[name] = fn
+	}
+	s.interp.globals = map[string]interface{}{}
+	for name, v := range values {
+		s.interp.globals[name] = v
+	}
+	newNames := map[string]bool{}
+	for _, def := range definitions {
+		var name string
+		var line, column int
+		switch d := def.(type) {
+		case GlobalDefinition:
+			name, line, column = d.Name, d.LineNumber, d.Column
+		case FunctionDefinition:
+			name, line, column = d.Name, d.LineNumber, d.Column
+		}
+		if newNames[strings.ToUpper(name)] {
+			restore()
+			return msg(line, column, "P0202", "Duplicate top-level name: "+name)
+		}
+		newNames[strings.ToUpper(name)] = true
+		// a definition replaces one of the same name, but names may not differ only in case
+		delete(s.pkg.Globals, name)
+		delete(s.pkg.Funcs, name)
+		delete(s.interp.globals, name)
+		for _, existing := range s.pkg.topLevelNames() {
+			if strings.EqualFold(existing, name) {
+				restore()
+				return msg(line, column, "P0202", "Duplicate top-level name: "+name+" (already defined as "+existing+")")
+			}
+		}
+		switch d := def.(type) {
+		case GlobalDefinition:
+			s.pkg.Globals[name] = d
+		case FunctionDefinition:
+			s.pkg.Funcs[name] = d
+		}
+	}
+	var errs Diagnostics
+	for _, def := range definitions {
+		switch d := def.(type) {
+		case GlobalDefinition:
+			_, err = compileExpression(d.Value, s.pkg, map[string]string{})
+		case FunctionDefinition:
+			_, err = compileFunc(d)
+		}
+		if err != nil {
+			errs = errs.add(err)
+		}
+	}
+	if len(errs) > 0 {
+		restore()
+		return errs.err()
+	}
+	// only the new globals are evaluated (in order of definition); the others keep their values
+	return s.run(func() {
+		for _, def := range definitions {
+			if g, ok := def.(GlobalDefinition); ok {
+				s.interp.global(g.Name)
+			}
+		}
+	}, restore)
+}
+
+// runs the function, returning a RuntimeError if it fails (after calling onError, if given)
+func (s *Session) run(f func(), onError ...func()) (err error) {
+	fatalln := stdlib.Fatalln
+	stdlib.Fatalln = func(v ...interface{}) {
+		panic(RuntimeError{strings.TrimSuffix(fmt.Sprintln(v...), "\n")})
+	}
+	defer func() {
+		stdlib.Fatalln = fatalln
+		r := recover()
+		if r == nil {
+			return
+		}
+		s.interp.initializing = map[string]bool{}
+		if e, ok := r.(RuntimeError); ok {
+			err = e
+		} else {
+			err = RuntimeError{fmt.Sprint(r)}
+		}
+		for _, f := range onError {
+			f()
+		}
+	}()
+	f()
+	return nil
+}
+
+// Funcs returns the functions defined in the session, each as its name followed by its parameters, e.g. "add3 a b c".
+func (s *Session) Funcs() []string {
+	funcs := []string{}
+	for _, fn := range s.pkg.Funcs {
+		funcs = append(funcs, strings.Join(append([]string{sourceName(fn.Name)}, fn.Parameters...), " "))
+	}
+	sort.Strings(funcs)
+	return funcs
+}
+
+// Globals returns the globals defined in the session, each as its name and its value, e.g. "x = [1 2 3]".
+func (s *Session) Globals() []string {
+	globals := []string{}
+	for name := range s.pkg.Globals {
+		globals = append(globals, name+" = "+fmt.Sprint(s.interp.globals[name]))
+	}
+	sort.Strings(globals)
+	return globals
+}
+
+// the compiler renames main to _main
+func sourceName(name string) string {
+	if name == "_main" {
+		return "main"
+	}
+	return name
+}
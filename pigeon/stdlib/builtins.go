@@ -21,9 +21,17 @@ var Breakpoints = make(map[int]bool)
 
 type MapType map[interface{}]interface{}
 
+// Stdin is the standard input read by prompt. Because a scanner may read ahead of the line it returns,
+// anything else reading standard input in the same process (such as the REPL) must read it from Stdin.
+var Stdin = bufio.NewScanner(os.Stdin)
+
+// Fatalln reports a runtime error, ending the program. (The REPL replaces it with a function
+// which panics instead, so that a runtime error only ends the evaluation of the input.)
+var Fatalln = log.Fatalln
+
 func Add(numbers ...interface{}) interface{} {
 	if len(numbers) < 2 {
-		log.Fatalln("Add operation has too few operands.")
+		Fatalln("Add operation has too few operands.")
 	}
 	var sum float64
 	for _, n := range numbers {
@@ -31,7 +39,7 @@ func Add(numbers ...interface{}) interface{} {
 		case float64:
 			sum += n
 		default:
-			log.Fatalln("Attempted to add a non-number.")
+			Fatalln("Attempted to add a non-number.")
 		}
 	}
 	return sum
@@ -39,40 +47,40 @@ func Add(numbers ...interface{}) interface{} {
 
 func Inc(numbers ...interface{}) interface{} {
 	if len(numbers) != 1 {
-		log.Fatalln("Inc operation has too few operands.")
+		Fatalln("Inc operation has too few operands.")
 	}
 	val, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted to inc a non-number.")
+		Fatalln("Attempted to inc a non-number.")
 	}
 	return val + 1
 }
 
 func Dec(numbers ...interface{}) interface{} {
 	if len(numbers) != 1 {
-		log.Fatalln("Dec operation has too few operands.")
+		Fatalln("Dec operation has too few operands.")
 	}
 	val, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted to inc a non-number.")
+		Fatalln("Attempted to inc a non-number.")
 	}
 	return val - 1
 }
 
 func Sub(numbers ...interface{}) interface{} {
 	if len(numbers) < 2 {
-		log.Fatalln("Sub operation has too few operands.")
+		Fatalln("Sub operation has too few operands.")
 	}
 	val, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted to subtract a non-number.")
+		Fatalln("Attempted to subtract a non-number.")
 	}
 	for _, n := range numbers[1:] {
 		switch n := n.(type) {
 		case float64:
 			val -= n
 		default:
-			log.Fatalln("Attempted to subtract a non-number.")
+			Fatalln("Attempted to subtract a non-number.")
 		}
 	}
 	return val
@@ -80,18 +88,18 @@ func Sub(numbers ...interface{}) interface{} {
 
 func Mul(numbers ...interface{}) interface{} {
 	if len(numbers) < 2 {
-		log.Fatalln("Mul operation has too few operands.")
+		Fatalln("Mul operation has too few operands.")
 	}
 	product, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted to multiply a non-number.")
+		Fatalln("Attempted to multiply a non-number.")
 	}
 	for _, n := range numbers[1:] {
 		switch n := n.(type) {
 		case float64:
 			product *= n
 		default:
-			log.Fatalln("Attempted to multiply a non-number.")
+			Fatalln("Attempted to multiply a non-number.")
 		}
 	}
 	return product
@@ -99,18 +107,18 @@ func Mul(numbers ...interface{}) interface{} {
 
 func Div(numbers ...interface{}) interface{} {
 	if len(numbers) < 2 {
-		log.Fatalln("Div operation has too few operands.")
+		Fatalln("Div operation has too few operands.")
 	}
 	quotient, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted to divide a non-number.")
+		Fatalln("Attempted to divide a non-number.")
 	}
 	for _, n := range numbers[1:] {
 		switch n := n.(type) {
 		case float64:
 			quotient /= n
 		default:
-			log.Fatalln("Attempted to divide a non-number.")
+			Fatalln("Attempted to divide a non-number.")
 		}
 	}
 	return quotient
@@ -118,26 +126,26 @@ func Div(numbers ...interface{}) interface{} {
 
 func Mod(numbers ...interface{}) interface{} {
 	if len(numbers) != 2 {
-		log.Fatalln("Modulus operation does not have two operands.")
+		Fatalln("Modulus operation does not have two operands.")
 	}
 	a, ok1 := numbers[0].(float64)
 	b, ok2 := numbers[1].(float64)
 	if !ok1 || !ok2 {
-		log.Fatalln("Attempted modulus with a non-number.")
+		Fatalln("Attempted modulus with a non-number.")
 	}
 	return float64(int(a) % int(b))
 }
 
 func Eq(values ...interface{}) interface{} {
 	if len(values) < 2 {
-		log.Fatalln("Attempted equality test with fewer than 2 operands.")
+		Fatalln("Attempted equality test with fewer than 2 operands.")
 	}
 
 	for _, val := range values {
 		switch val.(type) {
 		case float64, bool, string, Nil, ListType, MapType:
 		default:
-			log.Fatalln("Attempted equality test with type other than a number, boolean, string, or null.")
+			Fatalln("Attempted equality test with type other than a number, boolean, string, or null.")
 		}
 	}
 
@@ -194,7 +202,7 @@ func Neq(values ...interface{}) interface{} {
 
 func Id(vals ...interface{}) interface{} {
 	if len(vals) < 2 {
-		log.Fatalln("Too few operands for 'id' operation.")
+		Fatalln("Too few operands for 'id' operation.")
 	}
 	first := vals[0]
 	for _, v := range vals[1:] {
@@ -207,27 +215,27 @@ func Id(vals ...interface{}) interface{} {
 
 func Not(vals ...interface{}) interface{} {
 	if len(vals) != 1 {
-		log.Fatalln("Incorrect number of operands for get operation.")
+		Fatalln("Incorrect number of operands for get operation.")
 	}
 	b, ok := vals[0].(bool)
 	if !ok {
-		log.Fatalln("Attempted logical not operation on a non-boolean value.")
+		Fatalln("Attempted logical not operation on a non-boolean value.")
 	}
 	return !b
 }
 
 func Lt(numbers ...interface{}) interface{} {
 	if len(numbers) < 2 {
-		log.Fatalln("Too few operands for 'lt' operation.")
+		Fatalln("Too few operands for 'lt' operation.")
 	}
 	prev, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted 'lt' operation on a non-number.")
+		Fatalln("Attempted 'lt' operation on a non-number.")
 	}
 	for _, n := range numbers[1:] {
 		f, ok := n.(float64)
 		if !ok {
-			log.Fatalln("Attempted 'lt' operation on a non-number.")
+			Fatalln("Attempted 'lt' operation on a non-number.")
 		}
 		if prev >= f {
 			return false
@@ -239,16 +247,16 @@ func Lt(numbers ...interface{}) interface{} {
 
 func Gt(numbers ...interface{}) interface{} {
 	if len(numbers) < 2 {
-		log.Fatalln("Too few operands for 'gt' operation.")
+		Fatalln("Too few operands for 'gt' operation.")
 	}
 	prev, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted 'gt' operation on a non-number.")
+		Fatalln("Attempted 'gt' operation on a non-number.")
 	}
 	for _, n := range numbers[1:] {
 		f, ok := n.(float64)
 		if !ok {
-			log.Fatalln("Attempted 'gt' operation on a non-number.")
+			Fatalln("Attempted 'gt' operation on a non-number.")
 		}
 		if prev <= f {
 			return false
@@ -260,16 +268,16 @@ func Gt(numbers ...interface{}) interface{} {
 
 func Lte(numbers ...interface{}) interface{} {
 	if len(numbers) < 2 {
-		log.Fatalln("Too few operands for 'lte' operation.")
+		Fatalln("Too few operands for 'lte' operation.")
 	}
 	prev, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted 'lte' operation on a non-number.")
+		Fatalln("Attempted 'lte' operation on a non-number.")
 	}
 	for _, n := range numbers[1:] {
 		f, ok := n.(float64)
 		if !ok {
-			log.Fatalln("Attempted 'lte' operation on a non-number.")
+			Fatalln("Attempted 'lte' operation on a non-number.")
 		}
 		if prev > f {
 			return false
@@ -281,16 +289,16 @@ func Lte(numbers ...interface{}) interface{} {
 
 func Gte(numbers ...interface{}) interface{} {
 	if len(numbers) < 2 {
-		log.Fatalln("Too few operands for 'gte' operation.")
+		Fatalln("Too few operands for 'gte' operation.")
 	}
 	prev, ok := numbers[0].(float64)
 	if !ok {
-		log.Fatalln("Attempted 'gte' operation on a non-number.")
+		Fatalln("Attempted 'gte' operation on a non-number.")
 	}
 	for _, n := range numbers[1:] {
 		f, ok := n.(float64)
 		if !ok {
-			log.Fatalln("Attempted 'gte' operation on a non-number.")
+			Fatalln("Attempted 'gte' operation on a non-number.")
 		}
 		if prev < f {
 			return false
@@ -302,16 +310,16 @@ func Gte(numbers ...interface{}) interface{} {
 
 func Get(args ...interface{}) interface{} {
 	if len(args) != 2 {
-		log.Fatalln("Incorrect number of operands for 'get' operation.")
+		Fatalln("Incorrect number of operands for 'get' operation.")
 	}
 	switch v := args[0].(type) {
 	case ListType:
 		f, ok := args[1].(float64)
 		if !ok {
-			log.Fatalln("Second operand to 'get' on a list should be a number.")
+			Fatalln("Second operand to 'get' on a list should be a number.")
 		}
 		if int(f) >= len(*v.List) {
-			log.Fatalln("Index of 'get' exceeds bounds of the list.")
+			Fatalln("Index of 'get' exceeds bounds of the list.")
 		}
 		return (*v.List)[int(f)]
 	case MapType:
@@ -319,23 +327,23 @@ func Get(args ...interface{}) interface{} {
 		case float64, string:
 			return v[key]
 		default:
-			log.Fatalln("Second operand to 'get' on a map should be a string or number.")
+			Fatalln("Second operand to 'get' on a map should be a string or number.")
 		}
 	default:
-		log.Fatalln("First operand to 'get' must be a map or a list.")
+		Fatalln("First operand to 'get' must be a map or a list.")
 	}
 	return nil
 }
 
 func Set(args ...interface{}) interface{} {
 	if len(args) != 3 {
-		log.Fatalln("Incorrect number of operands for 'set' operation.")
+		Fatalln("Incorrect number of operands for 'set' operation.")
 	}
 	switch v := args[0].(type) {
 	case ListType:
 		f, ok := args[1].(float64)
 		if !ok {
-			log.Fatalln("Second operand to 'set' on a list should be a number.")
+			Fatalln("Second operand to 'set' on a list should be a number.")
 		}
 		(*v.List)[int(f)] = args[2]
 	case MapType:
@@ -343,21 +351,21 @@ func Set(args ...interface{}) interface{} {
 		case float64, string:
 			v[key] = args[2]
 		default:
-			log.Fatalln("Second operand to 'set' on a map should be a string or number.")
+			Fatalln("Second operand to 'set' on a map should be a string or number.")
 		}
 	default:
-		log.Fatalln("First operand to 'set' must be a map or a list.")
+		Fatalln("First operand to 'set' must be a map or a list.")
 	}
 	return Nil(0)
 }
 
 func Push(args ...interface{}) interface{} {
 	if len(args) < 2 {
-		log.Fatalln("Too few operands for 'push' operation.")
+		Fatalln("Too few operands for 'push' operation.")
 	}
 	list, ok := args[0].(ListType)
 	if !ok {
-		log.Fatalln("First operand to 'push' must be a list.")
+		Fatalln("First operand to 'push' must be a list.")
 	}
 	for _, v := range args[1:] {
 		*list.List = append(*list.List, v)
@@ -367,12 +375,12 @@ func Push(args ...interface{}) interface{} {
 
 func Or(args ...interface{}) interface{} {
 	if len(args) < 2 {
-		log.Fatalln("Too few operands for 'or' operation.")
+		Fatalln("Too few operands for 'or' operation.")
 	}
 	for _, a := range args {
 		b, ok := a.(bool)
 		if !ok {
-			log.Fatalln("Operands of 'or' must be booleans.")
+			Fatalln("Operands of 'or' must be booleans.")
 		}
 		if b {
 			return true
@@ -383,12 +391,12 @@ func Or(args ...interface{}) interface{} {
 
 func And(args ...interface{}) interface{} {
 	if len(args) < 2 {
-		log.Fatalln("Too few operands for 'or' operation.")
+		Fatalln("Too few operands for 'or' operation.")
 	}
 	for _, a := range args {
 		b, ok := a.(bool)
 		if !ok {
-			log.Fatalln("Operands of 'or' must be booleans.")
+			Fatalln("Operands of 'or' must be booleans.")
 		}
 		if !b {
 			return false
@@ -399,7 +407,7 @@ func And(args ...interface{}) interface{} {
 
 func Print(args ...interface{}) interface{} {
 	if len(args) == 0 {
-		log.Fatalln("Print operation needs at least one operand.")
+		Fatalln("Print operation needs at least one operand.")
 	}
 	fmt.Print(args...)
 	return Nil(0)
@@ -407,7 +415,7 @@ func Print(args ...interface{}) interface{} {
 
 func Println(args ...interface{}) interface{} {
 	if len(args) == 0 {
-		log.Fatalln("Println operation needs at least one operand.")
+		Fatalln("Println operation needs at least one operand.")
 	}
 	fmt.Println(args...)
 	return Nil(0)
@@ -417,12 +425,11 @@ func Prompt(args ...interface{}) interface{} {
 	if len(args) >= 1 {
 		fmt.Println(args...)
 	}
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	if scanner.Err() != nil {
-		log.Fatalln(scanner.Err())
+	Stdin.Scan()
+	if Stdin.Err() != nil {
+		Fatalln(Stdin.Err())
 	}
-	s := scanner.Text()
+	s := Stdin.Text()
 	return s
 }
 
@@ -436,7 +443,7 @@ func List(args ...interface{}) interface{} {
 
 func Lconcat(args ...interface{}) interface{} {
 	if len(args) < 2 {
-		log.Fatalln("'lconcat' operation needs two or more operands.")
+		Fatalln("'lconcat' operation needs two or more operands.")
 	}
 	list := []interface{}{}
 	for _, v := range args {
@@ -446,7 +453,7 @@ func Lconcat(args ...interface{}) interface{} {
 				list = append(list, val)
 			}
 		default:
-			log.Fatalln("'lconcat' operands must all be lists.")
+			Fatalln("'lconcat' operands must all be lists.")
 		}
 	}
 	return ListType{&list}
@@ -454,37 +461,37 @@ func Lconcat(args ...interface{}) interface{} {
 
 func Concat(args ...interface{}) interface{} {
 	if len(args) < 2 {
-		log.Fatalln("Concat operation needs two or more operands.")
+		Fatalln("Concat operation needs two or more operands.")
 	}
 	return fmt.Sprint(args...)
 }
 
 func Floor(args ...interface{}) interface{} {
 	if len(args) != 1 {
-		log.Fatalln("'floor' operation needs one operand.")
+		Fatalln("'floor' operation needs one operand.")
 	}
 	switch v := args[0].(type) {
 	case float64:
 		return math.Floor(v)
 	default:
-		log.Fatalln("'floor' operation operand must be a number")
+		Fatalln("'floor' operation operand must be a number")
 		return nil
 	}
 }
 
 func RandNum(args ...interface{}) interface{} {
 	if len(args) != 0 {
-		log.Fatalln("'randNum' operation should have no operands.")
+		Fatalln("'randNum' operation should have no operands.")
 	}
 	return rand.Float64()
 }
 
 func Map(args ...interface{}) interface{} {
 	if len(args) == 0 {
-		log.Fatalln("'Map' operation needs at least one operand.")
+		Fatalln("'Map' operation needs at least one operand.")
 	}
 	if len(args)%2 != 0 {
-		log.Fatalln("'Map' operations needs an even number of operands.")
+		Fatalln("'Map' operations needs an even number of operands.")
 	}
 	_map := make(MapType)
 	for i := 0; i < len(args); {
@@ -496,7 +503,7 @@ func Map(args ...interface{}) interface{} {
 
 func Len(args ...interface{}) interface{} {
 	if len(args) != 1 {
-		log.Fatalln("'len' operator must have just one operand.")
+		Fatalln("'len' operator must have just one operand.")
 	}
 	switch a := args[0].(type) {
 	case ListType:
@@ -506,18 +513,18 @@ func Len(args ...interface{}) interface{} {
 	case string:
 		return float64(len(a))
 	default:
-		log.Fatalln("'len' operator operand must be a map or list.")
+		Fatalln("'len' operator operand must be a map or list.")
 		return nil
 	}
 }
 
 func Charlist(args ...interface{}) interface{} {
 	if len(args) != 1 {
-		log.Fatalln("'charlist' operation needs one operand.")
+		Fatalln("'charlist' operation needs one operand.")
 	}
 	s, ok := args[0].(string)
 	if !ok {
-		log.Fatalln("'charlist' operation operand must be a string.")
+		Fatalln("'charlist' operation operand must be a string.")
 	}
 	list := make([]interface{}, len(s))
 	for i, a := range []rune(s) {
@@ -528,22 +535,22 @@ func Charlist(args ...interface{}) interface{} {
 
 func Getchar(args ...interface{}) interface{} {
 	if len(args) != 2 {
-		log.Fatalln("'getchar' operation needs two operands.")
+		Fatalln("'getchar' operation needs two operands.")
 	}
 	s, ok := args[0].(string)
 	if !ok {
-		log.Fatalln("'getchar' operation's first operand must be a string.")
+		Fatalln("'getchar' operation's first operand must be a string.")
 	}
 	idx, ok := args[1].(float64)
 	if !ok {
-		log.Fatalln("'getchar' operation's second operand must be a number.")
+		Fatalln("'getchar' operation's second operand must be a number.")
 	}
 	for i, a := range []rune(s) {
 		if i == int(idx) {
 			return string(a)
 		}
 	}
-	log.Fatalln("index for 'getchar' operation is out of bounds")
+	Fatalln("index for 'getchar' operation is out of bounds")
 	return ListType{}
 }
 
@@ -559,12 +566,6 @@ func (l ListType) String() string {
 	return s[:len(s)-1] + "]"
 }
 
-type DebugVar struct {
-	name   string
-	val    interface{}
-	global bool
-}
-
 // do nothing (used to supress unused variable compile errors)
 func NullOp(args ...interface{}) {
 	// do nothing
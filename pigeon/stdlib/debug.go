@@ -0,0 +1,259 @@
+package stdlib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+/*
+Debugging support. In a program compiled for debugging, every function begins with:
+
+	_std.Enter("name", _locals)
+	defer _std.Exit()
+
+...and every statement is preceded by:
+
+	if _std.Break(line) {_debug(line)}
+
+...where _debug gathers the globals and locals and passes them to PollContinue.
+
+The program connects to the debugger at the address in the PIGEON_DEBUG environment variable
+(if PIGEON_DEBUG is not set, the program runs as normal). Messages in both directions are
+JSON values, one per line: the program sends a DebugStop each time it stops, and the debugger
+sends DebugCommands. The program waits for the first resume command before running.
+
+Each goroutine has its own stack of calls. A stop reports the calls of the goroutine which stopped,
+and a step or next resumes that goroutine to its next stop (other goroutines run on, stopping only
+at breakpoints). Only one goroutine at a time reports a stop: another which reaches a breakpoint
+meanwhile waits until the stopped goroutine resumes.
+*/
+
+// DebugVar is the name and printed value of a variable.
+type DebugVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// DebugFrame is a function call on the stack.
+type DebugFrame struct {
+	Name   string     `json:"name"`
+	Line   int        `json:"line"`
+	Locals []DebugVar `json:"locals"`
+	locals func() map[string]interface{}
+}
+
+// DebugStop is sent to the debugger when the program stops.
+type DebugStop struct {
+	Line    int          `json:"line"`
+	Reason  string       `json:"reason"` // "breakpoint" or "step"
+	Frames  []DebugFrame `json:"frames"` // innermost call first
+	Globals []DebugVar   `json:"globals"`
+}
+
+// DebugCommand is sent by the debugger. Command "breakpoints" replaces the breakpoints with Lines.
+// The resume commands are "continue", "step" (stop at the next statement) and
+// "next" (stop at the next statement of the current function or its callers).
+type DebugCommand struct {
+	Command string `json:"command"`
+	Lines   []int  `json:"lines,omitempty"`
+}
+
+var debugger struct {
+	sync.Mutex
+	once     sync.Once
+	conn     net.Conn
+	resume   chan string
+	stopped  sync.Mutex               // held by the goroutine which is reporting a stop
+	stacks   map[uint64][]*DebugFrame // the calls of each goroutine, by goroutine ID
+	mode     string                   // the last resume command
+	stepping uint64                   // the goroutine to which a step or next applies (0 for any goroutine)
+	depth    int                      // number of its frames when the last resume command was received
+}
+
+func debugConnect() {
+	addr := os.Getenv("PIGEON_DEBUG")
+	if addr == "" {
+		return
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		log.Fatalln("Cannot connect to debugger:", err)
+	}
+	debugger.conn = conn
+	debugger.resume = make(chan string, 1)
+	debugger.stacks = make(map[uint64][]*DebugFrame)
+	go debugRead(conn)
+	debugger.mode = <-debugger.resume
+	// (connecting as the first call is entered, so a next stops in that call)
+	debugger.stepping = goroutineID()
+	debugger.depth = 1
+}
+
+// returns the ID of the calling goroutine, from the header of its stack trace ("goroutine 1 [running]:")
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = bytes.TrimPrefix(buf[:runtime.Stack(buf, false)], []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i != -1 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// reads commands from the debugger until the connection closes
+func debugRead(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var cmd DebugCommand
+		err := json.Unmarshal(scanner.Bytes(), &cmd)
+		if err != nil {
+			continue
+		}
+		switch cmd.Command {
+		case "breakpoints":
+			debugger.Lock()
+			Breakpoints = make(map[int]bool)
+			for _, line := range cmd.Lines {
+				Breakpoints[line] = true
+			}
+			debugger.Unlock()
+		case "continue", "step", "next":
+			select {
+			case debugger.resume <- cmd.Command:
+			default: // not stopped, so ignore
+			}
+		}
+	}
+	// the debugger has quit
+	os.Exit(1)
+}
+
+// Enter records a call to the named function. The locals function returns the function's current locals.
+func Enter(name string, locals func() map[string]interface{}) {
+	debugger.once.Do(debugConnect)
+	if debugger.conn == nil {
+		return
+	}
+	id := goroutineID()
+	debugger.Lock()
+	debugger.stacks[id] = append(debugger.stacks[id], &DebugFrame{Name: name, locals: locals})
+	debugger.Unlock()
+}
+
+// Exit records the return from the function of the goroutine's last call to Enter.
+func Exit() {
+	if debugger.conn == nil {
+		return
+	}
+	id := goroutineID()
+	debugger.Lock()
+	frames := debugger.stacks[id]
+	if len(frames) > 1 {
+		debugger.stacks[id] = frames[:len(frames)-1]
+	} else {
+		delete(debugger.stacks, id)
+		if id == debugger.stepping && debugger.mode != "continue" {
+			// the stepped goroutine has finished, so stop at the next statement of any goroutine
+			debugger.mode = "step"
+			debugger.stepping = 0
+		}
+	}
+	debugger.Unlock()
+}
+
+// Break records that the current function has reached the statement on the line.
+// Returns true if the program should stop before executing the statement.
+func Break(line int) bool {
+	if debugger.conn == nil {
+		return false
+	}
+	id := goroutineID()
+	debugger.Lock()
+	defer debugger.Unlock()
+	frames := debugger.stacks[id]
+	if len(frames) > 0 {
+		frames[len(frames)-1].Line = line
+	}
+	if Breakpoints[line] {
+		return true
+	}
+	if debugger.stepping != 0 && id != debugger.stepping {
+		return false
+	}
+	switch debugger.mode {
+	case "step":
+		return true
+	case "next":
+		return debugger.stepping == 0 || len(frames) <= debugger.depth
+	}
+	return false
+}
+
+// PollContinue reports a stop to the debugger and waits for a resume command.
+func PollContinue(line int, globals map[string]interface{}, locals map[string]interface{}) {
+	id := goroutineID()
+	debugger.stopped.Lock()
+	defer debugger.stopped.Unlock()
+	debugger.Lock()
+	reason := "step"
+	if Breakpoints[line] {
+		reason = "breakpoint"
+	}
+	stop := DebugStop{Line: line, Reason: reason, Globals: debugVars(globals)}
+	frames := debugger.stacks[id]
+	for i := len(frames) - 1; i >= 0; i-- {
+		f := frames[i]
+		vars := locals
+		if i < len(frames)-1 {
+			vars = f.locals()
+		}
+		stop.Frames = append(stop.Frames, DebugFrame{Name: f.Name, Line: f.Line, Locals: debugVars(vars)})
+	}
+	data, err := json.Marshal(stop)
+	if err != nil {
+		log.Fatalln("Cannot send to debugger:", err)
+	}
+	_, err = debugger.conn.Write(append(data, '\n'))
+	if err != nil {
+		os.Exit(1)
+	}
+	debugger.Unlock()
+	mode := <-debugger.resume
+	debugger.Lock()
+	debugger.mode = mode
+	debugger.stepping = id
+	debugger.depth = len(debugger.stacks[id])
+	debugger.Unlock()
+}
+
+// returns the variables sorted by name
+func debugVars(vars map[string]interface{}) []DebugVar {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	debugVars := make([]DebugVar, len(names))
+	for i, name := range names {
+		var value string
+		switch v := vars[name].(type) {
+		case string:
+			value = strconv.Quote(v)
+		case Nil:
+			value = "nil"
+		default:
+			value = fmt.Sprint(v)
+		}
+		debugVars[i] = DebugVar{name, value}
+	}
+	return debugVars
+}
@@ -0,0 +1,102 @@
+package pigeon
+
+import (
+	"sort"
+	"strings"
+)
+
+// didYouMean returns " Did you mean x?", where x is the candidate most likely intended by the
+// misspelled name, or "" if no candidate is close enough.
+func didYouMean(name string, candidates ...[]string) string {
+	s, ok := suggest(name, candidates...)
+	if !ok {
+		return ""
+	}
+	return " Did you mean " + s + "?"
+}
+
+// suggest returns the candidate nearest the name by edit distance. A candidate which differs only in
+// case is always suggested; otherwise the distance may be at most a third of the name's length
+// (so short names, such as single letters, get no suggestions but for case).
+// Ties go to the candidate which sorts first.
+func suggest(name string, candidates ...[]string) (string, bool) {
+	var all []string
+	for _, c := range candidates {
+		all = append(all, c...)
+	}
+	sort.Strings(all)
+	best := ""
+	bestDistance := len(name)/3 + 1
+	for _, c := range all {
+		if c == name {
+			continue
+		}
+		if strings.EqualFold(c, name) {
+			return c, true
+		}
+		d := editDistance(name, c)
+		if d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance returns the number of single-character insertions, deletions, substitutions, and
+// transpositions of adjacent characters needed to turn a into b
+// (the optimal string alignment distance).
+func editDistance(a string, b string) int {
+	// d[i][j] is the distance between the first i characters of a and the first j characters of b
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func min(first int, rest ...int) int {
+	for _, n := range rest {
+		if n < first {
+			first = n
+		}
+	}
+	return first
+}
+
+func localNames(locals map[string]string) []string {
+	names := []string{}
+	for name := range locals {
+		names = append(names, name)
+	}
+	return names
+}
+
+// returns the names of the package's globals and functions
+func (p *Package) topLevelNames() []string {
+	names := []string{}
+	for name := range p.Globals {
+		names = append(names, name)
+	}
+	for name := range p.Funcs {
+		if name != "_main" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -1,9 +1,8 @@
 package pigeon
 
 import (
-	"errors"
 	"fmt"
-	"strconv"
+	"go/ast"
 )
 
 type TokenType int
@@ -32,6 +31,9 @@ const (
 
 const indentationSpaces = 4
 
+// reserved words which start top-level definitions
+var definitionWords = []string{"func", "global"}
+
 var reservedWords = []string{
 	"func",
 	"global",
@@ -45,6 +47,9 @@ var reservedWords = []string{
 	"if",
 	"else",
 	"elif",
+	"switch",
+	"case",
+	"default",
 	"return",
 	"as",
 	"locals",
@@ -130,6 +135,7 @@ func (t GlobalDefinition) Definition()   {}
 
 func (t LocalsStatement) Statement()     {}
 func (t IfStatement) Statement()         {}
+func (t SwitchStatement) Statement()     {}
 func (t WhileStatement) Statement()      {}
 func (t ForeachStatement) Statement()    {}
 func (t ForincStatement) Statement()     {}
@@ -146,6 +152,10 @@ func (t LocalsStatement) Line() int {
 func (t IfStatement) Line() int {
 	return t.LineNumber
 }
+func (t SwitchStatement) Line() int {
+	return t.LineNumber
+}
+
 func (t WhileStatement) Line() int {
 	return t.LineNumber
 }
@@ -184,13 +194,27 @@ func (t Token) Line() int {
 	return t.LineNumber
 }
 
+// returns the line and column at which the expression starts
+func position(e Expression) (int, int) {
+	switch e := e.(type) {
+	case Token:
+		return e.LineNumber, e.Column
+	case FunctionCall:
+		return e.LineNumber, e.Column
+	case Operation:
+		return e.LineNumber, e.Column
+	}
+	return e.Line(), 0
+}
+
 type FunctionDefinition struct {
-	LineNumber int
-	Column     int
-	Name       string
-	Parameters []string
-	Body       []Statement
-	Pkg        *Package
+	LineNumber  int
+	Column      int
+	Name        string
+	Parameters  []string
+	Body        []Statement
+	Pkg         *Package
+	BodyInvalid bool // the body failed to parse, so only the parameters are checked
 }
 
 type GlobalDefinition struct {
@@ -245,6 +269,21 @@ type ElseClause struct {
 	Body       []Statement
 }
 
+type SwitchStatement struct {
+	LineNumber int
+	Column     int
+	Value      Expression
+	Cases      []SwitchCase
+	Default    []Statement
+}
+
+type SwitchCase struct {
+	LineNumber int
+	Column     int
+	Values     []Expression // the case runs if the switch value equals any of these
+	Body       []Statement
+}
+
 type LocalsStatement struct {
 	LineNumber int
 	Column     int
@@ -256,6 +295,7 @@ type WhileStatement struct {
 	Column     int
 	Condition  Expression
 	Body       []Statement
+	Label      string // the name by which a break or continue can target the loop ("" if none)
 }
 
 type ForeachStatement struct {
@@ -265,6 +305,7 @@ type ForeachStatement struct {
 	ValName    string
 	Collection Expression
 	Body       []Statement
+	Label      string // the name by which a break or continue can target the loop ("" if none)
 }
 
 type ForincStatement struct {
@@ -275,6 +316,7 @@ type ForincStatement struct {
 	EndVal     Expression
 	Body       []Statement
 	Dec        bool
+	Label      string // the name by which a break or continue can target the loop ("" if none)
 }
 
 type ReturnStatement struct {
@@ -286,11 +328,13 @@ type ReturnStatement struct {
 type BreakStatement struct {
 	LineNumber int
 	Column     int
+	Label      string // the label of the targeted loop ("" for the innermost loop)
 }
 
 type ContinueStatement struct {
 	LineNumber int
 	Column     int
+	Label      string // the label of the targeted loop ("" for the innermost loop)
 }
 
 type AssignmentStatement struct {
@@ -301,15 +345,13 @@ type AssignmentStatement struct {
 }
 
 type Package struct {
+	FullPath         string
 	Globals          map[string]GlobalDefinition
 	ValidBreakpoints map[string]bool
 	Funcs            map[string]FunctionDefinition
 	Code             string
-}
-
-func msg(line int, column int, s string) error {
-	return errors.New("Line " + strconv.Itoa(line) + ", column " +
-		strconv.Itoa(column) + ": " + s)
+	Debug            bool             // if true, the code is instrumented for the debugger
+	lines            map[ast.Node]int // the source line of each generated declaration and statement (see mapLine)
 }
 
 func debug(args ...interface{}) {
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BrianWill/pigeon/pigeon"
+	"github.com/BrianWill/pigeon/pigeon/stdlib"
+)
+
+const replHelp = `Enter an expression, such as (add 3 4), to see its value.
+Enter a func or global definition to define (or redefine) it. The lines of a function body
+are indented four spaces, and a blank line ends the function.
+
+Commands:
+    :funcs         list the functions
+    :globals       list the globals and their values
+    :reset         discard every definition
+    :load file     define the funcs and globals of a Pigeon source file
+    :help          show this help
+    :quit          leave the REPL (as does end of input)
+`
+
+// replCommand reads Pigeon input from the terminal and evaluates it in the interpreter.
+func replCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Println("pigeon repl takes no arguments.")
+		return 2
+	}
+	fmt.Println("Pigeon REPL. Enter :help for help.")
+	session := pigeon.NewSession()
+	var pending string // a line read while reading a definition but not part of it
+	hasPending := false
+	for {
+		var line string
+		if hasPending {
+			line, hasPending = pending, false
+		} else {
+			fmt.Print(">>> ")
+			var ok bool
+			line, ok = readInputLine()
+			if !ok {
+				fmt.Println()
+				return 0
+			}
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ":") {
+			if !replCommandLine(session, trimmed) {
+				return 0
+			}
+			continue
+		}
+		input := line
+		if strings.HasPrefix(trimmed, "func ") || trimmed == "func" {
+			// the body continues until a blank line or a line which isn't indented
+			for {
+				fmt.Print("... ")
+				next, ok := readInputLine()
+				if !ok || strings.TrimSpace(next) == "" {
+					break
+				}
+				if !strings.HasPrefix(next, " ") && !strings.HasPrefix(next, "\t") {
+					pending, hasPending = next, true
+					break
+				}
+				input += "\n" + next
+			}
+		}
+		val, err := session.Eval(input)
+		if err != nil {
+			printReplError(err, input)
+			continue
+		}
+		if val != "" {
+			fmt.Println(val)
+		}
+	}
+}
+
+// performs the REPL command, returning false if the command is to quit
+func replCommandLine(session *pigeon.Session, command string) bool {
+	fields := strings.Fields(command)
+	switch fields[0] {
+	case ":funcs":
+		funcs := session.Funcs()
+		if len(funcs) == 0 {
+			fmt.Println("No functions are defined.")
+		}
+		for _, fn := range funcs {
+			fmt.Println("func " + fn)
+		}
+	case ":globals":
+		globals := session.Globals()
+		if len(globals) == 0 {
+			fmt.Println("No globals are defined.")
+		}
+		for _, g := range globals {
+			fmt.Println(g)
+		}
+	case ":reset":
+		session.Reset()
+		fmt.Println("Every definition is discarded.")
+	case ":load":
+		if len(fields) != 2 {
+			fmt.Println("Usage: :load file.pigeon")
+			break
+		}
+		err := session.Load(fields[1])
+		if err != nil {
+			printReplError(err, "")
+			break
+		}
+		fmt.Println("Loaded " + fields[1] + ".")
+	case ":help":
+		fmt.Print(replHelp)
+	case ":quit":
+		return false
+	default:
+		fmt.Println("Unknown command " + fields[0] + ". Enter :help for the commands.")
+	}
+	return true
+}
+
+// prints an error of a session, with excerpts of the input (or, if input is "", of the loaded file) for compile errors
+func printReplError(err error, input string) {
+	diags, ok := err.(pigeon.Diagnostics)
+	if !ok {
+		fmt.Println(err)
+		return
+	}
+	if input == "" {
+		fmt.Print(formatDiagnostics(pigeonDiagnostics(diags)))
+		return
+	}
+	fmt.Print(formatDiagnosticsOf(pigeonDiagnostics(diags), func(string) []string {
+		return strings.Split(input, "\n")
+	}))
+}
+
+// reads a line of standard input (through the scanner shared with the prompt operation)
+func readInputLine() (string, bool) {
+	if !stdlib.Stdin.Scan() {
+		return "", false
+	}
+	return strings.TrimRight(stdlib.Stdin.Text(), "\r"), true
+}
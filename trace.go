@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// A traceFilter rewrites the error output of 'go build' and of a generated program
+// so that it refers to the source program rather than to the generated Go code.
+// The generated code carries //line directives, so Go already reports source line numbers;
+// the filter replaces workspace paths with the source file name, replaces Go function names
+// with source function names, and hides the stack frames of the runtime, the _std package,
+// and the generated main wrapper.
+type traceFilter struct {
+	out     io.Writer
+	prog    *program
+	dir     string // the workspace directory
+	buf     []byte // incomplete last line
+	inTrace bool   // true while reading the frames of a goroutine
+	frame   string // function line of a frame, awaiting its location line
+}
+
+func newTraceFilter(out io.Writer, prog *program, dir string) *traceFilter {
+	return &traceFilter{out: out, prog: prog, dir: dir}
+}
+
+func (f *traceFilter) Write(p []byte) (int, error) {
+	f.buf = append(f.buf, p...)
+	for {
+		idx := bytes.IndexByte(f.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		line := string(f.buf[:idx])
+		f.buf = f.buf[idx+1:]
+		err := f.writeLine(strings.TrimSuffix(line, "\r"))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// writes out any incomplete last line
+func (f *traceFilter) Flush() error {
+	if len(f.buf) == 0 {
+		return nil
+	}
+	line := string(f.buf)
+	f.buf = nil
+	_, err := io.WriteString(f.out, f.location(line))
+	return err
+}
+
+func (f *traceFilter) writeLine(line string) error {
+	if !f.inTrace {
+		if line == "# "+strings.TrimSuffix(outputModule, "/") || strings.HasPrefix(line, "# "+outputModule) {
+			return nil // package header printed by 'go build'
+		}
+		if strings.HasPrefix(line, "goroutine ") && strings.HasSuffix(line, ":") {
+			f.inTrace = true
+		}
+		_, err := io.WriteString(f.out, f.location(line)+"\n")
+		return err
+	}
+	switch {
+	case line == "":
+		f.inTrace = false
+		f.frame = ""
+		_, err := io.WriteString(f.out, "\n")
+		return err
+	case strings.HasPrefix(line, "\t"):
+		frame := f.frame
+		f.frame = ""
+		location := strings.TrimPrefix(line, "\t")
+		if idx := strings.LastIndex(location, " +0x"); idx != -1 {
+			location = location[:idx]
+		}
+		if frame == "" || !f.isSourceLocation(location) {
+			return nil
+		}
+		name, ok := f.frameName(frame)
+		if !ok {
+			return nil
+		}
+		_, err := io.WriteString(f.out, name+"\n\t"+f.location(location)+"\n")
+		return err
+	case strings.HasPrefix(line, "..."):
+		_, err := io.WriteString(f.out, line+"\n")
+		return err
+	default:
+		f.frame = line
+		return nil
+	}
+}
+
+// true if the location (file:line) is in a source file rather than in the Go runtime or _std
+func (f *traceFilter) isSourceLocation(location string) bool {
+	idx := strings.LastIndex(location, ":")
+	if idx == -1 {
+		return false
+	}
+	_, ok := f.sourceFiles()[location[:idx]]
+	return ok || filepath.Base(location[:idx]) == filepath.Base(f.prog.Source)
+}
+
+// returns the names of the source files of the program keyed by their paths within the workspace
+// (the //line directives of each generated package name its source file relative to the package's directory)
+func (f *traceFilter) sourceFiles() map[string]string {
+	files := map[string]string{filepath.Join(f.dir, filepath.Base(f.prog.Source)): f.prog.Source}
+	for _, p := range f.prog.Packages {
+		files[filepath.Join(f.dir, p.Dir, filepath.Base(p.Source))] = p.Source
+	}
+	return files
+}
+
+// returns the source form of a frame's function line, e.g. "main.Foo(0x5)" becomes "foo(...)".
+// Returns false if the frame should be hidden.
+func (f *traceFilter) frameName(frame string) (string, bool) {
+	if strings.HasPrefix(frame, "created by ") {
+		name := strings.TrimPrefix(frame, "created by ")
+		suffix := ""
+		if idx := strings.Index(name, " in goroutine "); idx != -1 {
+			name, suffix = name[:idx], name[idx:]
+		}
+		return "created by " + f.funcName(name) + suffix, true
+	}
+	name := frame
+	if strings.HasSuffix(name, ")") {
+		if idx := strings.LastIndex(name, "("); idx != -1 {
+			name = name[:idx]
+		}
+	}
+	if name == "main.main" {
+		return "", false
+	}
+	return f.funcName(name) + "(...)", true
+}
+
+func (f *traceFilter) funcName(name string) string {
+	if sourceName, ok := f.prog.FuncNames[name]; ok {
+		return sourceName
+	}
+	// closures are named after the enclosing function, e.g. main.Foo.func1
+	for goName, sourceName := range f.prog.FuncNames {
+		if strings.HasPrefix(name, goName+".func") {
+			return sourceName
+		}
+	}
+	return strings.TrimPrefix(name, "main.")
+}
+
+// replaces paths of the source files within the workspace with the source file names
+func (f *traceFilter) location(s string) string {
+	for path, source := range f.sourceFiles() {
+		s = strings.Replace(s, path, source, -1)
+		// 'go build' reports paths relative to the workspace
+		rel, err := filepath.Rel(f.dir, path)
+		if err != nil {
+			continue
+		}
+		for _, prefix := range []string{"./" + filepath.ToSlash(rel) + ":", filepath.ToSlash(rel) + ":"} {
+			if strings.HasPrefix(s, prefix) {
+				s = source + ":" + strings.TrimPrefix(s, prefix)
+			}
+		}
+	}
+	return s
+}
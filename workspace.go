@@ -0,0 +1,246 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// The runtime packages imported by generated code. They are embedded in the compiler so that
+// a generated program builds offline, whether or not the pigeon source is in GOPATH or the module cache.
+//
+//go:embed goPigeon/stdlib/*.go pigeon/stdlib/*.go
+var runtimeFiles embed.FS
+
+// module path of the runtime packages, as imported by generated code
+const runtimeModule = "github.com/BrianWill/pigeon"
+
+// subdirectory of a workspace to which the embedded runtime is written
+// (the leading underscore keeps it out of ./... patterns)
+const runtimeDir = "_runtime"
+
+const workspaceGoMod = `module pigeon_output
+
+go 1.16
+
+require ` + runtimeModule + ` v0.0.0
+
+replace ` + runtimeModule + ` => ./` + runtimeDir + `
+`
+
+const runtimeGoMod = `module ` + runtimeModule + `
+
+go 1.16
+`
+
+// A workspace is a directory holding a self-contained Go module in which a generated program is built.
+// Every run gets its own workspace, so concurrent runs never write to the same output.go.
+type workspace struct {
+	Dir     string
+	Dialect *dialect
+	Program *program  // the program written by writeProgram
+	Stdout  io.Writer // output of the Go tool and of the program
+	Stderr  io.Writer
+	temp    bool // if true, Dir is removed by Close
+}
+
+// creates a workspace for a program of dialect d in dir, or in a new temporary directory if dir is empty
+func newWorkspace(dir string, d *dialect) (*workspace, error) {
+	w := &workspace{Dir: dir, Dialect: d, Stdout: os.Stdout, Stderr: os.Stderr}
+	if dir == "" {
+		tempDir, err := ioutil.TempDir("", "pigeon-")
+		if err != nil {
+			return nil, err
+		}
+		w.Dir = tempDir
+		w.temp = true
+	} else {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		w.Dir = absDir
+		err = checkKeepDir(absDir)
+		if err != nil {
+			return nil, err
+		}
+		err = os.MkdirAll(absDir, os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := w.writeRuntime()
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// returns an error unless the directory chosen by the user can hold a workspace: it must not exist,
+// be empty, or be a workspace already (whose go.mod was written by pigeon), so that no file of the
+// user's is overwritten
+func checkKeepDir(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err == nil && strings.HasPrefix(string(data), "module pigeon_output\n") {
+		return nil
+	}
+	return errors.New("Cannot write the generated code to " + dir + ": the directory is not empty " +
+		"(and is not a directory to which pigeon wrote generated code before). Choose a new or empty directory.")
+}
+
+// writes the go.mod of the workspace and the embedded runtime module it replaces
+// (only the runtime package of the workspace's dialect is written)
+func (w *workspace) writeRuntime() error {
+	if !strings.HasPrefix(w.Dialect.RuntimeImport, runtimeModule+"/") {
+		return errors.New("Runtime package is not embedded: " + w.Dialect.RuntimeImport)
+	}
+	runtimePath := strings.TrimPrefix(w.Dialect.RuntimeImport, runtimeModule+"/")
+	if _, err := fs.Stat(runtimeFiles, runtimePath); err != nil {
+		return errors.New("Runtime package is not embedded: " + w.Dialect.RuntimeImport)
+	}
+	err := ioutil.WriteFile(filepath.Join(w.Dir, "go.mod"), []byte(workspaceGoMod), 0644)
+	if err != nil {
+		return err
+	}
+	root := filepath.Join(w.Dir, runtimeDir)
+	err = os.MkdirAll(root, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(root, "go.mod"), []byte(runtimeGoMod), 0644)
+	if err != nil {
+		return err
+	}
+	return fs.WalkDir(runtimeFiles, runtimePath, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		data, err := runtimeFiles.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, data, 0644)
+	})
+}
+
+// writes the program's code (already formatted by the compiler) to the dialect's output file in the workspace,
+// and the code of each package of the program in a subdirectory beside it.
+// Returns the path of the written file.
+func (w *workspace) writeProgram(prog *program) (string, error) {
+	w.Program = prog
+	outputFile := filepath.Join(w.Dir, filepath.FromSlash(w.Dialect.OutputFile))
+	err := os.MkdirAll(filepath.Dir(outputFile), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	err = ioutil.WriteFile(outputFile, prog.Code, 0644)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range prog.Packages {
+		file := filepath.Join(filepath.Dir(outputFile), p.Dir, filepath.Base(outputFile))
+		err = os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		if err == nil {
+			err = ioutil.WriteFile(file, p.Code, 0644)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return outputFile, nil
+}
+
+// returns a go tool command which runs in the workspace in module mode without network access
+func (w *workspace) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = w.Dir
+	cmd.Env = append(os.Environ(),
+		"GO111MODULE=on",
+		"GOFLAGS=-mod=mod",
+		"GOPROXY=off",
+		"GOWORK=off",
+		"GOTOOLCHAIN=local",
+	)
+	return cmd
+}
+
+// compiles the program in the workspace into an executable at output.
+// Returns the exit status of 'go build'.
+func (w *workspace) build(output string) (int, error) {
+	mainPackage := "./" + path.Dir(w.Dialect.OutputFile)
+	cmd := w.command("build", "-o", output, mainPackage)
+	cmd.Stdout = w.Stdout
+	stderr := newTraceFilter(w.Stderr, w.Program, w.Dir)
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	stderr.Flush()
+	return exitStatus(err)
+}
+
+// compiles and executes the program in the workspace, connecting it to the terminal.
+// Returns the exit status of the program (or of 'go build' if the build fails).
+func (w *workspace) run() (int, error) {
+	status, err := w.build(w.executable())
+	if status != 0 || err != nil {
+		return status, err
+	}
+	cmd := exec.Command(w.executable())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = w.Stdout
+	stderr := newTraceFilter(w.Stderr, w.Program, w.Dir)
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	stderr.Flush()
+	return exitStatus(err)
+}
+
+// returns the path at which run builds the executable
+func (w *workspace) executable() string {
+	executable := filepath.Join(w.Dir, "program")
+	if runtime.GOOS == "windows" {
+		executable += ".exe"
+	}
+	return executable
+}
+
+// removes the workspace directory unless it was chosen by the user
+func (w *workspace) Close() error {
+	if w.temp {
+		return os.RemoveAll(w.Dir)
+	}
+	return nil
+}
+
+// a command which ran but exited with a non-zero status is not an error
+func exitStatus(err error) (int, error) {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, err
+	}
+	return 0, nil
+}